@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"keptn/git-promotion-service/pkg/handler"
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/pending"
+	"keptn/git-promotion-service/pkg/secrets"
+	"keptn/git-promotion-service/pkg/webhook"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/kelseyhightower/envconfig"
@@ -27,6 +34,21 @@ type envConfig struct {
 	// Port on which to listen for cloudevents
 	Port int    `envconfig:"RCV_PORT" default:"8080"`
 	Path string `envconfig:"RCV_PATH" default:"/"`
+	// SecretsDirectory contains one directory per secret for secret source file
+	SecretsDirectory string `envconfig:"SECRETS_DIRECTORY" default:"/var/run/secrets/git-promotion"`
+	// SecretsEnvPrefix is the prefix of environment variables for secret source env
+	SecretsEnvPrefix string `envconfig:"SECRETS_ENV_PREFIX" default:""`
+	// PendingStore is where promotions waiting for a merge are saved: configmap or file (defaults to configmap in-cluster)
+	PendingStore string `envconfig:"PENDING_STORE" default:""`
+	// PendingDirectory is used by pending store file and should be a persistent volume
+	PendingDirectory string `envconfig:"PENDING_DIRECTORY" default:"/var/lib/git-promotion/pending"`
+	// MergePollInterval is the interval in which the pull requests of pending promotions are checked
+	MergePollInterval time.Duration `envconfig:"MERGE_POLL_INTERVAL" default:"1m"`
+	// Port on which to listen for pull request webhooks
+	WebhookPort int    `envconfig:"WEBHOOK_PORT" default:"8082"`
+	WebhookPath string `envconfig:"WEBHOOK_PATH" default:"/webhook"`
+	// WebhookSecret verifies the webhooks, the endpoint is disabled without secret
+	WebhookSecret string `envconfig:"WEBHOOK_SECRET" default:""`
 }
 
 // Opaque key type used for graceful shutdown context value
@@ -34,6 +56,9 @@ type gracefulShutdownKeyType struct{}
 
 var gracefulShutdownKey = gracefulShutdownKeyType{}
 
+var secretProviders secrets.Providers
+var pendingStore pending.Store
+
 func main() {
 	logger.SetLevel(logger.InfoLevel)
 	logger.Printf("Starting keptn git promotion service")
@@ -56,6 +81,12 @@ func main() {
 
 func _main(args []string, env envConfig) int {
 	ctx := getGracefulContext()
+	kubeAPI := getKubeAPI()
+	secretProviders = getSecretProviders(env, kubeAPI)
+	pendingStore = getPendingStore(env, kubeAPI)
+	watcher := handler.NewMergeWatcher(pendingStore, secretProviders, env.MergePollInterval)
+	go watcher.Run(ctx)
+	webhookStopped := startWebhookServer(ctx, env, watcher)
 
 	p, err := cloudevents.NewHTTP(cloudevents.WithPath(env.Path), cloudevents.WithPort(env.Port), cloudevents.WithGetHandlerFunc(keptnapi.HealthEndpointHandler))
 	if err != nil {
@@ -65,7 +96,10 @@ func _main(args []string, env envConfig) int {
 	if err != nil {
 		log.Fatalf("failed to create client, %v", err)
 	}
-	log.Fatal(c.StartReceiver(ctx, gotEvent))
+	if err := c.StartReceiver(ctx, gotEvent); err != nil {
+		log.Fatalf("failed to start receiver, %v", err)
+	}
+	<-webhookStopped
 
 	return 0
 }
@@ -104,19 +138,8 @@ func switchEvent(ctx context.Context, event cloudevents.Event) {
 		return
 	}
 
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		logger.WithError(err).Error("failed to initialize kube client rest")
-		return
-	}
-	kubeAPI, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		logger.WithError(err).Error("failed to initialize kube client config")
-		return
-	}
-
 	handlers := []handler.Handler{
-		handler.NewGitPromotionTriggeredEventHandler(keptnHandlerV2, apiSet, kubeAPI),
+		handler.NewGitPromotionTriggeredEventHandler(keptnHandlerV2, apiSet, secretProviders, pendingStore),
 	}
 
 	unhandled := true
@@ -132,18 +155,98 @@ func switchEvent(ctx context.Context, event cloudevents.Event) {
 	}
 }
 
+// startWebhookServer serves pull request webhooks of github and gitlab next to the cloudevents receiver until ctx is
+// done, the returned channel is closed when the server is shut down. The endpoint is reachable from the git providers,
+// so slow clients are cut off by the timeouts.
+func startWebhookServer(ctx context.Context, env envConfig, watcher *handler.MergeWatcher) (stopped <-chan struct{}) {
+	done := make(chan struct{})
+	if env.WebhookSecret == "" {
+		logger.Info("WEBHOOK_SECRET not set, webhook endpoint is disabled")
+		close(done)
+		return done
+	}
+	mux := http.NewServeMux()
+	mux.Handle(env.WebhookPath, webhook.NewHandler([]byte(env.WebhookSecret), watcher.HandlePullRequestEvent))
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", env.WebhookPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// processing a webhook calls the provider api
+		WriteTimeout: 2 * time.Minute,
+		IdleTimeout:  2 * time.Minute,
+	}
+	go func() {
+		logger.Infof("listening for webhooks on port %d path %s", env.WebhookPort, env.WebhookPath)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.WithError(err).Error("webhook server stopped")
+		}
+	}()
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.WithError(err).Error("could not shut down webhook server")
+		}
+	}()
+	return done
+}
+
+// getKubeAPI returns nil if not running in-cluster
+func getKubeAPI() kubernetes.Interface {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		logger.WithError(err).Warn("not running in kubernetes, kubernetes secrets and configmaps are not available")
+		return nil
+	}
+	kubeAPI, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		logger.WithError(err).Error("failed to initialize kube client config")
+		return nil
+	}
+	return kubeAPI
+}
+
+// getSecretProviders returns all available secret sources, kubernetes secrets are only available when running in-cluster
+func getSecretProviders(env envConfig, kubeAPI kubernetes.Interface) secrets.Providers {
+	providers := secrets.Providers{
+		model.SecretSourceFile: secrets.NewFileProvider(env.SecretsDirectory),
+		model.SecretSourceEnv:  secrets.NewEnvProvider(env.SecretsEnvPrefix),
+	}
+	if kubeAPI != nil {
+		providers[model.SecretSourceKubernetes] = secrets.NewKubernetesProvider(kubeAPI, os.Getenv("K8S_NAMESPACE"))
+	}
+	return providers
+}
+
+// getPendingStore returns the configured store for promotions waiting for a merge
+func getPendingStore(env envConfig, kubeAPI kubernetes.Interface) pending.Store {
+	if env.PendingStore == "file" || (env.PendingStore == "" && kubeAPI == nil) {
+		logger.Infof("saving pending promotions to directory %s", env.PendingDirectory)
+		return pending.NewFileStore(env.PendingDirectory)
+	} else if env.PendingStore != "" && env.PendingStore != "configmap" {
+		log.Fatalf("invalid PENDING_STORE %s, must be configmap or file", env.PendingStore)
+	} else if kubeAPI == nil {
+		log.Fatalf("PENDING_STORE configmap is only available when running in kubernetes")
+	}
+	logger.Infof("saving pending promotions to configmaps of namespace %s", os.Getenv("K8S_NAMESPACE"))
+	return pending.NewConfigMapStore(kubeAPI, os.Getenv("K8S_NAMESPACE"))
+}
+
 func getGracefulContext() context.Context {
 
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
 	wg := &sync.WaitGroup{}
 	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), gracefulShutdownKey, wg))
 	ctx = cloudevents.WithEncodingStructured(ctx)
 	go func() {
 		<-ch
-		logger.Fatal("Container termination triggered, starting graceful shutdown")
+		logger.Info("Container termination triggered, starting graceful shutdown")
 		wg.Wait()
-		logger.Fatal("cancelling context")
+		logger.Info("cancelling context")
 		cancel()
 	}()
 	return ctx
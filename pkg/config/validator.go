@@ -4,12 +4,30 @@ import (
 	"fmt"
 	logger "github.com/sirupsen/logrus"
 	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/replacer"
 	"net/url"
+	pathpkg "path"
 	"regexp"
 	"strings"
+	"time"
 )
 
 const githubPathRegexp = "^/[a-zA-Z0-9-]+/[a-zA-Z-_.]+$"
+const gitlabPathRegexp = "^(/[a-zA-Z0-9-_.]+){2,}$"
+const bitbucketServerPathRegexp = "^(/[^/]+)*/(projects/[^/]+/repos/[^/]+(/.*)?|scm/[^/]+/[^/]+)$"
+const azureDevOpsPathRegexp = "^(/[^/]+)+/_git/[^/]+$"
+const giteaPathRegexp = "^(/[^/]+)*/[a-zA-Z0-9-_.]+/[a-zA-Z0-9-_.]+$"
+const gitRemoteRegexp = "^((https?|ssh|file)://.+|[^/:@]+@[^/:]+:.+)$"
+const sshRemoteRegexp = "^(ssh://.+|[^/:@]+@[^/:]+:.+)$"
+
+// directProviders commit all changes atomically and reject the commit if the branch was changed concurrently. Bitbucket
+// server only supports single file commits and the gitea contents api can't guard the branch.
+var directProviders = []string{model.ProviderGithub, model.ProviderGitlab, model.ProviderAzureDevOps, model.ProviderGit}
+
+// tagProviders implement repoaccess.TagClient
+var tagProviders = []string{model.ProviderGithub, model.ProviderGitlab, model.ProviderGitea, model.ProviderGit}
+
+var supportedProviders = []string{model.ProviderGithub, model.ProviderGitlab, model.ProviderBitbucketServer, model.ProviderAzureDevOps, model.ProviderGitea, model.ProviderGit}
 
 type validator struct {
 }
@@ -21,37 +39,93 @@ func NewValidator() model.PromotionConfigValidator {
 func (v validator) Validate(config model.PromotionConfig) (validationErrrors []string) {
 	if config.Spec.Strategy == nil || *config.Spec.Strategy == "" {
 		validationErrrors = append(validationErrrors, `"spec.strategy" missing`)
-	} else if *config.Spec.Strategy != model.StrategyBranch && *config.Spec.Strategy != model.StrategyFlatPR {
+	} else if *config.Spec.Strategy != model.StrategyBranch && *config.Spec.Strategy != model.StrategyFlatPR && *config.Spec.Strategy != model.StrategyDirect && *config.Spec.Strategy != model.StrategyTag {
 		validationErrrors = append(validationErrrors, fmt.Sprintf(`"spec.strategy" %s invalid`, *config.Spec.Strategy))
 	}
-	if config.Spec.Target.Secret == nil || *config.Spec.Target.Secret == "" {
+	isGitProvider := config.Spec.Target.Provider != nil && *config.Spec.Target.Provider == model.ProviderGit
+	if (config.Spec.Target.Secret == nil || *config.Spec.Target.Secret == "") && !isGitProvider {
 		validationErrrors = append(validationErrrors, `"target.secret" missing`)
 	}
 	if config.Spec.Target.Provider == nil || *config.Spec.Target.Provider == "" {
 		validationErrrors = append(validationErrrors, `"target.platform" missing`)
-	} else if *config.Spec.Target.Provider != "github" {
+	} else if !isSupportedProvider(*config.Spec.Target.Provider) {
 		validationErrrors = append(validationErrrors, `target.platform not supported`)
 	}
 	if config.Spec.Target.Repo == nil || *config.Spec.Target.Repo == "" {
 		validationErrrors = append(validationErrrors, `"target.repository" missing`)
+	} else if isGitProvider {
+		if matched, err := regexp.MatchString(gitRemoteRegexp, *config.Spec.Target.Repo); err != nil || !matched {
+			validationErrrors = append(validationErrrors, `"target.repository" must be a "http", "https", "ssh" or "file" url or a scp-like ssh remote (user@host:path)`)
+		}
 	} else {
 		u, err := url.Parse(*config.Spec.Target.Repo)
 		if err != nil {
 			validationErrrors = append(validationErrrors, `"target.repository" is not a valid URL`)
-		} else {
-			if u.Scheme != "https" || u.Host != "github.com" {
-				validationErrrors = append(validationErrrors, `"target.repository" must be a "https" url to a repository on github.com`)
-			} else if matched, err := regexp.MatchString(githubPathRegexp, u.Path); err != nil || !matched {
-				validationErrrors = append(validationErrrors, `"target.repository" must be a "https" url to a repository on github.com`)
+		} else if config.Spec.Target.Provider != nil && isSupportedProvider(*config.Spec.Target.Provider) {
+			if validationError := validateRepositoryUrl(*config.Spec.Target.Provider, u); validationError != "" {
+				validationErrrors = append(validationErrrors, validationError)
 			}
 		}
 	}
+	if config.Spec.Target.DirectPush != nil && *config.Spec.Target.DirectPush && !isGitProvider {
+		validationErrrors = append(validationErrrors, `"target.directPush" is only supported by provider git`)
+	}
+	if config.Spec.Target.Auth != nil && *config.Spec.Target.Auth != "" {
+		switch *config.Spec.Target.Auth {
+		case model.AuthToken:
+		case model.AuthGithubApp:
+			if config.Spec.Target.Provider == nil || *config.Spec.Target.Provider != model.ProviderGithub {
+				validationErrrors = append(validationErrrors, `"target.auth" githubApp is only supported by provider github`)
+			}
+		case model.AuthSSH:
+			if !isGitProvider {
+				validationErrrors = append(validationErrrors, `"target.auth" ssh is only supported by provider git`)
+			} else if config.Spec.Target.Repo != nil && !isSSHRemote(*config.Spec.Target.Repo) {
+				validationErrrors = append(validationErrrors, `"target.auth" ssh needs a "ssh" url or a scp-like ssh remote (user@host:path) as "target.repository"`)
+			} else if config.Spec.Target.Secret == nil || *config.Spec.Target.Secret == "" {
+				validationErrrors = append(validationErrrors, `"target.secret" missing`)
+			}
+		default:
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"target.auth" %s invalid`, *config.Spec.Target.Auth))
+		}
+	}
+	if config.Spec.Target.SecretSource != nil && *config.Spec.Target.SecretSource != "" &&
+		*config.Spec.Target.SecretSource != model.SecretSourceKubernetes && *config.Spec.Target.SecretSource != model.SecretSourceFile && *config.Spec.Target.SecretSource != model.SecretSourceEnv {
+		validationErrrors = append(validationErrrors, fmt.Sprintf(`"target.secretSource" %s invalid`, *config.Spec.Target.SecretSource))
+	}
+	if config.Spec.Target.APIUrl != nil && *config.Spec.Target.APIUrl != "" {
+		if u, err := url.Parse(*config.Spec.Target.APIUrl); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			validationErrrors = append(validationErrrors, `"target.apiUrl" must be a "http" or "https" url`)
+		}
+	}
+	if config.Spec.Strategy != nil && *config.Spec.Strategy == model.StrategyBranch && config.Spec.Target.Branch != nil && *config.Spec.Target.Branch != "" {
+		validationErrrors = append(validationErrrors, `"target.branch" not supported for branch strategy`)
+	}
 	if config.Spec.Strategy != nil && *config.Spec.Strategy == model.StrategyBranch && len(config.Spec.Paths) > 0 {
 		validationErrrors = append(validationErrrors, `no "paths" supported for branch strategy`)
 	}
 	if config.Spec.Strategy != nil && *config.Spec.Strategy == model.StrategyFlatPR && len(config.Spec.Paths) == 0 {
 		validationErrrors = append(validationErrrors, `at least one path is necessary for strategy flat-pr`)
 	}
+	if config.Spec.Strategy != nil && *config.Spec.Strategy == model.StrategyDirect {
+		if len(config.Spec.Paths) == 0 {
+			validationErrrors = append(validationErrrors, `at least one path is necessary for strategy direct`)
+		}
+		if config.Spec.Target.Provider != nil && isSupportedProvider(*config.Spec.Target.Provider) && !contains(directProviders, *config.Spec.Target.Provider) {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`strategy direct is not supported by provider %s`, *config.Spec.Target.Provider))
+		}
+	}
+	if config.Spec.Strategy != nil && *config.Spec.Strategy == model.StrategyTag {
+		if len(config.Spec.Paths) > 0 {
+			validationErrrors = append(validationErrrors, `no "paths" supported for tag strategy`)
+		}
+		if config.Spec.Target.Provider != nil && isSupportedProvider(*config.Spec.Target.Provider) && !contains(tagProviders, *config.Spec.Target.Provider) {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`strategy tag is not supported by provider %s`, *config.Spec.Target.Provider))
+		}
+		if config.Spec.Tag != nil && config.Spec.Tag.Name != nil && !isValidTagName(*config.Spec.Tag.Name) {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"tag.name" %s is not a valid tag name`, *config.Spec.Tag.Name))
+		}
+	}
 	for i, p := range config.Spec.Paths {
 		if p.Target == nil || *p.Target == "" {
 			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].target" is missing`, i))
@@ -67,7 +141,145 @@ func (v validator) Validate(config model.PromotionConfig) (validationErrrors []s
 		if p.Source != nil && *p.Source == *p.Target {
 			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].source" is same as target`, i))
 		}
+		if p.Kustomize != nil {
+			validationErrrors = append(validationErrrors, validateKustomize(i, *p.Kustomize)...)
+		}
+		validationErrrors = append(validationErrrors, validateReplacements(i, p.Replacements)...)
+	}
+	if config.Spec.WaitForMerge != nil && config.Spec.WaitForMerge.Timeout != nil && *config.Spec.WaitForMerge.Timeout != "" {
+		if d, err := time.ParseDuration(*config.Spec.WaitForMerge.Timeout); err != nil || d <= 0 {
+			validationErrrors = append(validationErrrors, `"waitForMerge.timeout" must be a positive duration (e.g. 12h)`)
+		}
+	}
+	if config.Spec.Merge != nil {
+		if m := config.Spec.Merge.Mode; m != nil && *m != "" && *m != model.MergeModeAuto && *m != model.MergeModeManual {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"merge.mode" %s invalid`, *m))
+		}
+		if m := config.Spec.Merge.Method; m != nil && *m != "" && *m != model.MergeMethodMerge && *m != model.MergeMethodSquash && *m != model.MergeMethodRebase {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"merge.method" %s invalid`, *m))
+		}
+		if config.Spec.Target.Provider != nil && *config.Spec.Target.Provider == model.ProviderGit {
+			validationErrrors = append(validationErrrors, `"merge" is not supported by provider git, use "target.directPush"`)
+		}
+		// the merge method of a gitlab project decides whether merge requests are rebased
+		if m := config.Spec.Merge.Method; m != nil && *m == model.MergeMethodRebase && config.Spec.Target.Provider != nil && *config.Spec.Target.Provider == model.ProviderGitlab {
+			validationErrrors = append(validationErrrors, `"merge.method" rebase is not supported by provider gitlab`)
+		}
 	}
 	logger.WithField("func", "validateInputEvent").Infof("validation finished with %d validation errors", len(validationErrrors))
 	return validationErrrors
 }
+
+func isSupportedProvider(provider string) bool {
+	return contains(supportedProviders, provider)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidTagName checks the rules of git check-ref-format which can be violated by a simple name (placeholders are
+// replaced later and are valid)
+func isValidTagName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " ~^:?*[\\") && !strings.Contains(name, "..") && !strings.Contains(name, "@{") &&
+		!strings.HasPrefix(name, "-") && !strings.HasPrefix(name, "/") && !strings.HasSuffix(name, "/") && !strings.HasSuffix(name, ".lock") && !strings.HasSuffix(name, ".")
+}
+
+func isSSHRemote(remote string) bool {
+	matched, err := regexp.MatchString(sshRemoteRegexp, remote)
+	return err == nil && matched
+}
+
+// validateRepositoryUrl checks the provider specific format of the repository url
+func validateRepositoryUrl(provider string, u *url.URL) (validationError string) {
+	switch provider {
+	case model.ProviderGitlab:
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return `"target.repository" must be a "http" or "https" url to a gitlab project`
+		} else if matched, err := regexp.MatchString(gitlabPathRegexp, u.Path); err != nil || !matched {
+			return `"target.repository" must be a "http" or "https" url to a gitlab project`
+		}
+	case model.ProviderBitbucketServer:
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return `"target.repository" must be a "http" or "https" browse or clone url of a bitbucket repository`
+		} else if matched, err := regexp.MatchString(bitbucketServerPathRegexp, u.Path); err != nil || !matched {
+			return `"target.repository" must be a "http" or "https" browse or clone url of a bitbucket repository`
+		}
+	case model.ProviderAzureDevOps:
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return `"target.repository" must be a "http" or "https" url like https://dev.azure.com/<organization>/<project>/_git/<repository>`
+		} else if matched, err := regexp.MatchString(azureDevOpsPathRegexp, u.Path); err != nil || !matched {
+			return `"target.repository" must be a "http" or "https" url like https://dev.azure.com/<organization>/<project>/_git/<repository>`
+		}
+	case model.ProviderGitea:
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return `"target.repository" must be a "http" or "https" url to a gitea repository`
+		} else if matched, err := regexp.MatchString(giteaPathRegexp, u.Path); err != nil || !matched {
+			return `"target.repository" must be a "http" or "https" url to a gitea repository`
+		}
+	default:
+		if u.Scheme != "https" || u.Host == "" {
+			return `"target.repository" must be a "https" url to a repository on github.com or a GitHub Enterprise Server`
+		} else if matched, err := regexp.MatchString(githubPathRegexp, u.Path); err != nil || !matched {
+			return `"target.repository" must be a "https" url to a repository on github.com or a GitHub Enterprise Server`
+		}
+	}
+	return ""
+}
+
+func validateKustomize(path int, kustomize model.Kustomize) (validationErrrors []string) {
+	if len(kustomize.Images) == 0 {
+		validationErrrors = append(validationErrrors, fmt.Sprintf(`at least one image is necessary for "paths[%d].kustomize"`, path))
+	}
+	for i, image := range kustomize.Images {
+		if image.Name == nil || *image.Name == "" {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].kustomize.images[%d].name" is missing`, path, i))
+		}
+		if image.Image != nil && (image.NewName != nil || image.NewTag != nil || image.Digest != nil) {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].kustomize.images[%d].image" can't be combined with "newName", "newTag" or "digest"`, path, i))
+		} else if image.Image == nil && image.NewName == nil && image.NewTag == nil && image.Digest == nil {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].kustomize.images[%d]" needs one of "image", "newName", "newTag" or "digest"`, path, i))
+		}
+		for _, f := range []struct {
+			name  string
+			field *string
+		}{{"image", image.Image}, {"newName", image.NewName}, {"newTag", image.NewTag}, {"digest", image.Digest}} {
+			if f.field == nil {
+				continue
+			}
+			if err := replacer.CheckTemplate(*f.field); err != nil {
+				validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].kustomize.images[%d].%s" %s`, path, i, f.name, err.Error()))
+			}
+		}
+	}
+	return validationErrrors
+}
+
+func validateReplacements(path int, replacements []model.Replacement) (validationErrrors []string) {
+	for i, r := range replacements {
+		if len(r.Files) == 0 {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].replacements[%d].files" is missing`, path, i))
+		}
+		for _, pattern := range r.Files {
+			if _, err := pathpkg.Match(pattern, ""); err != nil {
+				validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].replacements[%d].files" %s is not a valid pattern`, path, i, pattern))
+			}
+		}
+		if r.Path == nil || *r.Path == "" {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].replacements[%d].path" is missing`, path, i))
+		} else if err := replacer.CheckSelector(*r.Path); err != nil {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].replacements[%d].path" %s`, path, i, err.Error()))
+		}
+		if r.Field == nil || *r.Field == "" {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].replacements[%d].field" is missing`, path, i))
+		} else if err := replacer.CheckTemplate(*r.Field); err != nil {
+			validationErrrors = append(validationErrrors, fmt.Sprintf(`"paths[%d].replacements[%d].field" %s`, path, i, err.Error()))
+		}
+	}
+	return validationErrrors
+}
@@ -55,6 +55,513 @@ func Test_Validate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "valid gitlab config with subgroup",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://gitlab.example.com/group/subgroup/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("gitlab"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "gitlab config without project",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://gitlab.com/group"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("gitlab"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.repository" must be a "http" or "https" url to a gitlab project`,
+			},
+		},
+		{
+			name: "valid bitbucket server config with clone url",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://bitbucket.example.com/scm/prj/test.git"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("bitbucket-server"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "bitbucket server config with invalid url",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://bitbucket.example.com/prj/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("bitbucket-server"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.repository" must be a "http" or "https" browse or clone url of a bitbucket repository`,
+			},
+		},
+		{
+			name: "valid azure devops config",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://dev.azure.com/org/project/_git/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("azure-devops"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "valid gitea config",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("http://gitea.gitea.svc:3000/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("gitea"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "valid git config with scp-like remote and without secret",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:       stradr("git@git.example.com:test/test.git"),
+							Provider:   stradr("git"),
+							DirectPush: booladr(true),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "direct push with github",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:       stradr("https://github.com/test/test"),
+							Secret:     stradr("hallosecret"),
+							Provider:   stradr("github"),
+							DirectPush: booladr(true),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.directPush" is only supported by provider git`,
+			},
+		},
+		{
+			name: "unsupported provider",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("svn"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				"target.platform not supported",
+			},
+		},
+		{
+			name: "valid github enterprise config",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.example.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+							APIUrl:   stradr("https://github.example.com/api/v3"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid api url",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.example.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+							APIUrl:   stradr("github.example.com/api/v3"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.apiUrl" must be a "http" or "https" url`,
+			},
+		},
+		{
+			name: "valid github app config",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+							Auth:     stradr("githubApp"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "github app with gitlab",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://gitlab.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("gitlab"),
+							Auth:     stradr("githubApp"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.auth" githubApp is only supported by provider github`,
+			},
+		},
+		{
+			name: "invalid auth",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+							Auth:     stradr("oauth"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.auth" oauth invalid`,
+			},
+		},
+		{
+			name: "valid ssh config",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("git@git.example.com:test/test.git"),
+							Secret:   stradr("deploykey"),
+							Provider: stradr("git"),
+							Auth:     stradr("ssh"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ssh with github",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("deploykey"),
+							Provider: stradr("github"),
+							Auth:     stradr("ssh"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.auth" ssh is only supported by provider git`,
+			},
+		},
+		{
+			name: "ssh with https remote",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://git.example.com/test/test.git"),
+							Secret:   stradr("deploykey"),
+							Provider: stradr("git"),
+							Auth:     stradr("ssh"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.auth" ssh needs a "ssh" url or a scp-like ssh remote (user@host:path) as "target.repository"`,
+			},
+		},
+		{
+			name: "ssh without secret",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("ssh://git@git.example.com/test/test.git"),
+							Provider: stradr("git"),
+							Auth:     stradr("ssh"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.secret" missing`,
+			},
+		},
+		{
+			name: "valid file secret source",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:         stradr("https://github.com/test/test"),
+							Secret:       stradr("hallosecret"),
+							Provider:     stradr("github"),
+							SecretSource: stradr("file"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid secret source",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:         stradr("https://github.com/test/test"),
+							Secret:       stradr("hallosecret"),
+							Provider:     stradr("github"),
+							SecretSource: stradr("vault"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.secretSource" vault invalid`,
+			},
+		},
+		{
+			name: "branch with branch strategy",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+							Branch:   stradr("master"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"target.branch" not supported for branch strategy`,
+			},
+		},
+		{
+			name: "wait for merge with timeout",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						WaitForMerge: &model.WaitForMerge{Enabled: booladr(true), Timeout: stradr("48h")},
+					},
+				},
+			},
+		},
+		{
+			name: "wait for merge with invalid timeout",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						WaitForMerge: &model.WaitForMerge{Enabled: booladr(true), Timeout: stradr("2 days")},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"waitForMerge.timeout" must be a positive duration (e.g. 12h)`,
+			},
+		},
+		{
+			name: "invalid merge",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Merge: &model.Merge{Mode: stradr("always"), Method: stradr("fast-forward")},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"merge.mode" always invalid`,
+				`"merge.method" fast-forward invalid`,
+			},
+		},
+		{
+			name: "merge with provider git",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://git.example.com/test/test.git"),
+							Provider: stradr("git"),
+						},
+						Merge: &model.Merge{Mode: stradr("auto")},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"merge" is not supported by provider git, use "target.directPush"`,
+			},
+		},
+		{
+			name: "rebase with provider gitlab",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("branch"),
+						Target: model.Target{
+							Repo:     stradr("https://gitlab.com/group/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("gitlab"),
+						},
+						Merge: &model.Merge{Mode: stradr("auto"), Method: stradr("rebase")},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"merge.method" rebase is not supported by provider gitlab`,
+			},
+		},
 		{
 			name: "flat-pr config without paths",
 			args: args{
@@ -75,6 +582,191 @@ func Test_Validate(t *testing.T) {
 				"at least one path is necessary for strategy flat-pr",
 			},
 		},
+		{
+			name: "valid direct config",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("direct"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+							Branch:   stradr("main"),
+						},
+						Paths: []model.Path{
+							{
+								Source: stradr("dev"),
+								Target: stradr("staging"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "direct config without paths",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("direct"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				"at least one path is necessary for strategy direct",
+			},
+		},
+		{
+			name: "direct config with bitbucket server",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("direct"),
+						Target: model.Target{
+							Repo:     stradr("https://bitbucket.example.com/projects/PRJ/repos/repo"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("bitbucket-server"),
+						},
+						Paths: []model.Path{
+							{
+								Source: stradr("dev"),
+								Target: stradr("staging"),
+							},
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				"strategy direct is not supported by provider bitbucket-server",
+			},
+		},
+		{
+			name: "direct config with gitea",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("direct"),
+						Target: model.Target{
+							Repo:     stradr("https://gitea.example.com/owner/repo"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("gitea"),
+						},
+						Paths: []model.Path{
+							{
+								Source: stradr("dev"),
+								Target: stradr("staging"),
+							},
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				"strategy direct is not supported by provider gitea",
+			},
+		},
+		{
+			name: "valid tag config",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("tag"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Tag: &model.Tag{
+							Name:   stradr("${nextstage}-${service}"),
+							Source: stradr("${stage}-${service}"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "tag config with paths",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("tag"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Paths: []model.Path{
+							{
+								Target: stradr("staging"),
+							},
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				"no \"paths\" supported for tag strategy",
+			},
+		},
+		{
+			name: "tag config with invalid name",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("tag"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Tag: &model.Tag{
+							Name: stradr("prod..${service}"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				"\"tag.name\" prod..${service} is not a valid tag name",
+			},
+		},
+		{
+			name: "tag config with azure devops",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("tag"),
+						Target: model.Target{
+							Repo:     stradr("https://dev.azure.com/org/project/_git/repo"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("azure-devops"),
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				"strategy tag is not supported by provider azure-devops",
+			},
+		},
 		{
 			name: "flat-pr config with contained paths",
 			args: args{
@@ -105,6 +797,134 @@ func Test_Validate(t *testing.T) {
 				"paths[1].target is already included in paths[0].target",
 			},
 		},
+		{
+			name: "flat-pr config with kustomize images",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("flat-pr"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Paths: []model.Path{
+							{
+								Target: stradr("overlays/production"),
+								Kustomize: &model.Kustomize{Images: []model.KustomizeImage{
+									{Name: stradr("podtato-head"), Image: stradr("data.configurationChange.values.image")},
+									{Name: stradr("other"), NewTag: stradr("data.tag"), Digest: stradr("data.digest")},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "flat-pr config with replacements",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("flat-pr"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Paths: []model.Path{
+							{
+								Target: stradr("charts/staging"),
+								Replacements: []model.Replacement{
+									{Files: []string{"values.yaml"}, Path: stradr("image.tag"), Field: stradr("data.image.tag")},
+									{Files: []string{"charts/*/values-*.yaml"}, Path: stradr("$.sidecars[0].image"), Field: stradr("data.sidecar")},
+									{Files: []string{"dashboards/*.json"}, Path: stradr("/panels/0/title"), Field: stradr("data.title")},
+									{Files: []string{"values.yaml"}, Path: stradr("image.tag"), Field: stradr(`{{ .data.tag | default "latest" | trimPrefix "v" }}`)},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "flat-pr config with invalid replacements",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("flat-pr"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Paths: []model.Path{
+							{
+								Target: stradr("charts/staging"),
+								Replacements: []model.Replacement{
+									{},
+									{Files: []string{"[values.yaml"}, Path: stradr("image..tag"), Field: stradr("data.image.tag")},
+									{Files: []string{"*.json"}, Path: stradr("/panels/~2"), Field: stradr("data.title")},
+									{Files: []string{"*.json"}, Path: stradr("/panels/0/title"), Field: stradr("{{ .data.title | lower }")},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`"paths[0].replacements[0].files" is missing`,
+				`"paths[0].replacements[0].path" is missing`,
+				`"paths[0].replacements[0].field" is missing`,
+				`"paths[0].replacements[1].files" [values.yaml is not a valid pattern`,
+				`"paths[0].replacements[1].path" invalid selector image..tag: empty key`,
+				`"paths[0].replacements[2].path" invalid selector /panels/~2: invalid escape in ~2`,
+				`"paths[0].replacements[3].field" template: value:1: unexpected "}" in operand`,
+			},
+		},
+		{
+			name: "flat-pr config with invalid kustomize images",
+			args: args{
+				config: model.PromotionConfig{
+					APIVersion: stradr("keptn.sh/v1"),
+					Kind:       stradr("GitPromotionConfig"),
+					Spec: model.PromotionConfigSpec{
+						Strategy: stradr("flat-pr"),
+						Target: model.Target{
+							Repo:     stradr("https://github.com/test/test"),
+							Secret:   stradr("hallosecret"),
+							Provider: stradr("github"),
+						},
+						Paths: []model.Path{
+							{
+								Target:    stradr("overlays/staging"),
+								Kustomize: &model.Kustomize{},
+							},
+							{
+								Target: stradr("overlays/production"),
+								Kustomize: &model.Kustomize{Images: []model.KustomizeImage{
+									{Image: stradr("data.configurationChange.values.image")},
+									{Name: stradr("podtato-head"), Image: stradr("data.image"), NewTag: stradr("data.tag")},
+									{Name: stradr("other")},
+								}},
+							},
+						},
+					},
+				},
+			},
+			wantValidationErrrors: []string{
+				`at least one image is necessary for "paths[0].kustomize"`,
+				`"paths[1].kustomize.images[0].name" is missing`,
+				`"paths[1].kustomize.images[1].image" can't be combined with "newName", "newTag" or "digest"`,
+				`"paths[1].kustomize.images[2]" needs one of "image", "newName", "newTag" or "digest"`,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -119,3 +939,7 @@ func Test_Validate(t *testing.T) {
 func stradr(str string) *string {
 	return &str
 }
+
+func booladr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/pending"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"keptn/git-promotion-service/pkg/secrets"
+	"keptn/git-promotion-service/pkg/webhook"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	keptncommon "github.com/keptn/go-utils/pkg/lib/keptn"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	logger "github.com/sirupsen/logrus"
+)
+
+// MergeWatcher polls the pull requests of pending promotions and sends their finished event once the pull request is
+// merged (pass), closed (fail) or the deadline of the promotion has passed (fail). Webhooks passed to
+// HandlePullRequestEvent finish promotions without waiting for the next poll.
+type MergeWatcher struct {
+	// mu prevents finishing a promotion twice by a poll and a webhook
+	mu        sync.Mutex
+	store     pending.Store
+	secrets   secrets.Providers
+	interval  time.Duration
+	newClient func(target model.Target, credentials repoaccess.Credentials) (repoaccess.Client, error)
+	send      func(promotion pending.Promotion, event cloudevents.Event) error
+}
+
+// NewMergeWatcher returns a MergeWatcher checking the promotions of store every interval
+func NewMergeWatcher(store pending.Store, secrets secrets.Providers, interval time.Duration) *MergeWatcher {
+	return &MergeWatcher{store: store, secrets: secrets, interval: interval, newClient: repoaccess.NewClient, send: sendFinishedEvent}
+}
+
+// Run checks the pending promotions until ctx is done, promotions saved before a restart are picked up by the first check
+func (w *MergeWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.checkPromotions(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *MergeWatcher) checkPromotions(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	promotions, err := w.store.List()
+	if err != nil {
+		logger.WithField("func", "checkPromotions").WithError(err).Error("could not list pending promotions")
+		return
+	}
+	for _, p := range promotions {
+		if err := w.checkPromotion(p, now); err != nil {
+			// errors are retried with the next check
+			logger.WithField("func", "checkPromotions").WithError(err).Errorf("checking pull request %s of promotion %s failed", p.PullRequest.URL, p.TriggeredID)
+		}
+	}
+}
+
+func (w *MergeWatcher) checkPromotion(p pending.Promotion, now time.Time) error {
+	state, err := w.getPullRequestState(p)
+	if err != nil && !now.After(p.Deadline) {
+		return err
+	}
+	if err == nil && state != repoaccess.PullRequestOpen {
+		return w.finishPromotion(p, state)
+	} else if now.After(p.Deadline) {
+		return w.sendFinishedEvent(p, keptnv2.ResultFailed, fmt.Sprintf("pull request %s not merged until %s", p.PullRequest.URL, p.Deadline.Format(time.RFC3339)))
+	}
+	logger.WithField("func", "checkPromotion").Debugf("pull request %s of promotion %s is still open", p.PullRequest.URL, p.TriggeredID)
+	return nil
+}
+
+// HandlePullRequestEvent finishes the pending promotion of a merged or closed pull request, the promotion is found by the
+// keptn context in the branch name or title of the pull request
+func (w *MergeWatcher) HandlePullRequestEvent(e webhook.PullRequestEvent) error {
+	if e.State == repoaccess.PullRequestOpen {
+		return nil
+	}
+	keptnContext := getKeptnContext(e.SourceBranch, e.Title)
+	if keptnContext == "" {
+		logger.WithField("func", "HandlePullRequestEvent").Debugf("pull request %s was not opened by a promotion", e.URL)
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	promotions, err := w.store.List()
+	if err != nil {
+		return err
+	}
+	for _, p := range promotions {
+		if p.KeptnContext == keptnContext && p.PullRequest.Number == e.Number {
+			return w.finishPromotion(p, e.State)
+		}
+	}
+	logger.WithField("func", "HandlePullRequestEvent").Infof("no pending promotion found for pull request %s of keptn context %s", e.URL, keptnContext)
+	return nil
+}
+
+func (w *MergeWatcher) finishPromotion(p pending.Promotion, state repoaccess.PullRequestState) error {
+	if state == repoaccess.PullRequestMerged {
+		return w.sendFinishedEvent(p, keptnv2.ResultPass, fmt.Sprintf("pull request %s merged", p.PullRequest.URL))
+	}
+	return w.sendFinishedEvent(p, keptnv2.ResultFailed, fmt.Sprintf("pull request %s closed without merge", p.PullRequest.URL))
+}
+
+// sendFinishedEvent sends the finished event and removes the promotion from the store
+func (w *MergeWatcher) sendFinishedEvent(p pending.Promotion, result keptnv2.ResultType, message string) error {
+	logger.WithField("func", "sendFinishedEvent").Infof("finishing promotion %s with result %s: %s", p.TriggeredID, result, message)
+	event := getGitPromotionFinishedEvent(p.Data, keptnv2.StatusSucceeded, result, message, p.TriggeredID, p.KeptnContext, &p.PullRequest)
+	if err := w.send(p, *event); err != nil {
+		return err
+	}
+	return w.store.Delete(p.TriggeredID)
+}
+
+func (w *MergeWatcher) getPullRequestState(p pending.Promotion) (state repoaccess.PullRequestState, err error) {
+	credentials, err := getCredentials(w.secrets, p.Target)
+	if err != nil {
+		return "", err
+	}
+	client, err := w.newClient(p.Target, credentials)
+	if err != nil {
+		return "", err
+	}
+	if closer, ok := client.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return client.GetPullRequestState(p.PullRequest.Number)
+}
+
+func sendFinishedEvent(p pending.Promotion, event cloudevents.Event) error {
+	keptnHandler, err := keptnv2.NewKeptn(&p.Event, keptncommon.KeptnOpts{})
+	if err != nil {
+		return err
+	}
+	return keptnHandler.SendCloudEvent(event)
+}
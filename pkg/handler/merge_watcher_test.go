@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"errors"
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/pending"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"keptn/git-promotion-service/pkg/webhook"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// mapStore is an in-memory pending.Store
+type mapStore map[string]pending.Promotion
+
+func (s mapStore) Save(promotion pending.Promotion) error {
+	s[promotion.TriggeredID] = promotion
+	return nil
+}
+
+func (s mapStore) Delete(triggeredID string) error {
+	delete(s, triggeredID)
+	return nil
+}
+
+func (s mapStore) List() (promotions []pending.Promotion, err error) {
+	for _, p := range s {
+		promotions = append(promotions, p)
+	}
+	return promotions, nil
+}
+
+// pullRequestStateClient only implements GetPullRequestState
+type pullRequestStateClient struct {
+	repoaccess.Client
+	state repoaccess.PullRequestState
+	err   error
+}
+
+func (c pullRequestStateClient) GetPullRequestState(number int) (repoaccess.PullRequestState, error) {
+	return c.state, c.err
+}
+
+func TestMergeWatcher_checkPromotions(t *testing.T) {
+	deadline := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		client      pullRequestStateClient
+		sendErr     error
+		now         time.Time
+		wantResult  keptnv2.ResultType
+		wantPending bool
+	}{
+		{
+			name:       "merged",
+			client:     pullRequestStateClient{state: repoaccess.PullRequestMerged},
+			now:        deadline.Add(-time.Hour),
+			wantResult: keptnv2.ResultPass,
+		},
+		{
+			name:       "closed",
+			client:     pullRequestStateClient{state: repoaccess.PullRequestClosed},
+			now:        deadline.Add(-time.Hour),
+			wantResult: keptnv2.ResultFailed,
+		},
+		{
+			name:        "open",
+			client:      pullRequestStateClient{state: repoaccess.PullRequestOpen},
+			now:         deadline.Add(-time.Hour),
+			wantPending: true,
+		},
+		{
+			name:       "open after deadline",
+			client:     pullRequestStateClient{state: repoaccess.PullRequestOpen},
+			now:        deadline.Add(time.Second),
+			wantResult: keptnv2.ResultFailed,
+		},
+		{
+			name:        "provider error is retried",
+			client:      pullRequestStateClient{err: errors.New("unavailable")},
+			now:         deadline.Add(-time.Hour),
+			wantPending: true,
+		},
+		{
+			name:       "provider error after deadline",
+			client:     pullRequestStateClient{err: errors.New("unavailable")},
+			now:        deadline.Add(time.Second),
+			wantResult: keptnv2.ResultFailed,
+		},
+		{
+			name:        "send error is retried",
+			client:      pullRequestStateClient{state: repoaccess.PullRequestMerged},
+			sendErr:     errors.New("unavailable"),
+			now:         deadline.Add(-time.Hour),
+			wantPending: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := mapStore{"triggered": pending.Promotion{
+				TriggeredID:  "triggered",
+				KeptnContext: "ctx",
+				Data:         keptnv2.EventData{Project: "podtato", Stage: "dev", Service: "hello"},
+				PullRequest:  repoaccess.PullRequest{Number: 1, URL: "https://github.com/owner/repo/pull/1"},
+				Deadline:     deadline,
+			}}
+			var sent []cloudevents.Event
+			w := NewMergeWatcher(store, nil, time.Minute)
+			w.newClient = func(target model.Target, credentials repoaccess.Credentials) (repoaccess.Client, error) {
+				return tt.client, nil
+			}
+			w.send = func(p pending.Promotion, event cloudevents.Event) error {
+				if tt.sendErr != nil {
+					return tt.sendErr
+				}
+				sent = append(sent, event)
+				return nil
+			}
+			w.checkPromotions(tt.now)
+			if _, ok := store["triggered"]; ok != tt.wantPending {
+				t.Errorf("promotion pending = %v, want %v", ok, tt.wantPending)
+			}
+			if tt.wantResult == "" {
+				if len(sent) != 0 {
+					t.Errorf("sent %d events, want none", len(sent))
+				}
+				return
+			}
+			if len(sent) != 1 {
+				t.Fatalf("sent %d events, want 1", len(sent))
+			}
+			data := keptnv2.EventData{}
+			if err := sent[0].DataAs(&data); err != nil {
+				t.Fatal(err)
+			}
+			if sent[0].Type() != keptnv2.GetFinishedEventType(GitPromotionTaskName) || sent[0].Extensions()["triggeredid"] != "triggered" ||
+				data.Result != tt.wantResult || data.Status != keptnv2.StatusSucceeded || data.Labels["pullrequest"] != "https://github.com/owner/repo/pull/1" {
+				t.Errorf("sent unexpected event %s with data %+v", sent[0].Type(), data)
+			}
+		})
+	}
+}
+
+func TestMergeWatcher_HandlePullRequestEvent(t *testing.T) {
+	const keptnContext = "8d0c5f6e-1b7a-4c1e-9a59-1c2b3d4e5f60"
+	tests := []struct {
+		name        string
+		event       webhook.PullRequestEvent
+		wantResult  keptnv2.ResultType
+		wantPending bool
+	}{
+		{
+			name:       "merged flat-pr branch",
+			event:      webhook.PullRequestEvent{Number: 1, SourceBranch: buildBranchName("dev", "prod", keptnContext), State: repoaccess.PullRequestMerged},
+			wantResult: keptnv2.ResultPass,
+		},
+		{
+			name:       "closed branch strategy pull request",
+			event:      webhook.PullRequestEvent{Number: 1, SourceBranch: "dev", Title: buildTitle(keptnContext, "prod"), State: repoaccess.PullRequestClosed},
+			wantResult: keptnv2.ResultFailed,
+		},
+		{
+			name:        "opened",
+			event:       webhook.PullRequestEvent{Number: 1, SourceBranch: buildBranchName("dev", "prod", keptnContext), State: repoaccess.PullRequestOpen},
+			wantPending: true,
+		},
+		{
+			name:        "other pull request number",
+			event:       webhook.PullRequestEvent{Number: 2, SourceBranch: buildBranchName("dev", "prod", keptnContext), State: repoaccess.PullRequestMerged},
+			wantPending: true,
+		},
+		{
+			name:        "unrelated pull request",
+			event:       webhook.PullRequestEvent{Number: 1, SourceBranch: "feature", Title: "fix", State: repoaccess.PullRequestMerged},
+			wantPending: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := mapStore{"triggered": pending.Promotion{
+				TriggeredID:  "triggered",
+				KeptnContext: keptnContext,
+				PullRequest:  repoaccess.PullRequest{Number: 1},
+				Deadline:     time.Now().Add(time.Hour),
+			}}
+			var sent []cloudevents.Event
+			w := NewMergeWatcher(store, nil, time.Minute)
+			w.send = func(p pending.Promotion, event cloudevents.Event) error {
+				sent = append(sent, event)
+				return nil
+			}
+			if err := w.HandlePullRequestEvent(tt.event); err != nil {
+				t.Fatalf("HandlePullRequestEvent() error = %v", err)
+			}
+			if _, ok := store["triggered"]; ok != tt.wantPending {
+				t.Errorf("promotion pending = %v, want %v", ok, tt.wantPending)
+			}
+			if tt.wantPending {
+				if len(sent) != 0 {
+					t.Errorf("sent %d events, want none", len(sent))
+				}
+				return
+			}
+			data := keptnv2.EventData{}
+			if len(sent) != 1 || sent[0].DataAs(&data) != nil || data.Result != tt.wantResult {
+				t.Errorf("sent %v with data %+v, want result %s", sent, data, tt.wantResult)
+			}
+		})
+	}
+}
+
+func Test_getKeptnContext(t *testing.T) {
+	const keptnContext = "8d0c5f6e-1b7a-4c1e-9a59-1c2b3d4e5f60"
+	tests := []struct {
+		name   string
+		branch string
+		title  string
+		want   string
+	}{
+		{name: "flat-pr branch", branch: buildBranchName("dev-eu", "prod-eu", keptnContext), want: keptnContext},
+		{name: "branch strategy title", branch: "dev", title: buildTitle(keptnContext, "prod"), want: keptnContext},
+		{name: "other branch", branch: "promote/manual", title: "Promote to stage prod"},
+		{name: "title without prefix", branch: "dev", title: "Promote to stage prod (ctx: abc)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getKeptnContext(tt.branch, tt.title); got != tt.want {
+				t.Errorf("getKeptnContext() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,22 @@
 package handler
 
 import (
-	"context"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	promotionconfig "keptn/git-promotion-service/pkg/config"
 	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/pending"
 	"keptn/git-promotion-service/pkg/promoter"
 	"keptn/git-promotion-service/pkg/replacer"
 	"keptn/git-promotion-service/pkg/repoaccess"
+	"keptn/git-promotion-service/pkg/secrets"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/keptn/go-utils/pkg/api/models"
@@ -18,8 +24,6 @@ import (
 	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
 	logger "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 )
 
 const GitPromotionTaskName = "git-promotion"
@@ -27,18 +31,20 @@ const keptnPullRequestTitlePrefix = "keptn:"
 const configurationResource = GitPromotionTaskName + ".yaml"
 
 type GitPromotionTriggeredEventHandler struct {
-	keptn      *keptnv2.Keptn
-	api        *api.APISet
-	kubeClient *kubernetes.Clientset
+	keptn   *keptnv2.Keptn
+	api     *api.APISet
+	secrets secrets.Providers
+	pending pending.Store
 }
 
 type GitPromotionTriggeredEventData struct {
 	keptnv2.EventData
 }
 
-// NewGitPromotionTriggeredEventHandler returns a new GitPromotionTriggeredEventHandler
-func NewGitPromotionTriggeredEventHandler(keptn *keptnv2.Keptn, api *api.APISet, kubeClient *kubernetes.Clientset) *GitPromotionTriggeredEventHandler {
-	return &GitPromotionTriggeredEventHandler{keptn: keptn, api: api, kubeClient: kubeClient}
+// NewGitPromotionTriggeredEventHandler returns a new GitPromotionTriggeredEventHandler, promotions waiting for the merge
+// of their pull request are saved to pending
+func NewGitPromotionTriggeredEventHandler(keptn *keptnv2.Keptn, api *api.APISet, secrets secrets.Providers, pending pending.Store) *GitPromotionTriggeredEventHandler {
+	return &GitPromotionTriggeredEventHandler{keptn: keptn, api: api, secrets: secrets, pending: pending}
 }
 
 // IsTypeHandled godoc
@@ -62,13 +68,13 @@ func (a *GitPromotionTriggeredEventHandler) handleGitPromotionTriggeredEvent(eve
 	logger.WithField("func", "handleGitPromotionTriggeredEvent").Infof("start promoting service %s in project %s from stage %s", inputEvent.Service, inputEvent.Stage, inputEvent.Project)
 	if err := a.keptn.SendCloudEvent(*a.getGitPromotionStartedEvent(inputEvent, triggeredID, shkeptncontext)); err != nil {
 		logger.WithField("func", "handleGitPromotionTriggeredEvent").WithError(err).Errorf("sending started event failed")
-		return []cloudevents.Event{*a.getGitPromotionFinishedEvent(inputEvent, keptnv2.StatusErrored, keptnv2.ResultFailed, "sending starting event failed", triggeredID, shkeptncontext, nil)}
+		return []cloudevents.Event{*getGitPromotionFinishedEvent(inputEvent.EventData, keptnv2.StatusErrored, keptnv2.ResultFailed, "sending starting event failed", triggeredID, shkeptncontext, nil)}
 	}
 	outgoingEvents := make([]cloudevents.Event, 0)
 	var nextStage string
 	if nextStageTemp, err := a.getNextStage(inputEvent.Project, inputEvent.Stage); err != nil {
 		logger.WithField("func", "handleGitPromotionTriggeredEvent").WithError(err).Error("handleGitPromotionTriggeredEvent: error while reading nextStage")
-		return []cloudevents.Event{*a.getGitPromotionFinishedEvent(inputEvent, keptnv2.StatusErrored, keptnv2.ResultFailed, "error while reading nextStage", triggeredID, shkeptncontext, nil)}
+		return []cloudevents.Event{*getGitPromotionFinishedEvent(inputEvent.EventData, keptnv2.StatusErrored, keptnv2.ResultFailed, "error while reading nextStage", triggeredID, shkeptncontext, nil)}
 	} else {
 		nextStage = nextStageTemp
 	}
@@ -77,56 +83,184 @@ func (a *GitPromotionTriggeredEventHandler) handleGitPromotionTriggeredEvent(eve
 	var status keptnv2.StatusType
 	var result keptnv2.ResultType
 	var message string
-	var prLink *string
+	var pr *repoaccess.PullRequest
 	if vs := promotionconfig.NewValidator().Validate(config); len(vs) > 0 {
 		logger.WithField("func", "handleGitPromotionTriggeredEvent").Errorf("validation of configuration failed: %s", strings.Join(vs, ","))
 		status = keptnv2.StatusErrored
 		result = keptnv2.ResultFailed
 		message = "validation error: " + strings.Join(vs, ",")
-	} else if accessToken, err := a.getAccessToken(*config.Spec.Target.Secret); err != nil {
-		logger.WithField("func", "handleGitPromotionTriggeredEvent").WithError(err).Errorf("handleGitPromotionTriggeredEvent: error while reading secret with name %s", *config.Spec.Target.Secret)
+	} else if credentials, err := getCredentials(a.secrets, config.Spec.Target); err != nil {
+		logger.WithField("func", "handleGitPromotionTriggeredEvent").WithError(err).Errorf("handleGitPromotionTriggeredEvent: error while reading secret with name %s", toString(config.Spec.Target.Secret))
 		status = keptnv2.StatusErrored
 		result = keptnv2.ResultFailed
 		message = "error while reading secret"
-	} else if client, err := repoaccess.NewClient(accessToken, *config.Spec.Target.Repo); err != nil {
+	} else if client, err := repoaccess.NewClient(config.Spec.Target, credentials); err != nil {
 		logger.WithField("func", "handleGitPromotionTriggeredEvent").WithError(err).Errorf("handleGitPromotionTriggeredEvent: error while creating client for repo")
 		status = keptnv2.StatusErrored
 		result = keptnv2.ResultFailed
-		message = "error while reading secret"
-	} else if *config.Spec.Strategy == model.StrategyBranch {
-		status, result, message, prLink = handleBranchStrategy(client, inputEvent, config, shkeptncontext, nextStage)
-	} else if *config.Spec.Strategy == model.StrategyFlatPR {
-		status, result, message, prLink = handleFlatPRStrategy(client, event, inputEvent, config, shkeptncontext, nextStage)
+		message = "error while creating client for repository"
 	} else {
-		status = keptnv2.StatusErrored
-		result = keptnv2.ResultFailed
-		message = "unimplemented strategy"
+		if closer, ok := client.(io.Closer); ok {
+			defer closer.Close()
+		}
+		if *config.Spec.Strategy == model.StrategyBranch {
+			status, result, message, pr = handleBranchStrategy(client, inputEvent, config, shkeptncontext, nextStage)
+		} else if *config.Spec.Strategy == model.StrategyFlatPR {
+			status, result, message, pr = handleFlatPRStrategy(client, event, inputEvent, config, shkeptncontext, nextStage)
+		} else if *config.Spec.Strategy == model.StrategyDirect {
+			status, result, message = handleDirectStrategy(client, event, config)
+		} else if *config.Spec.Strategy == model.StrategyTag {
+			status, result, message = handleTagStrategy(client, config, shkeptncontext, nextStage)
+		} else {
+			status = keptnv2.StatusErrored
+			result = keptnv2.ResultFailed
+			message = "unimplemented strategy"
+		}
+		if status == keptnv2.StatusSucceeded && pr != nil && isAutoMergeEnabled(config) {
+			status, result, message = handleAutoMerge(client, config, pr, message)
+		}
 	}
-	finishedEvent := a.getGitPromotionFinishedEvent(inputEvent, status, result, message, triggeredID, shkeptncontext, prLink)
+	if status == keptnv2.StatusSucceeded && pr != nil && !pr.Merged && isWaitForMergeEnabled(config) {
+		if err := a.savePendingPromotion(event, inputEvent, config, *pr, triggeredID, shkeptncontext); err != nil {
+			logger.WithField("func", "handleGitPromotionTriggeredEvent").WithError(err).Errorf("saving pending promotion for pull request %s failed", pr.URL)
+			status = keptnv2.StatusErrored
+			result = keptnv2.ResultFailed
+			message = "error while saving pending promotion"
+		} else {
+			logger.WithField("func", "handleGitPromotionTriggeredEvent").Infof("waiting for merge of pull request %s", pr.URL)
+			return outgoingEvents
+		}
+	}
+	finishedEvent := getGitPromotionFinishedEvent(inputEvent.EventData, status, result, message, triggeredID, shkeptncontext, pr)
 	outgoingEvents = append(outgoingEvents, *finishedEvent)
 	return outgoingEvents
 }
 
-func handleFlatPRStrategy(client repoaccess.Client, event cloudevents.Event, inputEvent GitPromotionTriggeredEventData, config model.PromotionConfig, shkeptncontext, nextStage string) (status keptnv2.StatusType, result keptnv2.ResultType, message string, prLink *string) {
+func isAutoMergeEnabled(config model.PromotionConfig) bool {
+	return config.Spec.Merge != nil && config.Spec.Merge.Mode != nil && *config.Spec.Merge.Mode == model.MergeModeAuto
+}
+
+// handleAutoMerge merges pull requests opened by the service, unmanaged pull requests are never merged
+func handleAutoMerge(client repoaccess.Client, config model.PromotionConfig, pr *repoaccess.PullRequest, message string) (status keptnv2.StatusType, result keptnv2.ResultType, msg string) {
+	if !strings.HasPrefix(pr.Title, keptnPullRequestTitlePrefix) {
+		return keptnv2.StatusSucceeded, keptnv2.ResultPass, message
+	}
+	method := model.MergeMethodMerge
+	if config.Spec.Merge.Method != nil && *config.Spec.Merge.Method != "" {
+		method = *config.Spec.Merge.Method
+	}
+	if err := client.MergePullRequest(pr, method); err != nil {
+		logger.WithField("func", "handleAutoMerge").WithError(err).Errorf("merging pull request %s failed", pr.URL)
+		return keptnv2.StatusErrored, keptnv2.ResultFailed, "error while merging pull request"
+	} else if !pr.Merged {
+		logger.WithField("func", "handleAutoMerge").Infof("enabled auto-merge of pull request %s", pr.URL)
+		return keptnv2.StatusSucceeded, keptnv2.ResultPass, message + ", enabled auto-merge"
+	}
+	logger.WithField("func", "handleAutoMerge").Infof("merged pull request %s with commit %s", pr.URL, pr.MergeCommitSHA)
+	return keptnv2.StatusSucceeded, keptnv2.ResultPass, message + ", merged pull request"
+}
+
+func isWaitForMergeEnabled(config model.PromotionConfig) bool {
+	return config.Spec.WaitForMerge != nil && config.Spec.WaitForMerge.Enabled != nil && *config.Spec.WaitForMerge.Enabled
+}
+
+// savePendingPromotion stores the promotion, the finished event is sent by the MergeWatcher
+func (a *GitPromotionTriggeredEventHandler) savePendingPromotion(event cloudevents.Event, inputEvent GitPromotionTriggeredEventData, config model.PromotionConfig,
+	pr repoaccess.PullRequest, triggeredID, shkeptncontext string) error {
+	if a.pending == nil {
+		return errors.New("no store for pending promotions configured")
+	}
+	timeout := model.DefaultWaitForMergeTimeout
+	if config.Spec.WaitForMerge.Timeout != nil && *config.Spec.WaitForMerge.Timeout != "" {
+		timeout = *config.Spec.WaitForMerge.Timeout
+	}
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return err
+	}
+	return a.pending.Save(pending.Promotion{
+		TriggeredID:  triggeredID,
+		KeptnContext: shkeptncontext,
+		Event:        event,
+		Data:         inputEvent.EventData,
+		Target:       config.Spec.Target,
+		PullRequest:  pr,
+		Deadline:     time.Now().Add(duration),
+	})
+}
+
+func handleFlatPRStrategy(client repoaccess.Client, event cloudevents.Event, inputEvent GitPromotionTriggeredEventData, config model.PromotionConfig, shkeptncontext, nextStage string) (status keptnv2.StatusType, result keptnv2.ResultType, message string, pr *repoaccess.PullRequest) {
+	baseBranch, err := getBaseBranch(client, config.Spec.Target)
+	if err != nil {
+		logger.WithField("func", "handleFlatPRStrategy").WithError(err).Errorf("could not determine base branch of repository %s", *config.Spec.Target.Repo)
+		return keptnv2.StatusErrored, keptnv2.ResultFailed, "error while reading default branch", nil
+	}
 	p := promoter.NewFlatPrPromoter(client)
-	if msg, prlink, err := p.Promote(*config.Spec.Target.Repo, replacer.ConvertToMap(event), "main",
+	if msg, pr, err := p.Promote(*config.Spec.Target.Repo, replacer.ConvertToMap(event), baseBranch,
 		buildBranchName(inputEvent.Stage, nextStage, shkeptncontext),
 		buildTitle(shkeptncontext, nextStage),
 		buildBody(shkeptncontext, inputEvent.Project, inputEvent.Service, inputEvent.Stage), config.Spec.Paths); err != nil {
 		logger.WithField("func", "handleFlatPRStrategy").WithError(err).Errorf("flat pr strategy failed on repository %s", *config.Spec.Target.Repo)
 		return keptnv2.StatusErrored, keptnv2.ResultFailed, "error while opening pull request", nil
 	} else {
-		return keptnv2.StatusSucceeded, keptnv2.ResultPass, msg, prlink
+		return keptnv2.StatusSucceeded, keptnv2.ResultPass, msg, pr
 	}
 }
 
-func handleBranchStrategy(client repoaccess.Client, inputEvent GitPromotionTriggeredEventData, config model.PromotionConfig, shkeptncontext, nextStage string) (status keptnv2.StatusType, result keptnv2.ResultType, message string, prLink *string) {
+func handleDirectStrategy(client repoaccess.Client, event cloudevents.Event, config model.PromotionConfig) (status keptnv2.StatusType, result keptnv2.ResultType, message string) {
+	branch, err := getBaseBranch(client, config.Spec.Target)
+	if err != nil {
+		logger.WithField("func", "handleDirectStrategy").WithError(err).Errorf("could not determine branch of repository %s", *config.Spec.Target.Repo)
+		return keptnv2.StatusErrored, keptnv2.ResultFailed, "error while reading default branch"
+	}
+	p := promoter.NewDirectPromoter(client)
+	if msg, err := p.Promote(*config.Spec.Target.Repo, replacer.ConvertToMap(event), branch, config.Spec.Paths); err != nil {
+		logger.WithField("func", "handleDirectStrategy").WithError(err).Errorf("direct strategy failed on repository %s", *config.Spec.Target.Repo)
+		return keptnv2.StatusErrored, keptnv2.ResultFailed, "error while pushing changes"
+	} else {
+		return keptnv2.StatusSucceeded, keptnv2.ResultPass, msg
+	}
+}
+
+func handleTagStrategy(client repoaccess.Client, config model.PromotionConfig, shkeptncontext, nextStage string) (status keptnv2.StatusType, result keptnv2.ResultType, message string) {
+	tagClient, ok := client.(repoaccess.TagClient)
+	if !ok {
+		logger.WithField("func", "handleTagStrategy").Errorf("provider %s does not support tags", toString(config.Spec.Target.Provider))
+		return keptnv2.StatusErrored, keptnv2.ResultFailed, "tags are not supported by the provider"
+	}
+	p := promoter.NewTagPromoter(tagClient)
+	if msg, err := p.Promote(*config.Spec.Target.Repo, *config.Spec.Tag.Source, *config.Spec.Tag.Name, buildTitle(shkeptncontext, nextStage)); errors.Is(err, promoter.ErrTagMovesBackwards) {
+		logger.WithField("func", "handleTagStrategy").WithError(err).Warnf("refusing to move tag on repository %s", *config.Spec.Target.Repo)
+		return keptnv2.StatusSucceeded, keptnv2.ResultFailed, err.Error()
+	} else if err != nil {
+		logger.WithField("func", "handleTagStrategy").WithError(err).Errorf("tag strategy failed on repository %s", *config.Spec.Target.Repo)
+		return keptnv2.StatusErrored, keptnv2.ResultFailed, "error while moving tag"
+	} else {
+		return keptnv2.StatusSucceeded, keptnv2.ResultPass, msg
+	}
+}
+
+// getBaseBranch returns the configured branch or the default branch of the repository
+func getBaseBranch(client repoaccess.Client, target model.Target) (branch string, err error) {
+	if target.Branch != nil && *target.Branch != "" {
+		return *target.Branch, nil
+	}
+	if branch, err = client.DefaultBranch(); err != nil {
+		return "", err
+	} else if branch == "" {
+		return "", errors.New(fmt.Sprintf("repository %s has no default branch", toString(target.Repo)))
+	}
+	logger.WithField("func", "getBaseBranch").Infof("using default branch %s of repository %s", branch, toString(target.Repo))
+	return branch, nil
+}
+
+func handleBranchStrategy(client repoaccess.Client, inputEvent GitPromotionTriggeredEventData, config model.PromotionConfig, shkeptncontext, nextStage string) (status keptnv2.StatusType, result keptnv2.ResultType, message string, pr *repoaccess.PullRequest) {
 	p := promoter.NewBranchPromoter(client, keptnPullRequestTitlePrefix)
-	if msg, prLink, err := p.Promote(*config.Spec.Target.Repo, inputEvent.Stage, nextStage, buildTitle(shkeptncontext, nextStage), buildBody(shkeptncontext, inputEvent.Project, inputEvent.Service, inputEvent.Stage)); err != nil {
+	if msg, pr, err := p.Promote(*config.Spec.Target.Repo, inputEvent.Stage, nextStage, buildTitle(shkeptncontext, nextStage), buildBody(shkeptncontext, inputEvent.Project, inputEvent.Service, inputEvent.Stage)); err != nil {
 		logger.WithField("func", "handleBranchStrategy").WithError(err).Errorf("branch strategy failed on repository %s", *config.Spec.Target.Repo)
 		return keptnv2.StatusErrored, keptnv2.ResultFailed, "error while opening pull request", nil
 	} else {
-		return keptnv2.StatusSucceeded, keptnv2.ResultPass, msg, prLink
+		return keptnv2.StatusSucceeded, keptnv2.ResultPass, msg, pr
 	}
 }
 
@@ -146,6 +280,20 @@ func buildBranchName(stage string, nextStage string, shkeptncontext string) stri
 	return fmt.Sprintf("promote/%s_%s-%s", stage, nextStage, shkeptncontext)
 }
 
+var branchNameKeptnContext = regexp.MustCompile(`^promote/[^_]+_.+-([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+var titleKeptnContext = regexp.MustCompile(`^` + keptnPullRequestTitlePrefix + ` Promote to stage .+ \(ctx: ([^)]+)\)$`)
+
+// getKeptnContext returns the keptn context of a pull request opened with buildBranchName (flat-pr) or buildTitle
+// (branch) or an empty string for other pull requests
+func getKeptnContext(branch, title string) string {
+	if m := branchNameKeptnContext.FindStringSubmatch(branch); m != nil {
+		return m[1]
+	} else if m := titleKeptnContext.FindStringSubmatch(title); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
 func (a *GitPromotionTriggeredEventHandler) getGitPromotionStartedEvent(inputEvent GitPromotionTriggeredEventData, triggeredID, shkeptncontext string) *cloudevents.Event {
 	gitPromotionStartedEvent := keptnv2.EventData{
 		Project: inputEvent.Project,
@@ -158,11 +306,17 @@ func (a *GitPromotionTriggeredEventHandler) getGitPromotionStartedEvent(inputEve
 	return getCloudEvent(gitPromotionStartedEvent, keptnv2.GetStartedEventType(GitPromotionTaskName), shkeptncontext, triggeredID)
 }
 
-func (a *GitPromotionTriggeredEventHandler) getGitPromotionFinishedEvent(inputEvent GitPromotionTriggeredEventData,
-	status keptnv2.StatusType, result keptnv2.ResultType, message string, triggeredID, shkeptncontext string, prLink *string) *cloudevents.Event {
-	labels := inputEvent.Labels
-	if prLink != nil {
-		labels["pullrequest"] = *prLink
+func getGitPromotionFinishedEvent(inputEvent keptnv2.EventData,
+	status keptnv2.StatusType, result keptnv2.ResultType, message string, triggeredID, shkeptncontext string, pr *repoaccess.PullRequest) *cloudevents.Event {
+	labels := make(map[string]string, len(inputEvent.Labels)+1)
+	for k, v := range inputEvent.Labels {
+		labels[k] = v
+	}
+	if pr != nil && pr.URL != "" {
+		labels["pullrequest"] = pr.URL
+	}
+	if pr != nil && pr.MergeCommitSHA != "" {
+		labels["mergecommit"] = pr.MergeCommitSHA
 	}
 	gitPromotionFinishedEvent := keptnv2.EventData{
 		Project: inputEvent.Project,
@@ -176,13 +330,61 @@ func (a *GitPromotionTriggeredEventHandler) getGitPromotionFinishedEvent(inputEv
 	return getCloudEvent(gitPromotionFinishedEvent, keptnv2.GetFinishedEventType(GitPromotionTaskName), shkeptncontext, triggeredID)
 }
 
-func (a *GitPromotionTriggeredEventHandler) getAccessToken(secretName string) (accessToken string, err error) {
-	if secret, err := a.kubeClient.CoreV1().Secrets(os.Getenv("K8S_NAMESPACE")).Get(context.Background(), secretName, v1.GetOptions{}); err != nil {
-		return accessToken, err
+// getCredentials returns empty credentials if no secret is configured (only allowed for provider git)
+func getCredentials(providers secrets.Providers, target model.Target) (credentials repoaccess.Credentials, err error) {
+	if target.Secret == nil || *target.Secret == "" {
+		return credentials, nil
+	}
+	source := model.SecretSourceKubernetes
+	if target.SecretSource != nil && *target.SecretSource != "" {
+		source = *target.SecretSource
+	}
+	data, err := providers.GetSecret(source, *target.Secret)
+	if err != nil {
+		return credentials, err
+	}
+	credentials = repoaccess.Credentials{
+		Username:    string(data[secrets.KeyUsername]),
+		AccessToken: string(data[secrets.KeyAccessToken]),
+		CABundle:    data[secrets.KeyCABundle],
+	}
+	if target.Auth != nil && *target.Auth == model.AuthSSH {
+		if len(data[secrets.KeySSHPrivateKey]) == 0 {
+			return credentials, errors.New("ssh-privatekey is missing in secret")
+		} else if len(bytes.TrimSpace(data[secrets.KeyKnownHosts])) == 0 {
+			return credentials, errors.New("known_hosts is missing in secret, it is required to verify the host key")
+		}
+		credentials.SSHPrivateKey = data[secrets.KeySSHPrivateKey]
+		credentials.SSHKnownHosts = data[secrets.KeyKnownHosts]
+		logger.WithField("func", "getCredentials").Infof("found ssh-privatekey and known_hosts in %s secret %s", source, *target.Secret)
+	} else if target.Auth != nil && *target.Auth == model.AuthGithubApp {
+		if credentials.GithubApp, err = getGithubAppCredentials(data); err != nil {
+			return credentials, err
+		}
+		logger.WithField("func", "getCredentials").Infof("found github app %d with installation %d and ca bundle with length %d in %s secret %s", credentials.GithubApp.AppID, credentials.GithubApp.InstallationID, len(data[secrets.KeyCABundle]), source, *target.Secret)
 	} else {
-		logger.WithField("func", "getAccessToken").Infof("found access-token with length %d in secret %s", len(secret.Data["access-token"]), secret.Name)
-		return string(secret.Data["access-token"]), nil
+		logger.WithField("func", "getCredentials").Infof("found access-token with length %d and ca bundle with length %d in %s secret %s", len(data[secrets.KeyAccessToken]), len(data[secrets.KeyCABundle]), source, *target.Secret)
 	}
+	return credentials, nil
+}
+
+func getGithubAppCredentials(data map[string][]byte) (*repoaccess.GithubAppCredentials, error) {
+	appID, err := strconv.ParseInt(strings.TrimSpace(string(data[secrets.KeyAppID])), 10, 64)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid app-id in secret: %s", err.Error()))
+	}
+	installationID, err := strconv.ParseInt(strings.TrimSpace(string(data[secrets.KeyInstallationID])), 10, 64)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid installation-id in secret: %s", err.Error()))
+	}
+	if len(data[secrets.KeyPrivateKey]) == 0 {
+		return nil, errors.New("private-key is missing in secret")
+	}
+	return &repoaccess.GithubAppCredentials{
+		AppID:          appID,
+		InstallationID: installationID,
+		PrivateKey:     data[secrets.KeyPrivateKey],
+	}, nil
 }
 
 func (a *GitPromotionTriggeredEventHandler) getNextStage(project string, stage string) (nextStage string, err error) {
@@ -227,14 +429,45 @@ func (a *GitPromotionTriggeredEventHandler) getMergedConfiguration(project strin
 
 	config.Spec.Target.Repo = replacePlaceHolders(placeholders, config.Spec.Target.Repo)
 	config.Spec.Target.Secret = replacePlaceHolders(placeholders, config.Spec.Target.Secret)
+	config.Spec.Target.APIUrl = replacePlaceHolders(placeholders, config.Spec.Target.APIUrl)
+	config.Spec.Target.Branch = replacePlaceHolders(placeholders, config.Spec.Target.Branch)
+	if config.Spec.Strategy != nil && *config.Spec.Strategy == model.StrategyTag {
+		config.Spec.Tag = withTagDefaults(config.Spec.Tag)
+		config.Spec.Tag.Name = replacePlaceHolders(placeholders, config.Spec.Tag.Name)
+		config.Spec.Tag.Source = replacePlaceHolders(placeholders, config.Spec.Tag.Source)
+	}
 	for i, p := range config.Spec.Paths {
 		p.Target = replacePlaceHolders(placeholders, p.Target)
 		p.Source = replacePlaceHolders(placeholders, p.Source)
+		if p.Kustomize != nil {
+			kustomize := model.Kustomize{Images: append([]model.KustomizeImage(nil), p.Kustomize.Images...)}
+			for j := range kustomize.Images {
+				kustomize.Images[j].Name = replacePlaceHolders(placeholders, kustomize.Images[j].Name)
+			}
+			p.Kustomize = &kustomize
+		}
 		config.Spec.Paths[i] = p
 	}
 	return config
 }
 
+// withTagDefaults returns a copy of tag with model.DefaultTagName and model.DefaultTagSource for empty values
+func withTagDefaults(tag *model.Tag) *model.Tag {
+	result := model.Tag{}
+	if tag != nil {
+		result = *tag
+	}
+	if result.Name == nil || *result.Name == "" {
+		name := model.DefaultTagName
+		result.Name = &name
+	}
+	if result.Source == nil || *result.Source == "" {
+		source := model.DefaultTagSource
+		result.Source = &source
+	}
+	return &result
+}
+
 func replacePlaceHolders(placeholders map[string]string, p *string) (result *string) {
 	if p == nil {
 		return nil
@@ -270,6 +503,60 @@ func readAndMergeResource(target model.PromotionConfig, getResourceFunc func() (
 		if newConfig.Spec.Target.Provider != nil {
 			ret.Spec.Target.Provider = newConfig.Spec.Target.Provider
 		}
+		if newConfig.Spec.Target.APIUrl != nil {
+			ret.Spec.Target.APIUrl = newConfig.Spec.Target.APIUrl
+		}
+		if newConfig.Spec.Target.DirectPush != nil {
+			ret.Spec.Target.DirectPush = newConfig.Spec.Target.DirectPush
+		}
+		if newConfig.Spec.Target.Auth != nil {
+			ret.Spec.Target.Auth = newConfig.Spec.Target.Auth
+		}
+		if newConfig.Spec.Target.SecretSource != nil {
+			ret.Spec.Target.SecretSource = newConfig.Spec.Target.SecretSource
+		}
+		if newConfig.Spec.Target.Branch != nil {
+			ret.Spec.Target.Branch = newConfig.Spec.Target.Branch
+		}
+		if newConfig.Spec.WaitForMerge != nil {
+			waitForMerge := model.WaitForMerge{}
+			if ret.Spec.WaitForMerge != nil {
+				waitForMerge = *ret.Spec.WaitForMerge
+			}
+			if newConfig.Spec.WaitForMerge.Enabled != nil {
+				waitForMerge.Enabled = newConfig.Spec.WaitForMerge.Enabled
+			}
+			if newConfig.Spec.WaitForMerge.Timeout != nil {
+				waitForMerge.Timeout = newConfig.Spec.WaitForMerge.Timeout
+			}
+			ret.Spec.WaitForMerge = &waitForMerge
+		}
+		if newConfig.Spec.Merge != nil {
+			merge := model.Merge{}
+			if ret.Spec.Merge != nil {
+				merge = *ret.Spec.Merge
+			}
+			if newConfig.Spec.Merge.Mode != nil {
+				merge.Mode = newConfig.Spec.Merge.Mode
+			}
+			if newConfig.Spec.Merge.Method != nil {
+				merge.Method = newConfig.Spec.Merge.Method
+			}
+			ret.Spec.Merge = &merge
+		}
+		if newConfig.Spec.Tag != nil {
+			tag := model.Tag{}
+			if ret.Spec.Tag != nil {
+				tag = *ret.Spec.Tag
+			}
+			if newConfig.Spec.Tag.Name != nil {
+				tag.Name = newConfig.Spec.Tag.Name
+			}
+			if newConfig.Spec.Tag.Source != nil {
+				tag.Source = newConfig.Spec.Tag.Source
+			}
+			ret.Spec.Tag = &tag
+		}
 		ret.Spec.Paths = append(target.Spec.Paths, newConfig.Spec.Paths...)
 	}
 	return ret
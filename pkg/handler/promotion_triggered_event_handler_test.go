@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"github.com/google/go-github/github"
 	"github.com/keptn/go-utils/pkg/api/models"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
 	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"keptn/git-promotion-service/pkg/secrets"
 	"reflect"
 	"testing"
 )
@@ -92,6 +96,114 @@ spec:
 				},
 			},
 		},
+		{
+			name: "target connection settings",
+			args: args{
+				target: model.PromotionConfig{},
+				getResourceFunc: func() (resource *models.Resource, err error) {
+					return &models.Resource{
+						ResourceContent: `
+spec:
+  target:
+    repo: "myrepo"
+    apiUrl: "https://github.example.com/api/v3"
+    auth: "githubApp"
+    secretSource: "file"
+    branch: "${stage}"
+`,
+						ResourceURI: github.String("myresourceuri"),
+					}, nil
+				},
+			},
+			wantRet: model.PromotionConfig{
+				Spec: model.PromotionConfigSpec{
+					Target: model.Target{
+						Repo:         github.String("myrepo"),
+						APIUrl:       github.String("https://github.example.com/api/v3"),
+						Auth:         github.String("githubApp"),
+						SecretSource: github.String("file"),
+						Branch:       github.String("${stage}"),
+					},
+				},
+			},
+		},
+		{
+			name: "wait for merge timeout overrides enabled of previous resource",
+			args: args{
+				target: model.PromotionConfig{Spec: model.PromotionConfigSpec{WaitForMerge: &model.WaitForMerge{Enabled: github.Bool(true)}}},
+				getResourceFunc: func() (resource *models.Resource, err error) {
+					return &models.Resource{
+						ResourceContent: `
+spec:
+  waitForMerge:
+    timeout: "48h"
+`,
+						ResourceURI: github.String("myresourceuri"),
+					}, nil
+				},
+			},
+			wantRet: model.PromotionConfig{
+				Spec: model.PromotionConfigSpec{
+					WaitForMerge: &model.WaitForMerge{Enabled: github.Bool(true), Timeout: github.String("48h")},
+				},
+			},
+		},
+		{
+			name: "paths with kustomize images",
+			args: args{
+				target: model.PromotionConfig{},
+				getResourceFunc: func() (resource *models.Resource, err error) {
+					return &models.Resource{
+						ResourceContent: `
+spec:
+  paths:
+    - target: overlays/${nextstage}
+      kustomize:
+        images:
+          - name: ${service}
+            image: data.configurationChange.values.image
+          - name: sidecar
+            newTag: data.sidecarTag
+`,
+						ResourceURI: github.String("myresourceuri"),
+					}, nil
+				},
+			},
+			wantRet: model.PromotionConfig{
+				Spec: model.PromotionConfigSpec{
+					Paths: []model.Path{
+						{
+							Target: github.String("overlays/${nextstage}"),
+							Kustomize: &model.Kustomize{Images: []model.KustomizeImage{
+								{Name: github.String("${service}"), Image: github.String("data.configurationChange.values.image")},
+								{Name: github.String("sidecar"), NewTag: github.String("data.sidecarTag")},
+							}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "tag source overrides name of previous resource",
+			args: args{
+				target: model.PromotionConfig{Spec: model.PromotionConfigSpec{Tag: &model.Tag{Name: github.String("${nextstage}")}}},
+				getResourceFunc: func() (resource *models.Resource, err error) {
+					return &models.Resource{
+						ResourceContent: `
+spec:
+  tag:
+    source: "release-${stage}"
+`,
+						ResourceURI: github.String("myresourceuri"),
+					}, nil
+				},
+			},
+			wantRet: model.PromotionConfig{
+				Spec: model.PromotionConfigSpec{
+					Tag: &model.Tag{Name: github.String("${nextstage}"), Source: github.String("release-${stage}")},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -132,3 +244,259 @@ func Test_replacePlaceHolders(t *testing.T) {
 		})
 	}
 }
+
+type mapSecretProvider map[string]map[string][]byte
+
+func (p mapSecretProvider) GetSecret(name string) (map[string][]byte, error) {
+	if data, ok := p[name]; ok {
+		return data, nil
+	}
+	return nil, errors.New("secret not found")
+}
+
+func Test_getCredentials(t *testing.T) {
+	providers := secrets.Providers{
+		model.SecretSourceKubernetes: mapSecretProvider{
+			"token": {"access-token": []byte("k8s-token"), "ca.crt": []byte("cert")},
+			"app":   {"app-id": []byte("7\n"), "installation-id": []byte("42"), "private-key": []byte("key")},
+			"ssh":   {"ssh-privatekey": []byte("ssh-key"), "known_hosts": []byte("hosts")},
+			"key":   {"ssh-privatekey": []byte("ssh-key")},
+		},
+		model.SecretSourceEnv: mapSecretProvider{
+			"token": {"access-token": []byte("env-token"), "username": []byte("user")},
+		},
+	}
+	tests := []struct {
+		name    string
+		target  model.Target
+		want    repoaccess.Credentials
+		wantErr bool
+	}{
+		{
+			name:   "no secret",
+			target: model.Target{},
+		},
+		{
+			name:   "token from kubernetes by default",
+			target: model.Target{Secret: github.String("token")},
+			want:   repoaccess.Credentials{AccessToken: "k8s-token", CABundle: []byte("cert")},
+		},
+		{
+			name:   "token from env",
+			target: model.Target{Secret: github.String("token"), SecretSource: github.String("env")},
+			want:   repoaccess.Credentials{AccessToken: "env-token", Username: "user"},
+		},
+		{
+			name:    "unavailable secret source",
+			target:  model.Target{Secret: github.String("token"), SecretSource: github.String("file")},
+			wantErr: true,
+		},
+		{
+			name:    "missing secret",
+			target:  model.Target{Secret: github.String("missing")},
+			wantErr: true,
+		},
+		{
+			name:   "github app",
+			target: model.Target{Secret: github.String("app"), Auth: github.String("githubApp")},
+			want:   repoaccess.Credentials{GithubApp: &repoaccess.GithubAppCredentials{AppID: 7, InstallationID: 42, PrivateKey: []byte("key")}},
+		},
+		{
+			name:    "github app with token secret",
+			target:  model.Target{Secret: github.String("token"), Auth: github.String("githubApp")},
+			wantErr: true,
+		},
+		{
+			name:   "ssh",
+			target: model.Target{Secret: github.String("ssh"), Auth: github.String("ssh")},
+			want:   repoaccess.Credentials{SSHPrivateKey: []byte("ssh-key"), SSHKnownHosts: []byte("hosts")},
+		},
+		{
+			name:    "ssh without known hosts",
+			target:  model.Target{Secret: github.String("key"), Auth: github.String("ssh")},
+			wantErr: true,
+		},
+		{
+			name:    "ssh with token secret",
+			target:  model.Target{Secret: github.String("token"), Auth: github.String("ssh")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getCredentials(providers, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getCredentials() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// defaultBranchClient only implements DefaultBranch
+type defaultBranchClient struct {
+	repoaccess.Client
+	branch string
+	err    error
+}
+
+func (c defaultBranchClient) DefaultBranch() (string, error) {
+	return c.branch, c.err
+}
+
+func Test_getBaseBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  defaultBranchClient
+		target  model.Target
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "configured branch",
+			client: defaultBranchClient{err: errors.New("must not be called")},
+			target: model.Target{Branch: github.String("trunk")},
+			want:   "trunk",
+		},
+		{
+			name:   "default branch",
+			client: defaultBranchClient{branch: "master"},
+			target: model.Target{Branch: github.String("")},
+			want:   "master",
+		},
+		{
+			name:    "default branch error",
+			client:  defaultBranchClient{err: errors.New("not found")},
+			wantErr: true,
+		},
+		{
+			name:    "empty repository",
+			client:  defaultBranchClient{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getBaseBranch(tt.client, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getBaseBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getBaseBranch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// mergeClient only implements MergePullRequest
+type mergeClient struct {
+	repoaccess.Client
+	merge  bool
+	err    error
+	method string
+}
+
+func (c *mergeClient) MergePullRequest(pr *repoaccess.PullRequest, method string) error {
+	c.method = method
+	if c.merge {
+		pr.Merged, pr.MergeCommitSHA = true, "merge-sha"
+	}
+	return c.err
+}
+
+func Test_handleAutoMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *mergeClient
+		title       string
+		method      *string
+		wantStatus  keptnv2.StatusType
+		wantMessage string
+		wantMethod  string
+	}{
+		{
+			name:        "merged with default method",
+			client:      &mergeClient{merge: true},
+			title:       buildTitle("ctx", "prod"),
+			wantStatus:  keptnv2.StatusSucceeded,
+			wantMessage: "opened pull request, merged pull request",
+			wantMethod:  "merge",
+		},
+		{
+			name:        "auto-merge enabled",
+			client:      &mergeClient{},
+			title:       buildTitle("ctx", "prod"),
+			method:      github.String("squash"),
+			wantStatus:  keptnv2.StatusSucceeded,
+			wantMessage: "opened pull request, enabled auto-merge",
+			wantMethod:  "squash",
+		},
+		{
+			name:        "merge error",
+			client:      &mergeClient{err: errors.New("conflict")},
+			title:       buildTitle("ctx", "prod"),
+			wantStatus:  keptnv2.StatusErrored,
+			wantMessage: "error while merging pull request",
+			wantMethod:  "merge",
+		},
+		{
+			name:        "unmanaged pull request is not merged",
+			client:      &mergeClient{merge: true},
+			title:       "manual changes",
+			wantStatus:  keptnv2.StatusSucceeded,
+			wantMessage: "opened pull request",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := model.PromotionConfig{Spec: model.PromotionConfigSpec{Merge: &model.Merge{Mode: github.String("auto"), Method: tt.method}}}
+			status, _, message := handleAutoMerge(tt.client, config, &repoaccess.PullRequest{Title: tt.title}, "opened pull request")
+			if status != tt.wantStatus || message != tt.wantMessage || tt.client.method != tt.wantMethod {
+				t.Errorf("handleAutoMerge() = %s, %s using method %s, want %s, %s using %s", status, message, tt.client.method, tt.wantStatus, tt.wantMessage, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func Test_withTagDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  *model.Tag
+		want *model.Tag
+	}{
+		{
+			name: "no tag",
+			want: &model.Tag{Name: github.String(model.DefaultTagName), Source: github.String(model.DefaultTagSource)},
+		},
+		{
+			name: "empty name",
+			tag:  &model.Tag{Name: github.String(""), Source: github.String("main")},
+			want: &model.Tag{Name: github.String(model.DefaultTagName), Source: github.String("main")},
+		},
+		{
+			name: "configured",
+			tag:  &model.Tag{Name: github.String("${nextstage}"), Source: github.String("${stage}")},
+			want: &model.Tag{Name: github.String("${nextstage}"), Source: github.String("${stage}")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withTagDefaults(tt.tag); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withTagDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_handleTagStrategy_unsupportedProvider(t *testing.T) {
+	config := model.PromotionConfig{Spec: model.PromotionConfigSpec{
+		Target: model.Target{Repo: github.String("https://bitbucket.example.com/scm/prj/repo.git"), Provider: github.String(model.ProviderBitbucketServer)},
+		Tag:    withTagDefaults(nil),
+	}}
+	status, result, message := handleTagStrategy(&mergeClient{}, config, "ctx", "prod")
+	if status != keptnv2.StatusErrored || result != keptnv2.ResultFailed || message != "tags are not supported by the provider" {
+		t.Errorf("handleTagStrategy() = %s, %s, %s", status, result, message)
+	}
+}
@@ -7,6 +7,42 @@ type PromotionConfigValidator interface {
 const (
 	StrategyBranch string = "branch"
 	StrategyFlatPR        = "flat-pr"
+	// StrategyDirect commits the changes of the paths straight to the target branch without a pull request
+	StrategyDirect = "direct"
+	// StrategyTag moves a tag of the next stage to the commit deployed in the current stage
+	StrategyTag = "tag"
+)
+
+const (
+	ProviderGithub          string = "github"
+	ProviderGitlab                 = "gitlab"
+	ProviderBitbucketServer        = "bitbucket-server"
+	ProviderAzureDevOps            = "azure-devops"
+	ProviderGitea                  = "gitea"
+	ProviderGit                    = "git"
+)
+
+const (
+	AuthToken     string = "token"
+	AuthGithubApp        = "githubApp"
+	AuthSSH              = "ssh"
+)
+
+const (
+	MergeModeManual string = "manual"
+	MergeModeAuto          = "auto"
+)
+
+const (
+	MergeMethodMerge  string = "merge"
+	MergeMethodSquash        = "squash"
+	MergeMethodRebase        = "rebase"
+)
+
+const (
+	SecretSourceKubernetes string = "kubernetes"
+	SecretSourceFile              = "file"
+	SecretSourceEnv               = "env"
 )
 
 type PromotionConfig struct {
@@ -19,19 +55,91 @@ type Metadata struct {
 	Name string `yaml:"name"`
 }
 
+// DefaultWaitForMergeTimeout is used if WaitForMerge is enabled without a timeout
+const DefaultWaitForMergeTimeout = "24h"
+
 type PromotionConfigSpec struct {
 	Strategy *string `yaml:"strategy"`
 	Target   Target  `yaml:"target"`
 	Paths    []Path  `yaml:"paths"`
+	// WaitForMerge delays the finished event until the opened pull request is merged or closed
+	WaitForMerge *WaitForMerge `yaml:"waitForMerge"`
+	// Merge defines if pull requests are merged by the service
+	Merge *Merge `yaml:"merge"`
+	// Tag configures the tag strategy
+	Tag *Tag `yaml:"tag"`
+}
+
+// DefaultTagName and DefaultTagSource are used by the tag strategy if Tag.Name and Tag.Source are not set
+const (
+	DefaultTagName   = "${nextstage}-${service}"
+	DefaultTagSource = "${stage}-${service}"
+)
+
+type Tag struct {
+	// Name of the annotated tag created or moved for the next stage
+	Name *string `yaml:"name"`
+	// Source is the tag or branch pointing to the commit deployed in the current stage
+	Source *string `yaml:"source"`
+}
+
+type Merge struct {
+	// Mode is MergeModeManual (default) or MergeModeAuto
+	Mode *string `yaml:"mode"`
+	// Method is MergeMethodMerge (default), MergeMethodSquash or MergeMethodRebase
+	Method *string `yaml:"method"`
+}
+
+type WaitForMerge struct {
+	Enabled *bool `yaml:"enabled"`
+	// Timeout is a duration like 12h after which the promotion fails (defaults to DefaultWaitForMergeTimeout)
+	Timeout *string `yaml:"timeout"`
 }
 
 type Target struct {
 	Repo     *string `yaml:"repo"`
 	Secret   *string `yaml:"secret"`
 	Provider *string `yaml:"provider"`
+	APIUrl   *string `yaml:"apiUrl"`
+	// DirectPush is only supported by provider git
+	DirectPush *bool `yaml:"directPush"`
+	// Auth is the type of credentials stored in the secret (defaults to AuthToken)
+	Auth *string `yaml:"auth"`
+	// SecretSource defines where Secret is read from (defaults to SecretSourceKubernetes)
+	SecretSource *string `yaml:"secretSource"`
+	// Branch is the base branch of strategy flat-pr and the branch strategy direct commits to (defaults to the default
+	// branch of the repository)
+	Branch *string `yaml:"branch"`
 }
 
 type Path struct {
 	Source *string `yaml:"source"`
 	Target *string `yaml:"target"`
+	// Kustomize sets the images of the kustomization files below the target
+	Kustomize *Kustomize `yaml:"kustomize"`
+	// Replacements set values of the files below the target without annotations
+	Replacements []Replacement `yaml:"replacements"`
+}
+
+type Replacement struct {
+	// Files are glob patterns of the files relative to the target, patterns without a slash match the file name
+	Files []string `yaml:"files"`
+	// Path selects the value, e.g. image.tag or containers[0].image
+	Path *string `yaml:"path"`
+	// Field is the key of the event field written to the value
+	Field *string `yaml:"field"`
+}
+
+type Kustomize struct {
+	Images []KustomizeImage `yaml:"images"`
+}
+
+// KustomizeImage sets the images entry with the given name, the other fields are keys of event fields
+type KustomizeImage struct {
+	Name *string `yaml:"name"`
+	// Image is a complete image reference (name[:tag][@digest]) setting newName, newTag and digest
+	Image   *string `yaml:"image"`
+	NewName *string `yaml:"newName"`
+	NewTag  *string `yaml:"newTag"`
+	Digest  *string `yaml:"digest"`
 }
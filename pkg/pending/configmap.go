@@ -0,0 +1,71 @@
+package pending
+
+import (
+	"context"
+	"encoding/json"
+	logger "github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const configMapLabel = "keptn.sh/git-promotion"
+const configMapLabelValue = "pending"
+const configMapKey = "promotion.json"
+
+// configMapStore keeps one ConfigMap per pending promotion in namespace
+type configMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewConfigMapStore returns a Store using ConfigMaps of namespace
+func NewConfigMapStore(client kubernetes.Interface, namespace string) Store {
+	return &configMapStore{client: client, namespace: namespace}
+}
+
+func (s *configMapStore) Save(promotion Promotion) error {
+	data, err := json.Marshal(promotion)
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name(promotion.TriggeredID),
+			Namespace: s.namespace,
+			Labels:    map[string]string{configMapLabel: configMapLabelValue},
+		},
+		Data: map[string]string{configMapKey: string(data)},
+	}
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	if _, err = configMaps.Create(context.Background(), configMap, v1.CreateOptions{}); k8serrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(context.Background(), configMap, v1.UpdateOptions{})
+	}
+	return err
+}
+
+func (s *configMapStore) Delete(triggeredID string) error {
+	err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(context.Background(), name(triggeredID), v1.DeleteOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *configMapStore) List() (promotions []Promotion, err error) {
+	configMaps, err := s.client.CoreV1().ConfigMaps(s.namespace).List(context.Background(), v1.ListOptions{LabelSelector: configMapLabel + "=" + configMapLabelValue})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range configMaps.Items {
+		var promotion Promotion
+		if err := json.Unmarshal([]byte(c.Data[configMapKey]), &promotion); err != nil {
+			logger.WithField("func", "List").WithError(err).Errorf("ignoring invalid pending promotion %s", c.Name)
+			continue
+		}
+		promotions = append(promotions, promotion)
+	}
+	return promotions, nil
+}
@@ -0,0 +1,75 @@
+package pending
+
+import (
+	"encoding/json"
+	logger "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileStore keeps one json file per pending promotion, the directory should be a persistent volume
+type fileStore struct {
+	directory string
+}
+
+// NewFileStore returns a Store writing to directory, the directory is created if it does not exist
+func NewFileStore(directory string) Store {
+	return &fileStore{directory: directory}
+}
+
+func (s *fileStore) Save(promotion Promotion) error {
+	if err := os.MkdirAll(s.directory, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(promotion)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so that a crash never leaves a truncated promotion behind
+	tmp, err := os.CreateTemp(s.directory, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.directory, name(promotion.TriggeredID)+".json"))
+}
+
+func (s *fileStore) Delete(triggeredID string) error {
+	if err := os.Remove(filepath.Join(s.directory, name(triggeredID)+".json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) List() (promotions []Promotion, err error) {
+	entries, err := os.ReadDir(s.directory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.directory, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var promotion Promotion
+		if err := json.Unmarshal(data, &promotion); err != nil {
+			logger.WithField("func", "List").WithError(err).Errorf("ignoring invalid pending promotion %s", e.Name())
+			continue
+		}
+		promotions = append(promotions, promotion)
+	}
+	return promotions, nil
+}
@@ -0,0 +1,83 @@
+package pending
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/repoaccess"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPromotion(t *testing.T, triggeredID string) Promotion {
+	event := cloudevents.NewEvent()
+	event.SetID(triggeredID)
+	event.SetType(keptnv2.GetTriggeredEventType("git-promotion"))
+	event.SetSource("shipyard-controller")
+	if err := event.SetData(cloudevents.ApplicationJSON, map[string]string{"project": "podtato"}); err != nil {
+		t.Fatal(err)
+	}
+	repo := "https://github.com/owner/repo"
+	return Promotion{
+		TriggeredID:  triggeredID,
+		KeptnContext: "ctx",
+		Event:        event,
+		Data:         keptnv2.EventData{Project: "podtato", Stage: "dev", Service: "hello"},
+		PullRequest:  repoaccess.PullRequest{Number: 1, URL: "https://github.com/owner/repo/pull/1"},
+		Deadline:     time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Target:       model.Target{Repo: &repo},
+	}
+}
+
+func testStore(t *testing.T, store Store) {
+	if promotions, err := store.List(); err != nil || len(promotions) != 0 {
+		t.Fatalf("List() of empty store = %v, %v", promotions, err)
+	}
+	first, second := newPromotion(t, "8F4A-1"), newPromotion(t, "b-2")
+	for _, p := range []Promotion{first, second, first} {
+		if err := store.Save(p); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	promotions, err := store.List()
+	if err != nil || len(promotions) != 2 {
+		t.Fatalf("List() = %v, %v, want 2 promotions", promotions, err)
+	}
+	for _, p := range promotions {
+		if p.TriggeredID == first.TriggeredID && (!reflect.DeepEqual(p.Data, first.Data) || p.PullRequest != first.PullRequest ||
+			!p.Deadline.Equal(first.Deadline) || *p.Target.Repo != *first.Target.Repo || p.Event.ID() != first.Event.ID() ||
+			!reflect.DeepEqual(p.Event.Data(), first.Event.Data())) {
+			t.Errorf("List() returned %+v, want %+v", p, first)
+		}
+	}
+	if err := store.Delete(first.TriggeredID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(first.TriggeredID); err != nil {
+		t.Errorf("Delete() of deleted promotion error = %v", err)
+	}
+	if promotions, err := store.List(); err != nil || len(promotions) != 1 || promotions[0].TriggeredID != second.TriggeredID {
+		t.Errorf("List() after Delete() = %v, %v", promotions, err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "pending")
+	testStore(t, NewFileStore(directory))
+	if err := os.WriteFile(filepath.Join(directory, "invalid.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if promotions, err := NewFileStore(directory).List(); err != nil || len(promotions) != 1 {
+		t.Errorf("List() with invalid file = %v, %v", promotions, err)
+	}
+}
+
+func TestConfigMapStore(t *testing.T) {
+	testStore(t, NewConfigMapStore(fake.NewSimpleClientset(), "keptn"))
+}
@@ -0,0 +1,37 @@
+package pending
+
+import (
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"regexp"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// Promotion is a promotion whose finished event is sent once its pull request is merged or closed
+type Promotion struct {
+	TriggeredID  string                 `json:"triggeredId"`
+	KeptnContext string                 `json:"keptnContext"`
+	Event        cloudevents.Event      `json:"event"`
+	Data         keptnv2.EventData      `json:"data"`
+	Target       model.Target           `json:"target"`
+	PullRequest  repoaccess.PullRequest `json:"pullRequest"`
+	Deadline     time.Time              `json:"deadline"`
+}
+
+// Store persists pending promotions so that they survive restarts of the service
+type Store interface {
+	Save(promotion Promotion) error
+	Delete(triggeredID string) error
+	List() (promotions []Promotion, err error)
+}
+
+var invalidNameCharacters = regexp.MustCompile("[^a-z0-9-]")
+
+// name returns a file and kubernetes object name for the triggered id
+func name(triggeredID string) string {
+	return "git-promotion-" + invalidNameCharacters.ReplaceAllString(strings.ToLower(triggeredID), "-")
+}
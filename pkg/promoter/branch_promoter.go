@@ -1,6 +1,7 @@
 package promoter
 
 import (
+	"errors"
 	"fmt"
 	logger "github.com/sirupsen/logrus"
 	"keptn/git-promotion-service/pkg/repoaccess"
@@ -16,7 +17,7 @@ func NewBranchPromoter(client repoaccess.Client, pullRequestTitlePrefix string)
 	return BranchPromoter{client: client, pullRequestTitlePrefix: pullRequestTitlePrefix}
 }
 
-func (promoter BranchPromoter) Promote(repositoryUrl, fromBranch, toBranch, title, body string) (message string, prLink *string, err error) {
+func (promoter BranchPromoter) Promote(repositoryUrl, fromBranch, toBranch, title, body string) (message string, pr *repoaccess.PullRequest, err error) {
 	if newCommits, err := promoter.client.CheckForNewCommits(toBranch, fromBranch); err != nil {
 		return "", nil, err
 	} else if !newCommits {
@@ -31,16 +32,22 @@ func (promoter BranchPromoter) Promote(repositoryUrl, fromBranch, toBranch, titl
 				return "", nil, err
 			}
 			logger.WithField("func", "manageBranchStrategy").Infof("updated pull request %d in repo %s from branch %s to %s", pr.Number, repositoryUrl, fromBranch, toBranch)
-			return "updated pull request", &pr.URL, nil
+			return "updated pull request", pr, nil
 		} else {
-			return "unmanaged pull request already open", &pr.URL, nil
+			return "unmanaged pull request already open", pr, nil
 		}
 	} else {
 		pr, err := promoter.client.CreatePullRequest(fromBranch, toBranch, title, body)
-		if err != nil {
+		if errors.Is(err, repoaccess.ErrPullRequestsNotSupported) {
+			logger.WithField("func", "manageBranchStrategy").Infof("provider of repo %s does not support pull requests", repositoryUrl)
+			return fmt.Sprintf("new commits in branch %s not in %s, pull requests are not supported by the provider", fromBranch, toBranch), nil, nil
+		} else if err != nil {
 			return message, nil, err
+		} else if pr.Merged {
+			logger.WithField("func", "manageBranchStrategy").Infof("pushed branch %s directly to %s in repo %s", fromBranch, toBranch, repositoryUrl)
+			return fmt.Sprintf("pushed branch %s directly to %s", fromBranch, toBranch), nil, nil
 		}
 		logger.WithField("func", "manageBranchStrategy").Infof("opened pull request %d in repo %s from branch %s to %s", pr.Number, repositoryUrl, fromBranch, toBranch)
-		return "opened pull request", &pr.URL, nil
+		return "opened pull request", pr, nil
 	}
 }
@@ -0,0 +1,58 @@
+package promoter
+
+import (
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/repoaccess"
+)
+
+// directPromotionAttempts is the number of times a promotion is rebuilt if the branch was changed concurrently
+const directPromotionAttempts = 3
+
+// DirectPromoter applies the changes of all paths with a single commit straight to the target branch
+type DirectPromoter struct {
+	client   repoaccess.Client
+	attempts int
+}
+
+func NewDirectPromoter(client repoaccess.Client) DirectPromoter {
+	return DirectPromoter{client: client, attempts: directPromotionAttempts}
+}
+
+// Promote reads the paths at the current head of branch and commits the changes on top of it. If another commit was
+// pushed in the meantime the changes are read again from the new head, so concurrent promotions never overwrite each
+// other.
+func (promoter DirectPromoter) Promote(repositoryUrl string, fields map[string]string, branch string, paths []model.Path) (message string, err error) {
+	logger.WithField("func", "manageDirectStrategy").Infof("starting direct strategy with branch %s and fields %v", branch, fields)
+	for attempt := 1; attempt <= promoter.attempts; attempt++ {
+		head, err := promoter.client.GetBranchHead(branch)
+		if err != nil {
+			return "", err
+		}
+		var currentTargetFiles, newTargetFiles []repoaccess.RepositoryFile
+		for _, p := range paths {
+			pCurrentTargetFiles, pNewTargetFiles, err := getPathFiles(promoter.client, branch, p, fields)
+			if err != nil {
+				return "", err
+			}
+			currentTargetFiles = append(currentTargetFiles, pCurrentTargetFiles...)
+			newTargetFiles = append(newTargetFiles, pNewTargetFiles...)
+		}
+		if !checkForChanges(newTargetFiles, currentTargetFiles) {
+			logger.WithField("func", "manageDirectStrategy").Info("no changes detected, doing nothing")
+			return "no changes detected", nil
+		}
+		changes, err := promoter.client.SyncFilesWithBranchAt(branch, head, currentTargetFiles, newTargetFiles)
+		if errors.Is(err, repoaccess.ErrBranchMoved) {
+			logger.WithField("func", "manageDirectStrategy").Infof("branch %s in repo %s was changed concurrently (attempt %d of %d)", branch, repositoryUrl, attempt, promoter.attempts)
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		logger.WithField("func", "manageDirectStrategy").Infof("commited %d changes to branch %s in repo %s", changes, branch, repositoryUrl)
+		return fmt.Sprintf("pushed changes directly to branch %s", branch), nil
+	}
+	return "", errors.New(fmt.Sprintf("branch %s was changed concurrently %d times, giving up", branch, promoter.attempts))
+}
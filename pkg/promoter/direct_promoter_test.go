@@ -0,0 +1,99 @@
+package promoter
+
+import (
+	"github.com/google/go-github/github"
+	"io"
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"testing"
+)
+
+// concurrentClient pushes another commit to the branch before the first concurrentPushes syncs
+type concurrentClient struct {
+	repoaccess.Client
+	concurrentPushes int
+}
+
+func (c *concurrentClient) SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []repoaccess.RepositoryFile) (changes int, err error) {
+	if c.concurrentPushes > 0 {
+		c.concurrentPushes--
+		if _, err := c.Client.SyncFilesWithBranch(branch, nil, []repoaccess.RepositoryFile{{Path: "prod/values.yaml", Content: "tag: 0.9.0\n"}}); err != nil {
+			return 0, err
+		}
+	}
+	return c.Client.SyncFilesWithBranchAt(branch, head, currentTargetFiles, newTargetFiles)
+}
+
+func TestDirectPromoter_PromoteWithGitProvider(t *testing.T) {
+	tests := []struct {
+		name             string
+		concurrentPushes int
+		paths            []model.Path
+		wantMessage      string
+		wantErr          bool
+		wantContent      string
+		wantCommits      string
+	}{
+		{
+			name:        "single commit",
+			paths:       []model.Path{{Source: github.String("dev"), Target: github.String("staging")}},
+			wantMessage: "pushed changes directly to branch main",
+			wantContent: "tag: 1.0.2 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}",
+			wantCommits: "2",
+		},
+		{
+			name:        "replacement in place",
+			paths:       []model.Path{{Target: github.String("staging")}},
+			wantMessage: "pushed changes directly to branch main",
+			wantContent: "tag: 1.0.2 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}",
+			wantCommits: "2",
+		},
+		{
+			name:             "concurrent push is retried",
+			concurrentPushes: 1,
+			paths:            []model.Path{{Source: github.String("dev"), Target: github.String("staging")}},
+			wantMessage:      "pushed changes directly to branch main",
+			wantContent:      "tag: 1.0.2 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}",
+			wantCommits:      "3",
+		},
+		{
+			name:             "concurrent pushes exceed attempts",
+			concurrentPushes: directPromotionAttempts,
+			paths:            []model.Path{{Source: github.String("dev"), Target: github.String("staging")}},
+			wantErr:          true,
+			wantContent:      "tag: 1.0.0 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}",
+			wantCommits:      "4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bare := newBareRepository(t, map[string]string{
+				"dev/values.yaml":     "tag: 1.0.1 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+				"dev/other.yaml":      "other: true\n",
+				"staging/values.yaml": "tag: 1.0.0 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+			})
+			client, err := repoaccess.NewClient(model.Target{
+				Repo:     github.String("file://" + bare),
+				Provider: github.String(model.ProviderGit),
+			}, repoaccess.Credentials{})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			defer client.(io.Closer).Close()
+
+			message, err := NewDirectPromoter(&concurrentClient{Client: client, concurrentPushes: tt.concurrentPushes}).Promote("file://"+bare, map[string]string{"data.tag": "1.0.2"}, "main", tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Promote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if message != tt.wantMessage {
+				t.Errorf("Promote() = %s, want %s", message, tt.wantMessage)
+			}
+			if content := runGit(t, bare, "show", "main:staging/values.yaml"); content != tt.wantContent {
+				t.Errorf("unexpected content in branch main: %s", content)
+			}
+			if commits := runGit(t, bare, "rev-list", "--count", "main"); commits != tt.wantCommits {
+				t.Errorf("branch main has %s commits, want %s", commits, tt.wantCommits)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"keptn/git-promotion-service/pkg/model"
 	"keptn/git-promotion-service/pkg/replacer"
 	"keptn/git-promotion-service/pkg/repoaccess"
+	pathpkg "path"
 	"strings"
 )
 
@@ -18,7 +19,7 @@ func NewFlatPrPromoter(client repoaccess.Client) FlatPrPromoter {
 	return FlatPrPromoter{client: client}
 }
 
-func (promoter FlatPrPromoter) Promote(repositoryUrl string, fields map[string]string, sourceBranch, targetBranch, title, body string, paths []model.Path) (message string, prLink *string, err error) {
+func (promoter FlatPrPromoter) Promote(repositoryUrl string, fields map[string]string, sourceBranch, targetBranch, title, body string, paths []model.Path) (message string, pr *repoaccess.PullRequest, err error) {
 	logger.WithField("func", "manageFlatPRStrategy").Infof("starting flat pr strategy with sourceBranch %s and targetBranch %s and fields %v", sourceBranch, targetBranch, fields)
 
 	if exists, err := promoter.client.BranchExists(targetBranch); err != nil {
@@ -26,54 +27,48 @@ func (promoter FlatPrPromoter) Promote(repositoryUrl string, fields map[string]s
 	} else if exists {
 		return "", nil, errors.New(fmt.Sprintf("branch with name %s already exists", targetBranch))
 	}
-	if err := promoter.client.CreateBranch(sourceBranch, targetBranch); err != nil {
-		return "", nil, err
-	}
-	changes := 0
 	logger.WithField("func", "manageFlatPRStrategy").Infof("processing %d paths", len(paths))
+	// the files of all paths are collected first, so the promotion is committed at once and a failing path leaves
+	// nothing behind
+	var currentTargetFiles, newTargetFiles []repoaccess.RepositoryFile
 	for _, p := range paths {
-		var path string
-		if p.Source == nil {
-			path = *p.Target
-		} else {
-			path = *p.Source
-		}
-		pNewTargetFiles, err := promoter.client.GetFilesForBranch(sourceBranch, path)
+		pCurrentTargetFiles, pNewTargetFiles, err := getPathFiles(promoter.client, sourceBranch, p, fields)
 		if err != nil {
 			return "", nil, err
 		}
-		var pCurrentTargetFiles []repoaccess.RepositoryFile
-		if p.Source != nil {
-			if pCurrentTargetFiles, err = promoter.client.GetFilesForBranch(sourceBranch, *p.Target); err != nil {
-				return "", nil, err
-			}
-		} else {
-			pCurrentTargetFiles = pNewTargetFiles
-		}
-		for i, c := range pNewTargetFiles {
-			pNewTargetFiles[i].Content = replacer.Replace(c.Content, fields)
-			if p.Source != nil {
-				pNewTargetFiles[i].Path = strings.Replace(pNewTargetFiles[i].Path, *p.Source, *p.Target, -1)
-			}
-		}
-		if checkForChanges(pNewTargetFiles, pCurrentTargetFiles) {
-			if pathChanges, err := promoter.client.SyncFilesWithBranch(targetBranch, pCurrentTargetFiles, pNewTargetFiles); err != nil {
-				return "", nil, err
-			} else {
-				changes += pathChanges
-			}
-		} else {
-			logger.WithField("func", "manageFlatPRStrategy").Info("no changes detected, doing nothing")
-			return "no changes detected", nil, nil
+		currentTargetFiles = append(currentTargetFiles, pCurrentTargetFiles...)
+		newTargetFiles = append(newTargetFiles, pNewTargetFiles...)
+	}
+	if !checkForChanges(newTargetFiles, currentTargetFiles) {
+		logger.WithField("func", "manageFlatPRStrategy").Info("no changes detected, doing nothing")
+		return "no changes detected", nil, nil
+	}
+	if err := promoter.client.CreateBranch(sourceBranch, targetBranch); err != nil {
+		return "", nil, err
+	}
+	changes, err := promoter.client.SyncFilesWithBranch(targetBranch, currentTargetFiles, newTargetFiles)
+	if err != nil {
+		if deleteErr := promoter.client.DeleteBranch(targetBranch); deleteErr != nil {
+			logger.WithField("func", "manageFlatPRStrategy").Warnf("could not delete branch %s: %s", targetBranch, deleteErr.Error())
 		}
+		return "", nil, err
 	}
 	logger.WithField("func", "manageFlatPRStrategy").Infof("commited %d changes to branch %s", changes, targetBranch)
 	if changes > 0 {
-		if pr, err := promoter.client.CreatePullRequest(targetBranch, sourceBranch, title, body); err != nil {
+		if pr, err := promoter.client.CreatePullRequest(targetBranch, sourceBranch, title, body); errors.Is(err, repoaccess.ErrPullRequestsNotSupported) {
+			logger.WithField("func", "manageFlatPRStrategy").Infof("provider of repo %s does not support pull requests, keeping branch %s", repositoryUrl, targetBranch)
+			return fmt.Sprintf("pushed branch %s, pull requests are not supported by the provider", targetBranch), nil, nil
+		} else if err != nil {
 			return "", nil, err
+		} else if pr.Merged {
+			logger.WithField("func", "manageFlatPRStrategy").Infof("pushed changes directly to branch %s in repo %s, deleting branch %s", sourceBranch, repositoryUrl, targetBranch)
+			if err := promoter.client.DeleteBranch(targetBranch); err != nil {
+				return "", nil, err
+			}
+			return fmt.Sprintf("pushed changes directly to branch %s", sourceBranch), nil, nil
 		} else {
 			logger.WithField("func", "manageFlatPRStrategy").Infof("opened pull request %d in repo %s from branch %s to %s", pr.Number, repositoryUrl, sourceBranch, targetBranch)
-			return "opened pull request", &pr.URL, nil
+			return "opened pull request", pr, nil
 		}
 	} else {
 		logger.WithField("func", "manageFlatPRStrategy").Infof("no changes found, deleting branch %s", targetBranch)
@@ -85,6 +80,53 @@ func (promoter FlatPrPromoter) Promote(repositoryUrl string, fields map[string]s
 	}
 }
 
+// getPathFiles returns the current files of the target of p and the files replacing them, which are the files of the
+// source (or the target itself if no source is set) with replaced fields and selected values, kustomize images set and
+// paths moved to the target
+func getPathFiles(client repoaccess.Client, branch string, p model.Path, fields map[string]string) (currentTargetFiles, newTargetFiles []repoaccess.RepositoryFile, err error) {
+	var path string
+	if p.Source == nil {
+		path = *p.Target
+	} else {
+		path = *p.Source
+	}
+	if newTargetFiles, err = client.GetFilesForBranch(branch, path); err != nil {
+		return nil, nil, err
+	}
+	if p.Source != nil {
+		if currentTargetFiles, err = client.GetFilesForBranch(branch, *p.Target); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		currentTargetFiles = append([]repoaccess.RepositoryFile(nil), newTargetFiles...)
+	}
+	for i, c := range newTargetFiles {
+		if c.IsBinary() {
+			logger.WithField("func", "getPathFiles").Infof("copying binary file %s without replacements", c.Path)
+		} else {
+			newTargetFiles[i].Content = replacer.ReplaceFile(c.Path, c.Content, fields)
+		}
+		if p.Source != nil {
+			newTargetFiles[i].Path = strings.Replace(newTargetFiles[i].Path, *p.Source, *p.Target, -1)
+		}
+		if len(p.Replacements) > 0 && !c.IsBinary() {
+			file := strings.TrimPrefix(strings.TrimPrefix(newTargetFiles[i].Path, *p.Target), "/")
+			if file == "" {
+				file = pathpkg.Base(newTargetFiles[i].Path)
+			}
+			if newTargetFiles[i].Content, err = replacer.ReplaceSelected(file, newTargetFiles[i].Content, p.Replacements, fields); err != nil {
+				return nil, nil, err
+			}
+		}
+		if p.Kustomize != nil && replacer.IsKustomization(newTargetFiles[i].Path) {
+			if newTargetFiles[i].Content, err = replacer.SetKustomizeImages(newTargetFiles[i].Content, p.Kustomize.Images, fields); err != nil {
+				return nil, nil, errors.New(fmt.Sprintf("could not set images of %s: %s", newTargetFiles[i].Path, err.Error()))
+			}
+		}
+	}
+	return currentTargetFiles, newTargetFiles, nil
+}
+
 func checkForChanges(files []repoaccess.RepositoryFile, files2 []repoaccess.RepositoryFile) bool {
 	if len(files) != len(files2) {
 		return true
@@ -96,7 +138,7 @@ func checkForChanges(files []repoaccess.RepositoryFile, files2 []repoaccess.Repo
 	for _, f2 := range files2 {
 		if f, ok := tempmap[f2.Path]; !ok {
 			return true
-		} else if f.Content != f2.Content {
+		} else if f.Content != f2.Content || f.Mode != f2.Mode {
 			return true
 		}
 	}
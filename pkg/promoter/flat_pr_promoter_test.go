@@ -1,7 +1,14 @@
 package promoter
 
 import (
+	"github.com/google/go-github/github"
+	"io"
+	"keptn/git-promotion-service/pkg/model"
 	"keptn/git-promotion-service/pkg/repoaccess"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -97,6 +104,25 @@ func Test_checkForChanges(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "test different mode",
+			args: args{
+				files: []repoaccess.RepositoryFile{
+					{
+						Content: "hallo",
+						Path:    "/mnt",
+						Mode:    "100755",
+					},
+				},
+				files2: []repoaccess.RepositoryFile{
+					{
+						Content: "hallo",
+						Path:    "/mnt",
+					},
+				},
+			},
+			want: true,
+		},
 		{
 			name: "test different sha",
 			args: args{
@@ -126,3 +152,204 @@ func Test_checkForChanges(t *testing.T) {
 		})
 	}
 }
+
+func newBareRepository(t *testing.T, files map[string]string) string {
+	bare := filepath.Join(t.TempDir(), "remote.git")
+	work := t.TempDir()
+	runGit(t, "", "init", "--bare", "--quiet", bare)
+	runGit(t, "", "init", "--quiet", work)
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(work, path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(work, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "initial")
+	runGit(t, work, "push", "--quiet", bare, "HEAD:refs/heads/main")
+	return bare
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestFlatPrPromoter_PromoteWithGitProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		directPush  bool
+		wantMessage string
+		wantBranch  string
+	}{
+		{
+			name:        "branch only",
+			wantMessage: "pushed branch promote/dev_staging, pull requests are not supported by the provider",
+			wantBranch:  "promote/dev_staging",
+		},
+		{
+			name:        "direct push",
+			directPush:  true,
+			wantMessage: "pushed changes directly to branch main",
+			wantBranch:  "main",
+		},
+	}
+	// binary files must be copied verbatim even if they contain an annotation
+	binary := "\x00\xfftag: 1.0.1 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\x00"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bare := newBareRepository(t, map[string]string{
+				"dev/values.yaml":     "tag: 1.0.1 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+				"dev/chart.tgz":       binary,
+				"staging/values.yaml": "tag: 1.0.0 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+			})
+			client, err := repoaccess.NewClient(model.Target{
+				Repo:       github.String("file://" + bare),
+				Provider:   github.String(model.ProviderGit),
+				DirectPush: github.Bool(tt.directPush),
+			}, repoaccess.Credentials{})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			defer client.(io.Closer).Close()
+
+			message, pr, err := NewFlatPrPromoter(client).Promote("file://"+bare, map[string]string{"data.tag": "1.0.2"}, "main", "promote/dev_staging", "title", "body", []model.Path{
+				{Source: github.String("dev"), Target: github.String("staging")},
+			})
+			if err != nil {
+				t.Fatalf("Promote() error = %v", err)
+			}
+			if message != tt.wantMessage || pr != nil {
+				t.Errorf("Promote() = %s, %v, want %s, nil", message, pr, tt.wantMessage)
+			}
+			if content := runGit(t, bare, "show", tt.wantBranch+":staging/values.yaml"); content != "tag: 1.0.2 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}" {
+				t.Errorf("unexpected content in branch %s: %s", tt.wantBranch, content)
+			}
+			if content := runGit(t, bare, "show", tt.wantBranch+":staging/chart.tgz"); content != binary {
+				t.Errorf("unexpected binary content in branch %s: %q", tt.wantBranch, content)
+			}
+		})
+	}
+}
+
+func Test_getPathFilesWithKustomize(t *testing.T) {
+	bare := newBareRepository(t, map[string]string{
+		"base/deployment.yaml":                        "image: podtato-head # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+		"overlays/production/kustomization.yaml":      "resources:\n  - ../../base # shared\nimages:\n  - name: podtato-head\n    newTag: 0.1.0\n",
+		"overlays/production/other/kustomization.yml": "images: []\n",
+	})
+	client, err := repoaccess.NewClient(model.Target{
+		Repo:     github.String("file://" + bare),
+		Provider: github.String(model.ProviderGit),
+	}, repoaccess.Credentials{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.(io.Closer).Close()
+
+	_, newTargetFiles, err := getPathFiles(client, "main", model.Path{
+		Target: github.String("overlays/production"),
+		Kustomize: &model.Kustomize{Images: []model.KustomizeImage{
+			{Name: github.String("podtato-head"), Image: github.String("data.configurationChange.values.image")},
+		}},
+	}, map[string]string{"data.configurationChange.values.image": "ghcr.io/podtato-head:0.1.2"})
+	if err != nil {
+		t.Fatalf("getPathFiles() error = %v", err)
+	}
+	want := map[string]string{
+		"overlays/production/kustomization.yaml":      "resources:\n  - ../../base # shared\nimages:\n  - name: podtato-head\n    newTag: 0.1.2\n    newName: ghcr.io/podtato-head\n",
+		"overlays/production/other/kustomization.yml": "images: [{name: podtato-head, newName: ghcr.io/podtato-head, newTag: 0.1.2}]\n",
+	}
+	if len(newTargetFiles) != len(want) {
+		t.Fatalf("getPathFiles() returned %d files, want %d", len(newTargetFiles), len(want))
+	}
+	for _, f := range newTargetFiles {
+		if f.Content != want[f.Path] {
+			t.Errorf("unexpected content of %s: %q, want %q", f.Path, f.Content, want[f.Path])
+		}
+	}
+}
+
+func Test_getPathFilesWithReplacements(t *testing.T) {
+	bare := newBareRepository(t, map[string]string{
+		"dev/charts/app/values.yaml":      "image:\n  tag: 1.0.0 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n  repository: nginx\n",
+		"dev/charts/app/templates/x.yaml": "image: {{ .Values.image.repository }}\n",
+		"staging/charts/app/values.yaml":  "image:\n  tag: 0.9.0\n",
+	})
+	client, err := repoaccess.NewClient(model.Target{
+		Repo:     github.String("file://" + bare),
+		Provider: github.String(model.ProviderGit),
+	}, repoaccess.Credentials{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.(io.Closer).Close()
+
+	_, newTargetFiles, err := getPathFiles(client, "main", model.Path{
+		Source: github.String("dev"),
+		Target: github.String("staging"),
+		Replacements: []model.Replacement{
+			{Files: []string{"charts/*/values.yaml"}, Path: github.String("image.repository"), Field: github.String("data.repository")},
+		},
+	}, map[string]string{"data.tag": "1.0.1", "data.repository": "ghcr.io/app"})
+	if err != nil {
+		t.Fatalf("getPathFiles() error = %v", err)
+	}
+	want := map[string]string{
+		"staging/charts/app/values.yaml":      "image:\n  tag: 1.0.1 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n  repository: ghcr.io/app\n",
+		"staging/charts/app/templates/x.yaml": "image: {{ .Values.image.repository }}\n",
+	}
+	if len(newTargetFiles) != len(want) {
+		t.Fatalf("getPathFiles() returned %d files, want %d", len(newTargetFiles), len(want))
+	}
+	for _, f := range newTargetFiles {
+		if f.Content != want[f.Path] {
+			t.Errorf("unexpected content of %s: %q, want %q", f.Path, f.Content, want[f.Path])
+		}
+	}
+}
+
+func TestFlatPrPromoter_PromoteMultiplePaths(t *testing.T) {
+	bare := newBareRepository(t, map[string]string{
+		"dev/values.yaml":     "tag: 1.0.1 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+		"staging/values.yaml": "tag: 1.0.0 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+		"prod/values.yaml":    "tag: 1.0.0 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\n",
+		"shared/config.yaml":  "replicas: 1\n",
+	})
+	client, err := repoaccess.NewClient(model.Target{
+		Repo:     github.String("file://" + bare),
+		Provider: github.String(model.ProviderGit),
+	}, repoaccess.Credentials{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.(io.Closer).Close()
+
+	// the first path is unchanged, the changes of the following paths must be promoted anyway
+	message, _, err := NewFlatPrPromoter(client).Promote("file://"+bare, map[string]string{"data.tag": "1.0.2"}, "main", "promote/dev_staging", "title", "body", []model.Path{
+		{Target: github.String("shared")},
+		{Source: github.String("dev"), Target: github.String("staging")},
+		{Target: github.String("prod")},
+	})
+	if err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	if want := "pushed branch promote/dev_staging, pull requests are not supported by the provider"; message != want {
+		t.Errorf("Promote() = %s, want %s", message, want)
+	}
+	for _, path := range []string{"staging/values.yaml", "prod/values.yaml"} {
+		if content := runGit(t, bare, "show", "promote/dev_staging:"+path); content != "tag: 1.0.2 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}" {
+			t.Errorf("unexpected content of %s: %s", path, content)
+		}
+	}
+	if commits := runGit(t, bare, "rev-list", "--count", "main..promote/dev_staging"); commits != "1" {
+		t.Errorf("branch promote/dev_staging has %s new commits, want 1", commits)
+	}
+}
@@ -0,0 +1,57 @@
+package promoter
+
+import (
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"keptn/git-promotion-service/pkg/repoaccess"
+)
+
+// ErrTagMovesBackwards is returned if the commit to promote is not a descendant of the commit the tag points to
+var ErrTagMovesBackwards = errors.New("tag would be moved backwards")
+
+type TagPromoter struct {
+	client repoaccess.TagClient
+}
+
+func NewTagPromoter(client repoaccess.TagClient) TagPromoter {
+	return TagPromoter{client: client}
+}
+
+// Promote creates the annotated tag at the commit source points to or moves an existing tag forward. Tags are never
+// moved to a commit which does not contain the current commit of the tag (e.g. an older commit).
+func (promoter TagPromoter) Promote(repositoryUrl, source, tagName, message string) (msg string, err error) {
+	logger.WithField("func", "manageTagStrategy").Infof("starting tag strategy with source %s and tag %s", source, tagName)
+	commit, err := promoter.client.ResolveCommit(source)
+	if err != nil {
+		return "", err
+	} else if commit == "" {
+		return "", errors.New(fmt.Sprintf("branch or tag %s not found in repo %s", source, repositoryUrl))
+	}
+	tag, err := promoter.client.GetTag(tagName)
+	if err != nil {
+		return "", err
+	}
+	if tag != nil {
+		if tag.Commit == commit {
+			logger.WithField("func", "manageTagStrategy").Infof("tag %s already points to commit %s", tagName, commit)
+			return fmt.Sprintf("tag %s already points to commit %s => nothing todo", tagName, commit), nil
+		}
+		if isAncestor, err := promoter.client.IsAncestor(tag.Commit, commit); err != nil {
+			return "", err
+		} else if !isAncestor {
+			return "", fmt.Errorf("%w: commit %s of tag %s is not contained in commit %s of %s", ErrTagMovesBackwards, tag.Commit, tagName, commit, source)
+		}
+	}
+	if err := promoter.client.CreateTag(tagName, commit, message, tag); errors.Is(err, repoaccess.ErrTagMoved) {
+		return "", fmt.Errorf("%w: tag %s was changed by someone else while promoting commit %s of %s", err, tagName, commit, source)
+	} else if err != nil {
+		return "", err
+	}
+	if tag == nil {
+		logger.WithField("func", "manageTagStrategy").Infof("created tag %s at commit %s in repo %s", tagName, commit, repositoryUrl)
+		return fmt.Sprintf("created tag %s at commit %s", tagName, commit), nil
+	}
+	logger.WithField("func", "manageTagStrategy").Infof("moved tag %s from commit %s to %s in repo %s", tagName, tag.Commit, commit, repositoryUrl)
+	return fmt.Sprintf("moved tag %s from commit %s to %s", tagName, tag.Commit, commit), nil
+}
@@ -0,0 +1,116 @@
+package promoter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/go-github/github"
+	"io"
+	"keptn/git-promotion-service/pkg/model"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"testing"
+)
+
+// concurrentTagClient changes the repository right before the tag is created, like a concurrent promotion
+type concurrentTagClient struct {
+	repoaccess.TagClient
+	change func()
+}
+
+func (c concurrentTagClient) CreateTag(name, commit, message string, previous *repoaccess.Tag) (err error) {
+	c.change()
+	return c.TagClient.CreateTag(name, commit, message, previous)
+}
+
+func TestTagPromoter_PromoteWithGitProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare tags the commits of the repository, first is the parent of second
+		prepare func(t *testing.T, bare, first, second string)
+		// concurrent changes the repository after the tag was checked (optional)
+		concurrent  func(t *testing.T, bare, first, second string)
+		source      string
+		wantMessage string
+		wantErr     error
+		wantCommit  func(first, second string) string
+	}{
+		{
+			name:        "tag is created",
+			source:      "dev-hello",
+			prepare:     func(t *testing.T, bare, first, second string) { runGit(t, bare, "tag", "dev-hello", second) },
+			wantMessage: "created tag prod-hello at commit %[2]s",
+			wantCommit:  func(first, second string) string { return second },
+		},
+		{
+			name:   "tag is moved forward",
+			source: "main",
+			prepare: func(t *testing.T, bare, first, second string) {
+				runGit(t, bare, "tag", "prod-hello", first)
+			},
+			wantMessage: "moved tag prod-hello from commit %[1]s to %[2]s",
+			wantCommit:  func(first, second string) string { return second },
+		},
+		{
+			name:   "tag is up to date",
+			source: "main",
+			prepare: func(t *testing.T, bare, first, second string) {
+				runGit(t, bare, "tag", "prod-hello", second)
+			},
+			wantMessage: "tag prod-hello already points to commit %[2]s => nothing todo",
+			wantCommit:  func(first, second string) string { return second },
+		},
+		{
+			name:   "tag is not moved backwards",
+			source: "dev-hello",
+			prepare: func(t *testing.T, bare, first, second string) {
+				runGit(t, bare, "tag", "dev-hello", first)
+				runGit(t, bare, "tag", "prod-hello", second)
+			},
+			wantErr:    ErrTagMovesBackwards,
+			wantCommit: func(first, second string) string { return second },
+		},
+		{
+			name:   "tag moved concurrently is not overwritten",
+			source: "main",
+			prepare: func(t *testing.T, bare, first, second string) {
+				runGit(t, bare, "tag", "prod-hello", first)
+			},
+			concurrent: func(t *testing.T, bare, first, second string) {
+				runGit(t, bare, "-c", "user.name=test", "-c", "user.email=test@example.com", "tag", "--force", "--annotate", "--message", "other", "prod-hello", second)
+			},
+			wantErr:    repoaccess.ErrTagMoved,
+			wantCommit: func(first, second string) string { return second },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bare := newBareRepository(t, map[string]string{"dev/values.yaml": "tag: 1.0.1\n"})
+			first := runGit(t, bare, "rev-parse", "main")
+			second := runGit(t, bare, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit-tree", "-p", first, "-m", "second", first+"^{tree}")
+			runGit(t, bare, "update-ref", "refs/heads/main", second)
+			tt.prepare(t, bare, first, second)
+			client, err := repoaccess.NewClient(model.Target{
+				Repo:     github.String("file://" + bare),
+				Provider: github.String(model.ProviderGit),
+			}, repoaccess.Credentials{})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			defer client.(io.Closer).Close()
+
+			tagClient := client.(repoaccess.TagClient)
+			if tt.concurrent != nil {
+				tagClient = concurrentTagClient{TagClient: tagClient, change: func() { tt.concurrent(t, bare, first, second) }}
+			}
+			message, err := NewTagPromoter(tagClient).Promote("file://"+bare, tt.source, "prod-hello", "promote")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Promote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && message != fmt.Sprintf(tt.wantMessage, first, second) {
+				t.Errorf("Promote() = %s, want %s", message, fmt.Sprintf(tt.wantMessage, first, second))
+			}
+			if commit := runGit(t, bare, "rev-parse", "prod-hello^{commit}"); commit != tt.wantCommit(first, second) {
+				t.Errorf("tag prod-hello points to %s, want %s", commit, tt.wantCommit(first, second))
+			}
+		})
+	}
+}
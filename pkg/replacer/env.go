@@ -0,0 +1,58 @@
+package replacer
+
+import (
+	"regexp"
+	"strings"
+)
+
+var envKey = regexp.MustCompile(`^\s*(export\s+)?[A-Za-z_][A-Za-z0-9_.]*\s*=\s*`)
+
+// envPlainValue matches values which don't need quotes
+var envPlainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=~^-]*$`)
+
+// envFormat are .env files with lines like KEY=value or export KEY="value"
+type envFormat struct{}
+
+func (envFormat) value(lines []string, i int) (start, end, last int, ok bool) {
+	line := lines[i]
+	m := envKey.FindStringIndex(line)
+	if m == nil {
+		return 0, 0, 0, false
+	}
+	start = m[1]
+	if start < len(line) && (line[start] == '"' || line[start] == '\'') {
+		// values with line breaks are not supported
+		if end = quotedEnd(line, start); end < 0 {
+			return 0, 0, 0, false
+		}
+		return start, end, i, true
+	}
+	// a comment after an unquoted value starts with whitespace
+	end = len(line)
+	for c := start + 1; c < len(line); c++ {
+		if line[c] == '#' && (line[c-1] == ' ' || line[c-1] == '\t') {
+			end = c
+			break
+		}
+	}
+	return start, start + len(strings.TrimRight(line[start:end], " \t")), i, true
+}
+
+func (envFormat) format(value, current string) string {
+	switch {
+	case strings.HasPrefix(current, "'") && !strings.ContainsAny(value, "'\r\n"):
+		return "'" + value + "'"
+	case strings.HasPrefix(current, `"`) || !envPlainValue.MatchString(value):
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(value) + `"`
+	default:
+		return value
+	}
+}
+
+func (envFormat) comments() string {
+	return "#"
+}
+
+func (envFormat) inlineComments() bool {
+	return true
+}
@@ -0,0 +1,47 @@
+package replacer
+
+import "testing"
+
+func TestReplaceFileEnv(t *testing.T) {
+	tags := map[string]string{
+		"data.image.tag": "1.0.1",
+		"data.message":   `it's "done"`,
+		"data.dollar":    "$1",
+	}
+	tests := []struct {
+		name       string
+		path       string
+		fileData   string
+		wantResult string
+	}{
+		{
+			name: "annotation above",
+			path: "config/app.env",
+			fileData: "# keptn.git-promotion.replacewith: data.image.tag\nIMAGE_TAG=1.0.0\n" +
+				"# keptn.git-promotion.replacewith: data.message\n\n# the message\nexport MESSAGE='old' # shown on start\n" +
+				"OTHER=1.0.0\n",
+			wantResult: "# keptn.git-promotion.replacewith: data.image.tag\nIMAGE_TAG=1.0.1\n" +
+				"# keptn.git-promotion.replacewith: data.message\n\n# the message\nexport MESSAGE=\"it's \\\"done\\\"\" # shown on start\n" +
+				"OTHER=1.0.0\n",
+		},
+		{
+			name:       "inline annotation and crlf",
+			path:       ".env.production",
+			fileData:   "TAG = \"1.0.0\" # keptn.git-promotion.replacewith: data.image.tag\r\nVALUE=x#y # keptn.git-promotion.replacewith: data.dollar\r\n",
+			wantResult: "TAG = \"1.0.1\" # keptn.git-promotion.replacewith: data.image.tag\r\nVALUE=\"$1\" # keptn.git-promotion.replacewith: data.dollar\r\n",
+		},
+		{
+			name:       "unknown field and unsupported value",
+			path:       ".env",
+			fileData:   "# keptn.git-promotion.replacewith: data.missing\nA=1\n# keptn.git-promotion.replacewith: data.image.tag\nB=\"multi\nline\"\n",
+			wantResult: "# keptn.git-promotion.replacewith: data.missing\nA=1\n# keptn.git-promotion.replacewith: data.image.tag\nB=\"multi\nline\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotResult := ReplaceFile(tt.path, tt.fileData, tags); gotResult != tt.wantResult {
+				t.Errorf("ReplaceFile() = %q, want %q", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
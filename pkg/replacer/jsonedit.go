@@ -0,0 +1,136 @@
+package replacer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"io"
+	"strings"
+)
+
+// jsonValue is a value of a json file with its position, the values of objects and arrays are kept in the order of
+// the file
+type jsonValue struct {
+	start, end int
+	// kind is '{', '[', '"' for strings or 0 for other scalars
+	kind   byte
+	keys   []string
+	values []*jsonValue
+}
+
+// parseJSON returns the root value of data
+func parseJSON(data string) (root *jsonValue, err error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if root, err = parseJSONValue(decoder, data); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New(fmt.Sprintf("unexpected data after offset %d", root.end))
+	}
+	return root, nil
+}
+
+func parseJSONValue(decoder *json.Decoder, data string) (*jsonValue, error) {
+	start := int(decoder.InputOffset())
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	// the separators before the value are consumed with the token
+	for start < len(data) && strings.IndexByte(" \t\r\n,:", data[start]) >= 0 {
+		start++
+	}
+	v := &jsonValue{start: start}
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		v.kind = byte(token.(json.Delim))
+		for decoder.More() {
+			if v.kind == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				v.keys = append(v.keys, key.(string))
+			}
+			value, err := parseJSONValue(decoder, data)
+			if err != nil {
+				return nil, err
+			}
+			v.values = append(v.values, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	default:
+		if _, ok := token.(string); ok {
+			v.kind = '"'
+		}
+	}
+	v.end = int(decoder.InputOffset())
+	return v, nil
+}
+
+// selectJSON returns the value selected by steps, nil if it does not exist
+func selectJSON(root *jsonValue, steps []selectorStep) *jsonValue {
+	v := root
+	for _, step := range steps {
+		var next *jsonValue
+		switch {
+		case v.kind == '[' && step.index >= 0 && step.index < len(v.values):
+			next = v.values[step.index]
+		case v.kind == '{' && (step.index < 0 || step.key != ""):
+			// the last of duplicate keys is the one read by json parsers
+			for i, key := range v.keys {
+				if key == step.key {
+					next = v.values[i]
+				}
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		v = next
+	}
+	return v
+}
+
+func setJSONValues(file, fileData string, values []selectedValue) (string, error) {
+	root, err := parseJSON(fileData)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("could not parse %s: %s", file, err.Error()))
+	}
+	var edits []textEdit
+	for _, v := range values {
+		selected := selectJSON(root, v.steps)
+		switch {
+		case selected == nil:
+			logger.WithField("func", "setJSONValues").Warnf("%s not found in %s", v.selector, file)
+		case selected.kind == '{' || selected.kind == '[':
+			return "", errors.New(fmt.Sprintf("could not set %s of %s: value is not a scalar", v.selector, file))
+		default:
+			edits = append(edits, textEdit{start: selected.start, end: selected.end, text: formatJSONValue(v.value, selected.kind == '"')})
+		}
+	}
+	return applyEdits(fileData, edits)
+}
+
+// formatJSONValue returns value as json string. Values replacing a number, boolean or null keep the type if they are
+// valid json literals.
+func formatJSONValue(value string, replacesString bool) string {
+	if !replacesString && value != "" && strings.TrimSpace(value) == value && json.Valid([]byte(value)) && strings.IndexByte(`"{[`, value[0]) < 0 {
+		return value
+	}
+	return jsonString(value)
+}
+
+// jsonString returns value as json string without escaping html characters
+func jsonString(value string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(b.String(), "\n")
+}
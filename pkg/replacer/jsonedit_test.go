@@ -0,0 +1,79 @@
+package replacer
+
+import (
+	"keptn/git-promotion-service/pkg/model"
+	"testing"
+)
+
+func TestReplaceSelectedJSON(t *testing.T) {
+	str := func(s string) *string {
+		return &s
+	}
+	fields := map[string]string{
+		"data.version":  "1.0.1",
+		"data.replicas": "3",
+		"data.enabled":  "true",
+		"data.title":    `Podtato "prod" <1.0.1>`,
+		"data.text":     "on",
+	}
+	tests := []struct {
+		name         string
+		fileData     string
+		replacements []model.Replacement
+		wantResult   string
+		wantErr      bool
+	}{
+		{
+			name: "json pointer and path",
+			fileData: "{\n\t\"title\": \"Podtato\",\n\t\"panels\": [\n\t\t{\"id\": 2, \"version\": \"1.0.0\"},\n" +
+				"\t\t{\"id\": 1, \"version\": \"1.0.0\"}\n\t],\n\t\"a/b\": {\"replicas\" : 1}\n}\n",
+			replacements: []model.Replacement{
+				{Files: []string{"*.json"}, Path: str("/panels/1/version"), Field: str("data.version")},
+				{Files: []string{"*.json"}, Path: str("$.title"), Field: str("data.title")},
+				{Files: []string{"*.json"}, Path: str("/a~1b/replicas"), Field: str("data.replicas")},
+			},
+			wantResult: "{\n\t\"title\": \"Podtato \\\"prod\\\" <1.0.1>\",\n\t\"panels\": [\n\t\t{\"id\": 2, \"version\": \"1.0.0\"},\n" +
+				"\t\t{\"id\": 1, \"version\": \"1.0.1\"}\n\t],\n\t\"a/b\": {\"replicas\" : 3}\n}\n",
+		},
+		{
+			name:     "types of literals are kept if possible",
+			fileData: `{"flags": {"enabled": false, "mode": null, "count": 1, "name": "x"}, "list": [1, 2]}`,
+			replacements: []model.Replacement{
+				{Files: []string{"flags.json"}, Path: str("flags.enabled"), Field: str("data.enabled")},
+				{Files: []string{"flags.json"}, Path: str("flags.mode"), Field: str("data.text")},
+				{Files: []string{"flags.json"}, Path: str("flags.name"), Field: str("data.replicas")},
+				{Files: []string{"flags.json"}, Path: str("list[1]"), Field: str("data.version")},
+				{Files: []string{"flags.json"}, Path: str("flags.missing"), Field: str("data.version")},
+			},
+			wantResult: `{"flags": {"enabled": true, "mode": "on", "count": 1, "name": "3"}, "list": [1, "1.0.1"]}`,
+		},
+		{
+			name:     "not a scalar",
+			fileData: `{"flags": {"enabled": false}}`,
+			replacements: []model.Replacement{
+				{Files: []string{"flags.json"}, Path: str("flags"), Field: str("data.enabled")},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "invalid json",
+			fileData: `{"flags": {"enabled": false}} {}`,
+			replacements: []model.Replacement{
+				{Files: []string{"flags.json"}, Path: str("flags.enabled"), Field: str("data.enabled")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotResult, err := ReplaceSelected("config/flags.json", tt.fileData, tt.replacements, fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReplaceSelected() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotResult != tt.wantResult {
+				t.Errorf("ReplaceSelected() = %q, want %q", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package replacer
+
+import (
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"keptn/git-promotion-service/pkg/model"
+	pathpkg "path"
+	"strings"
+)
+
+// kustomizationFileNames are the file names read by kustomize
+var kustomizationFileNames = map[string]bool{"kustomization.yaml": true, "kustomization.yml": true, "Kustomization": true}
+
+// IsKustomization reports whether the file at path is a kustomization file
+func IsKustomization(path string) bool {
+	return kustomizationFileNames[pathpkg.Base(path)]
+}
+
+// kustomizeImageValue is a key of an images entry, a nil value removes the key
+type kustomizeImageValue struct {
+	key   string
+	value *string
+}
+
+type kustomizeImageEntry struct {
+	name   string
+	values []kustomizeImageValue
+}
+
+// SetKustomizeImages sets newName, newTag and digest of the images entries of a kustomization file to the values of
+// the event fields. Missing entries are appended, the rest of the file is not changed.
+func SetKustomizeImages(content string, images []model.KustomizeImage, fields map[string]string) (result string, err error) {
+	doc, roots, err := parseYamlDocument(content)
+	if err != nil {
+		return "", err
+	}
+	if len(roots) != 1 || len(roots[0].Content) != 1 || roots[0].Content[0].Kind != yaml.MappingNode {
+		return "", errors.New("kustomization is not a yaml mapping")
+	}
+	root := roots[0].Content[0]
+	imagesKey, imagesNode := mappingValue(root, "images")
+	var newEntries []kustomizeImageEntry
+	for _, image := range images {
+		values := kustomizeImageValues(image, fields)
+		if len(values) == 0 {
+			continue
+		}
+		if entry := findKustomizeImage(imagesNode, *image.Name); entry != nil {
+			if err := doc.setKustomizeImageValues(entry, values); err != nil {
+				return "", errors.New(fmt.Sprintf("could not set image %s: %s", *image.Name, err.Error()))
+			}
+		} else {
+			newEntries = append(newEntries, kustomizeImageEntry{name: *image.Name, values: values})
+		}
+	}
+	if len(newEntries) > 0 {
+		if err := doc.appendKustomizeImages(root, imagesKey, imagesNode, newEntries); err != nil {
+			return "", errors.New(fmt.Sprintf("could not add images: %s", err.Error()))
+		}
+	}
+	return doc.apply()
+}
+
+// kustomizeImageValues returns the values of image read from the event fields, fields missing in the event are skipped
+func kustomizeImageValues(image model.KustomizeImage, fields map[string]string) (values []kustomizeImageValue) {
+	field := func(key *string) (string, bool) {
+		if key == nil {
+			return "", false
+		}
+		value, ok := lookupValue(fields, *key)
+		if !ok {
+			logger.WithField("func", "kustomizeImageValues").Warnf("field %s for image %s not found in event", *key, *image.Name)
+		}
+		return value, ok
+	}
+	optional := func(key, value string, set bool) kustomizeImageValue {
+		if set {
+			return kustomizeImageValue{key: key, value: &value}
+		}
+		return kustomizeImageValue{key: key}
+	}
+	if ref, ok := field(image.Image); ok {
+		name, tag, digest := parseImageReference(ref)
+		values = append(values, optional("newName", name, name != *image.Name), optional("newTag", tag, tag != ""),
+			optional("digest", digest, digest != ""))
+	}
+	for _, f := range []struct {
+		key   string
+		field *string
+	}{{"newName", image.NewName}, {"newTag", image.NewTag}, {"digest", image.Digest}} {
+		if value, ok := field(f.field); ok {
+			values = append(values, optional(f.key, value, true))
+		}
+	}
+	return values
+}
+
+// parseImageReference splits an image reference like registry:5000/app:1.0@sha256:... into name, tag and digest
+func parseImageReference(ref string) (name, tag, digest string) {
+	name = ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return name, tag, digest
+}
+
+func findKustomizeImage(images *yaml.Node, name string) *yaml.Node {
+	if images == nil || images.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range images.Content {
+		if _, n := mappingValue(entry, "name"); n != nil && n.Value == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (d *yamlDocument) setKustomizeImageValues(entry *yaml.Node, values []kustomizeImageValue) (err error) {
+	for _, v := range values {
+		keyNode, valueNode := mappingValue(entry, v.key)
+		switch {
+		case valueNode != nil && v.value != nil:
+			err = d.setScalar(valueNode, *v.value)
+		case valueNode != nil:
+			err = d.removeMappingEntry(keyNode, valueNode)
+		case v.value != nil:
+			err = d.addMappingEntry(entry, v.key, *v.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMappingEntry adds key after the last entry of mapping
+func (d *yamlDocument) addMappingEntry(mapping *yaml.Node, key, value string) error {
+	if mapping.Style&yaml.FlowStyle != 0 {
+		end, err := d.flowEnd(d.offset(mapping.Line, mapping.Column))
+		if err != nil {
+			return err
+		}
+		separator := ""
+		if len(mapping.Content) > 0 {
+			separator = ", "
+		}
+		d.insert(end, separator+key+": "+formatYamlScalar(value, "!!str", 0))
+		return nil
+	}
+	indent := strings.Repeat(" ", mapping.Content[0].Column-1)
+	d.insertLines(d.endLine(mapping), indent+key+": "+formatYamlScalar(value, "!!str", 0))
+	return nil
+}
+
+// removeMappingEntry removes the line of a key of a block mapping
+func (d *yamlDocument) removeMappingEntry(key, value *yaml.Node) error {
+	start := d.offset(key.Line, 1)
+	if key.Line != value.Line || value.Kind != yaml.ScalarNode || strings.TrimLeft(d.content[start:d.offset(key.Line, key.Column)], " ") != "" {
+		return errors.New(fmt.Sprintf("can not remove %s in line %d", key.Value, key.Line))
+	}
+	end := len(d.content)
+	if key.Line < len(d.lineOffsets) {
+		end = d.lineOffsets[key.Line]
+	}
+	d.edits = append(d.edits, textEdit{start: start, end: end})
+	return nil
+}
+
+// appendKustomizeImages adds entries to the images list, the list is created if the kustomization has none
+func (d *yamlDocument) appendKustomizeImages(root, imagesKey, images *yaml.Node, entries []kustomizeImageEntry) error {
+	switch {
+	case images == nil || images.Tag == "!!null" && images.Value == "":
+		if images == nil {
+			d.insertLines(d.endLine(root), append([]string{"images:"}, blockKustomizeImages("", entries)...)...)
+		} else {
+			d.insertLines(imagesKey.Line, blockKustomizeImages("", entries)...)
+		}
+	case images.Kind == yaml.SequenceNode && images.Style&yaml.FlowStyle != 0:
+		end, err := d.flowEnd(d.offset(images.Line, images.Column))
+		if err != nil {
+			return err
+		}
+		var items []string
+		for _, e := range entries {
+			item := "name: " + formatYamlScalar(e.name, "!!str", 0)
+			for _, v := range e.values {
+				if v.value != nil {
+					item += ", " + v.key + ": " + formatYamlScalar(*v.value, "!!str", 0)
+				}
+			}
+			items = append(items, "{"+item+"}")
+		}
+		separator := ""
+		if len(images.Content) > 0 {
+			separator = ", "
+		}
+		d.insert(end, separator+strings.Join(items, ", "))
+	case images.Kind == yaml.SequenceNode:
+		// the column of a block sequence is the one of the first dash
+		d.insertLines(d.endLine(images), blockKustomizeImages(strings.Repeat(" ", images.Column-1), entries)...)
+	default:
+		return errors.New(fmt.Sprintf("images in line %d is not a list", images.Line))
+	}
+	return nil
+}
+
+func blockKustomizeImages(indent string, entries []kustomizeImageEntry) (lines []string) {
+	for _, e := range entries {
+		lines = append(lines, indent+"- name: "+formatYamlScalar(e.name, "!!str", 0))
+		for _, v := range e.values {
+			if v.value != nil {
+				lines = append(lines, indent+"  "+v.key+": "+formatYamlScalar(*v.value, "!!str", 0))
+			}
+		}
+	}
+	return lines
+}
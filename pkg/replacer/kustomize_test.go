@@ -0,0 +1,218 @@
+package replacer
+
+import (
+	"keptn/git-promotion-service/pkg/model"
+	"testing"
+)
+
+func TestSetKustomizeImages(t *testing.T) {
+	str := func(s string) *string {
+		return &s
+	}
+	fields := map[string]string{
+		"data.image":     "registry.example.com:5000/podtato-head:0.1.2",
+		"data.digest":    "registry.example.com:5000/podtato-head@sha256:24a0c4b4",
+		"data.same":      "podtato-head:0.1.3",
+		"data.tag":       "0.1.2",
+		"data.numberTag": "1.10",
+	}
+	tests := []struct {
+		name       string
+		content    string
+		images     []model.KustomizeImage
+		wantResult string
+		wantErr    bool
+	}{
+		{
+			name: "update existing entry",
+			content: `# production overlay
+resources:
+  - ../../base # shared manifests
+images:
+  - name: podtato-head # the image
+    newName: podtato-head   # old name
+    newTag: "0.1.0"
+  - name: other
+    newTag: 1.0.0
+`,
+			images: []model.KustomizeImage{{Name: str("podtato-head"), Image: str("data.image")}},
+			wantResult: `# production overlay
+resources:
+  - ../../base # shared manifests
+images:
+  - name: podtato-head # the image
+    newName: registry.example.com:5000/podtato-head   # old name
+    newTag: "0.1.2"
+  - name: other
+    newTag: 1.0.0
+`,
+		},
+		{
+			name: "digest replaces tag",
+			content: `images:
+- name: podtato-head
+  newTag: 0.1.0
+  newName: old
+`,
+			images: []model.KustomizeImage{{Name: str("podtato-head"), Image: str("data.digest")}},
+			wantResult: `images:
+- name: podtato-head
+  newName: registry.example.com:5000/podtato-head
+  digest: sha256:24a0c4b4
+`,
+		},
+		{
+			name:       "same name removes newName",
+			content:    "images:\r\n- name: podtato-head\r\n  newName: old\r\n  newTag: 0.1.0\r\n",
+			images:     []model.KustomizeImage{{Name: str("podtato-head"), Image: str("data.same")}},
+			wantResult: "images:\r\n- name: podtato-head\r\n  newTag: 0.1.3\r\n",
+		},
+		{
+			name:    "single fields",
+			content: "images:\n  - name: podtato-head\n    newTag: '0.1.0'\n",
+			images: []model.KustomizeImage{{Name: str("podtato-head"), NewTag: str("data.numberTag"),
+				NewName: str("data.missing")}},
+			wantResult: "images:\n  - name: podtato-head\n    newTag: '1.10'\n",
+		},
+		{
+			name:       "plain number tag",
+			content:    "images:\n  - name: podtato-head\n    newTag: latest\n",
+			images:     []model.KustomizeImage{{Name: str("podtato-head"), NewTag: str("data.numberTag")}},
+			wantResult: "images:\n  - name: podtato-head\n    newTag: \"1.10\"\n",
+		},
+		{
+			name:    "add entry",
+			content: "images:\n  - name: other\n    newTag: 1.0.0\n",
+			images:  []model.KustomizeImage{{Name: str("podtato-head"), NewTag: str("data.tag")}},
+			wantResult: `images:
+  - name: other
+    newTag: 1.0.0
+  - name: podtato-head
+    newTag: 0.1.2
+`,
+		},
+		{
+			name:    "add images",
+			content: "resources:\n- deployment.yaml\n\n# trailing comment",
+			images: []model.KustomizeImage{{Name: str("podtato-head"), NewTag: str("data.tag")},
+				{Name: str("other"), Image: str("data.image")}},
+			wantResult: `resources:
+- deployment.yaml
+images:
+- name: podtato-head
+  newTag: 0.1.2
+- name: other
+  newName: registry.example.com:5000/podtato-head
+  newTag: 0.1.2
+
+# trailing comment`,
+		},
+		{
+			name: "multi-line entries",
+			content: `images:
+  - name: podtato-head
+    newTag: 0.1.0
+    notes: |
+      first line
+
+      second line
+patches:
+  - target:
+      kind: Deployment
+    patch: |-
+      - op: replace
+        path: /spec/replicas
+        value: 2
+`,
+			images: []model.KustomizeImage{{Name: str("podtato-head"), NewName: str("data.tag")},
+				{Name: str("other"), NewTag: str("data.tag")}},
+			wantResult: `images:
+  - name: podtato-head
+    newTag: 0.1.0
+    notes: |
+      first line
+
+      second line
+    newName: 0.1.2
+  - name: other
+    newTag: 0.1.2
+patches:
+  - target:
+      kind: Deployment
+    patch: |-
+      - op: replace
+        path: /spec/replicas
+        value: 2
+`,
+		},
+		{
+			name: "add images after nested block",
+			content: `resources:
+  - deployment.yaml
+configMapGenerator:
+  - name: config
+    literals:
+      - A=1
+      - B=[
+          2]
+`,
+			images: []model.KustomizeImage{{Name: str("podtato-head"), NewTag: str("data.tag")}},
+			wantResult: `resources:
+  - deployment.yaml
+configMapGenerator:
+  - name: config
+    literals:
+      - A=1
+      - B=[
+          2]
+images:
+- name: podtato-head
+  newTag: 0.1.2
+`,
+		},
+		{
+			name:       "flow style",
+			content:    "images: [{name: podtato-head, newTag: 0.1.0}]\n",
+			images:     []model.KustomizeImage{{Name: str("podtato-head"), Image: str("data.image")}, {Name: str("other"), NewTag: str("data.tag")}},
+			wantResult: "images: [{name: podtato-head, newTag: 0.1.2, newName: registry.example.com:5000/podtato-head}, {name: other, newTag: 0.1.2}]\n",
+		},
+		{
+			name:    "invalid yaml",
+			content: "images: [",
+			images:  []model.KustomizeImage{{Name: str("podtato-head"), NewTag: str("data.tag")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotResult, err := SetKustomizeImages(tt.content, tt.images, fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetKustomizeImages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotResult != tt.wantResult {
+				t.Errorf("SetKustomizeImages() = %q, want %q", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		ref                           string
+		wantName, wantTag, wantDigest string
+	}{
+		{ref: "nginx", wantName: "nginx"},
+		{ref: "nginx:1.21", wantName: "nginx", wantTag: "1.21"},
+		{ref: "localhost:5000/team/app", wantName: "localhost:5000/team/app"},
+		{ref: "localhost:5000/team/app:v1@sha256:abc", wantName: "localhost:5000/team/app", wantTag: "v1", wantDigest: "sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			name, tag, digest := parseImageReference(tt.ref)
+			if name != tt.wantName || tag != tt.wantTag || digest != tt.wantDigest {
+				t.Errorf("parseImageReference() = %s, %s, %s, want %s, %s, %s", name, tag, digest, tt.wantName, tt.wantTag, tt.wantDigest)
+			}
+		})
+	}
+}
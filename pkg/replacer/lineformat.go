@@ -0,0 +1,96 @@
+package replacer
+
+import (
+	logger "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+)
+
+// lineAnnotation is the annotation of formats with line comments, e.g.
+// # keptn.git-promotion.replacewith: data.image.tag
+var lineAnnotation = regexp.MustCompile(`^\s*` + regexp.QuoteMeta(annotationKey) + `:\s*(.*\S)\s*$`)
+
+// lineFormat is a format of key value lines like .env or .properties files
+type lineFormat interface {
+	// value returns the range of the value of the key value line lines[i], the value ends in lines[last]. ok is false
+	// if the line is no key value line or the value is not supported.
+	value(lines []string, i int) (start, end, last int, ok bool)
+	// format returns value in the syntax of the format, current is the value which is replaced
+	format(value, current string) string
+	// comments are the characters starting a comment line
+	comments() string
+	// inlineComments reports whether an annotation can follow the value in the same line
+	inlineComments() bool
+}
+
+// replaceAnnotatedLines replaces the values of the lines following an annotation comment. Comments and blank lines
+// between the annotation and the value are allowed.
+func replaceAnnotatedLines(fileData string, tags map[string]string, f lineFormat) string {
+	lines := strings.Split(fileData, "\n")
+	// the line breaks are kept for each line, so files with mixed line endings are not changed otherwise
+	crs := make([]bool, len(lines))
+	for i, l := range lines {
+		lines[i], crs[i] = strings.TrimSuffix(l, "\r"), strings.HasSuffix(l, "\r")
+	}
+	pending := ""
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if strings.IndexByte(f.comments(), trimmed[0]) >= 0 {
+			if m := lineAnnotation.FindStringSubmatch(trimmed[1:]); m != nil {
+				pending = m[1]
+			}
+			continue
+		}
+		key := pending
+		pending = ""
+		start, end, last, ok := f.value(lines, i)
+		if ok && f.inlineComments() {
+			if c := strings.IndexByte(lines[last][end:], '#'); c >= 0 {
+				if m := lineAnnotation.FindStringSubmatch(lines[last][end+c+1:]); m != nil {
+					key = m[1]
+				}
+			}
+		}
+		if !ok {
+			if key != "" {
+				logger.WithField("func", "replaceAnnotatedLines").Warnf("unsupported value for %s in line %d", key, i+1)
+			}
+			continue
+		}
+		if value, found := lookupValue(tags, key); key != "" && found {
+			current := lines[i][start:]
+			if last == i {
+				current = lines[i][start:end]
+			}
+			// a value continued in multiple lines is replaced with a single line
+			lines[i] = lines[i][:start] + f.format(value, current) + lines[last][end:]
+			crs[i] = crs[last]
+			lines = append(lines[:i+1], lines[last+1:]...)
+			crs = append(crs[:i+1], crs[last+1:]...)
+		} else {
+			i = last
+		}
+	}
+	for i := range lines {
+		if crs[i] {
+			lines[i] += "\r"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quotedEnd returns the offset after the quote closing the string starting at start, double quoted strings may contain
+// escaped quotes. It returns -1 if the string is not closed in line.
+func quotedEnd(line string, start int) int {
+	for i := start + 1; i < len(line); i++ {
+		if line[i] == '\\' && line[start] == '"' {
+			i++
+		} else if line[i] == line[start] {
+			return i + 1
+		}
+	}
+	return -1
+}
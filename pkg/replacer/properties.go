@@ -0,0 +1,82 @@
+package replacer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// propertiesFormat are Java .properties files with lines like key=value, key: value or key value
+type propertiesFormat struct{}
+
+func (propertiesFormat) value(lines []string, i int) (start, end, last int, ok bool) {
+	line := lines[i]
+	p := len(line) - len(strings.TrimLeft(line, " \t\f"))
+	keyStart := p
+	for ; p < len(line) && strings.IndexByte("=: \t\f", line[p]) < 0; p++ {
+		if line[p] == '\\' {
+			p++
+		}
+	}
+	// keys continued in the next line are not supported
+	if p == keyStart || p > len(line) || (p == len(line) && continuedProperty(line)) {
+		return 0, 0, 0, false
+	}
+	for p < len(line) && strings.IndexByte(" \t\f", line[p]) >= 0 {
+		p++
+	}
+	if p < len(line) && (line[p] == '=' || line[p] == ':') {
+		p++
+	}
+	for p < len(line) && strings.IndexByte(" \t\f", line[p]) >= 0 {
+		p++
+	}
+	last = i
+	for last+1 < len(lines) && continuedProperty(lines[last]) {
+		last++
+	}
+	return p, len(lines[last]), last, true
+}
+
+// continuedProperty reports whether line ends with an odd number of backslashes
+func continuedProperty(line string) bool {
+	return (len(line)-len(strings.TrimRight(line, `\`)))%2 == 1
+}
+
+// format escapes value like java.util.Properties.store, characters outside of ASCII are written as unicode escapes
+// because properties files are read as ISO 8859-1 by default
+func (propertiesFormat) format(value, current string) string {
+	var b strings.Builder
+	for i, r := range value {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && i == 0:
+			b.WriteString(`\ `)
+		case r < 0x20 || r > 0x7e:
+			for _, c := range utf16.Encode([]rune{r}) {
+				b.WriteString(fmt.Sprintf(`\u%04x`, c))
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (propertiesFormat) comments() string {
+	return "#!"
+}
+
+// inlineComments is false, a # after the value is part of the value
+func (propertiesFormat) inlineComments() bool {
+	return false
+}
@@ -0,0 +1,39 @@
+package replacer
+
+import "testing"
+
+func TestReplaceFileProperties(t *testing.T) {
+	tags := map[string]string{
+		"data.image.tag": "1.0.1",
+		"data.message":   " grüße\\n",
+	}
+	tests := []struct {
+		name       string
+		fileData   string
+		wantResult string
+	}{
+		{
+			name: "separators",
+			fileData: "# keptn.git-promotion.replacewith: data.image.tag\nimage.tag=1.0.0\n" +
+				"! keptn.git-promotion.replacewith: data.image.tag\n  image\\ name : 1.0.0 # not a comment\n" +
+				"# keptn.git-promotion.replacewith: data.image.tag\nimage.version 1.0.0\n",
+			wantResult: "# keptn.git-promotion.replacewith: data.image.tag\nimage.tag=1.0.1\n" +
+				"! keptn.git-promotion.replacewith: data.image.tag\n  image\\ name : 1.0.1\n" +
+				"# keptn.git-promotion.replacewith: data.image.tag\nimage.version 1.0.1\n",
+		},
+		{
+			name: "escapes and continuation lines",
+			fileData: "# keptn.git-promotion.replacewith: data.message\r\nmessage = first \\\r\n  second \\\\\\\r\n  third\r\n" +
+				"next=value\\\\\r\n",
+			wantResult: "# keptn.git-promotion.replacewith: data.message\r\nmessage = \\ gr\\u00fc\\u00dfe\\\\n\r\n" +
+				"next=value\\\\\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotResult := ReplaceFile("src/main/resources/application.properties", tt.fileData, tags); gotResult != tt.wantResult {
+				t.Errorf("ReplaceFile() = %q, want %q", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
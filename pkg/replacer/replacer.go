@@ -1,35 +1,138 @@
 package replacer
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	logger "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	pathpkg "path"
 	"regexp"
 	"strings"
 )
 
-const prefix = `{"keptn.git-promotion.replacewith":"`
+const annotationKey = "keptn.git-promotion.replacewith"
+const prefix = `{"` + annotationKey + `":"`
 const suffix = `"}`
 
 // Replace value marked by yaml comment e.g.
 // tag: 2.5.5 # {"keptn.git-promotion.replacewith":"data.image.tag"}
+// Yaml files are changed with the parsed nodes, so the new values are quoted and escaped as needed and everything
+// else is kept. Files which are not valid yaml (e.g. helm templates) are replaced line by line.
 func Replace(fileData string, tags map[string]string) (result string) {
 	replaced := fileData
 	//quick check for faster processing
-	for k, v := range tags {
-		if strings.Contains(replaced, prefix+k+suffix) {
-			replaced = replaceValue(replaced, k, v)
+	if strings.Contains(fileData, annotationKey) {
+		var err error
+		if replaced, err = replaceYaml(fileData, tags); err != nil {
+			logger.WithField("func", "Replace").Infof("replacing line by line: %s", err.Error())
+			replaced = replaceLines(fileData, tags)
 		}
 	}
 	logger.WithField("func", "Replace").Infof("tags: %v, original: %s, replaced: %s", tags, fileData, replaced)
 	return replaced
 }
 
-func replaceValue(file, key, value string) string {
+// ReplaceFile replaces the annotated values of the file at path with the handler of its format. Values of .env,
+// .properties and .toml files are annotated with a comment in the line above (in .env and .toml files also after the
+// value) e.g.
+// # keptn.git-promotion.replacewith: data.image.tag
+// All other files are replaced with Replace.
+func ReplaceFile(path, fileData string, tags map[string]string) (result string) {
+	var format lineFormat
+	name := strings.ToLower(pathpkg.Base(path))
+	switch {
+	case pathpkg.Ext(name) == ".env" || strings.HasPrefix(name, ".env."):
+		format = envFormat{}
+	case pathpkg.Ext(name) == ".properties":
+		format = propertiesFormat{}
+	case pathpkg.Ext(name) == ".toml":
+		format = tomlFormat{}
+	default:
+		return Replace(fileData, tags)
+	}
+	result = fileData
+	//quick check for faster processing
+	if strings.Contains(fileData, annotationKey) {
+		result = replaceAnnotatedLines(fileData, tags, format)
+	}
+	logger.WithField("func", "ReplaceFile").Infof("path: %s, tags: %v, original: %s, replaced: %s", path, tags, fileData, result)
+	return result
+}
+
+// replaceYaml sets all scalars with an annotation as line comment
+func replaceYaml(fileData string, tags map[string]string) (string, error) {
+	doc, roots, err := parseYamlDocument(fileData)
+	if err != nil {
+		return "", err
+	}
+	annotations := 0
+	// replace sets target if n is annotated, target is the value of n if n is a key
+	var replace func(n, target *yaml.Node) error
+	replace = func(n, target *yaml.Node) error {
+		if key, ok := parseAnnotation(n.LineComment); ok {
+			annotations++
+			if value, ok := lookupValue(tags, key); ok {
+				if err := doc.setScalar(target, value); err != nil {
+					return err
+				}
+			}
+		}
+		for i, c := range n.Content {
+			target := c
+			// the comment of a key without value (e.g. tag: # {...}) is attached to the key
+			if n.Kind == yaml.MappingNode && i%2 == 0 && i+1 < len(n.Content) {
+				target = n.Content[i+1]
+			}
+			if err := replace(c, target); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := replace(root, root); err != nil {
+			return "", err
+		}
+	}
+	// annotations which are not the line comment of a value (e.g. in a block scalar) are left to the line replacement
+	if annotations != strings.Count(fileData, annotationKey) {
+		return "", errors.New(fmt.Sprintf("found %d of %d annotations", annotations, strings.Count(fileData, annotationKey)))
+	}
+	return doc.apply()
+}
+
+// parseAnnotation returns the key of the event field of a comment like # {"keptn.git-promotion.replacewith":"key"}
+func parseAnnotation(comment string) (key string, ok bool) {
+	if !strings.Contains(comment, annotationKey) {
+		return "", false
+	}
+	var annotation map[string]string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(comment, "#"))), &annotation); err != nil {
+		return "", false
+	}
+	key, ok = annotation[annotationKey]
+	return key, ok
+}
+
+// lineAnnotated matches lines with a value and an annotation of the line based replacement
+var lineAnnotated = regexp.MustCompile(`^(.+: ).*( # ` + regexp.QuoteMeta(prefix) + `(.*)` + regexp.QuoteMeta(suffix) + "\r?)$")
+
+// replaceLines replaces the text between ": " and the annotation in each annotated line
+func replaceLines(file string, tags map[string]string) string {
 	splitted := strings.Split(file, "\n")
-	annotation := prefix + key + suffix
-	re := regexp.MustCompile(`(^.+: ).*( # ` + annotation + `$)`)
 	for i, s := range splitted {
-		if strings.Contains(s, annotation) {
-			splitted[i] = re.ReplaceAllString(s, "${1}"+value+"${2}")
+		m := lineAnnotated.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		var key string
+		if err := json.Unmarshal([]byte(`"`+m[3]+`"`), &key); err != nil {
+			continue
+		}
+		if value, ok := lookupValue(tags, key); ok {
+			// the value is inserted literally, a $ in the value must not be expanded
+			splitted[i] = m[1] + value + m[2]
 		}
 	}
 	return strings.Join(splitted, "\n")
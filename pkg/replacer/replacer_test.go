@@ -105,6 +105,86 @@ spec:
         shkeptncontext: mykeptncontext # {"keptn.git-promotion.replacewith":"shkeptncontext"}
 `,
 		},
+		{
+			name: "quoted values",
+			args: args{
+				fileData: `double: "1.0.0" # {"keptn.git-promotion.replacewith":"data.tag"}
+single: 'old' # {"keptn.git-promotion.replacewith":"data.quote"}
+plain: old # {"keptn.git-promotion.replacewith":"data.special"}
+`,
+				tags: map[string]string{
+					"data.tag":     "1.0.1",
+					"data.quote":   "it's",
+					"data.special": `a: "b" # c`,
+				},
+			},
+			wantResult: `double: "1.0.1" # {"keptn.git-promotion.replacewith":"data.tag"}
+single: 'it''s' # {"keptn.git-promotion.replacewith":"data.quote"}
+plain: "a: \"b\" # c" # {"keptn.git-promotion.replacewith":"data.special"}
+`,
+		},
+		{
+			name: "flow mapping and list items",
+			args: args{
+				fileData: `image: {
+  repository: podtato, # {"keptn.git-promotion.replacewith":"data.repository"}
+  tag: 1.0.0 # {"keptn.git-promotion.replacewith":"data.tag"}
+}
+list:
+  - 1.0.0 # {"keptn.git-promotion.replacewith":"data.tag"}
+  - {name: x} # unrelated comment
+  - name: app
+    tag: !!str 1.0 # { "keptn.git-promotion.replacewith" : "data.tag" }
+`,
+				tags: map[string]string{
+					"data.repository": "ghcr.io/podtato",
+					"data.tag":        "1.0.1",
+				},
+			},
+			wantResult: `image: {
+  repository: ghcr.io/podtato, # {"keptn.git-promotion.replacewith":"data.repository"}
+  tag: 1.0.1 # {"keptn.git-promotion.replacewith":"data.tag"}
+}
+list:
+  - 1.0.1 # {"keptn.git-promotion.replacewith":"data.tag"}
+  - {name: x} # unrelated comment
+  - name: app
+    tag: !!str 1.0.1 # { "keptn.git-promotion.replacewith" : "data.tag" }
+`,
+		},
+		{
+			name: "crlf and multiple documents",
+			args: args{
+				fileData: "a: 1 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\r\n---\r\nb: x # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\r\n",
+				tags:     map[string]string{"data.tag": "$1-${1}"},
+			},
+			wantResult: "a: \"$1-${1}\" # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\r\n---\r\nb: \"$1-${1}\" # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\r\n",
+		},
+		{
+			name: "empty values",
+			args: args{
+				fileData: "image:\n  tag: # {\"keptn.git-promotion.replacewith\":\"data.image.tag\"}\n  pullPolicy:    # {\"keptn.git-promotion.replacewith\":\"data.policy\"}\nreplicas: 1\n",
+				tags:     map[string]string{"data.image.tag": "1.2.3", "data.policy": "Always"},
+			},
+			wantResult: "image:\n  tag: 1.2.3 # {\"keptn.git-promotion.replacewith\":\"data.image.tag\"}\n  pullPolicy: Always    # {\"keptn.git-promotion.replacewith\":\"data.policy\"}\nreplicas: 1\n",
+		},
+		{
+			name: "string values keep their type",
+			args: args{
+				fileData: "a: latest # {\"keptn.git-promotion.replacewith\":\"data.a\"}\nb: latest # {\"keptn.git-promotion.replacewith\":\"data.b\"}\nc: latest # {\"keptn.git-promotion.replacewith\":\"data.c\"}\nd: latest # {\"keptn.git-promotion.replacewith\":\"data.d\"}\ne: latest # {\"keptn.git-promotion.replacewith\":\"data.e\"}\nreplicas: 1 # {\"keptn.git-promotion.replacewith\":\"data.replicas\"}\n",
+				tags: map[string]string{"data.a": "1.10", "data.b": "true", "data.c": "null", "data.d": "010", "data.e": "1e3",
+					"data.replicas": "2"},
+			},
+			wantResult: "a: \"1.10\" # {\"keptn.git-promotion.replacewith\":\"data.a\"}\nb: \"true\" # {\"keptn.git-promotion.replacewith\":\"data.b\"}\nc: \"null\" # {\"keptn.git-promotion.replacewith\":\"data.c\"}\nd: \"010\" # {\"keptn.git-promotion.replacewith\":\"data.d\"}\ne: \"1e3\" # {\"keptn.git-promotion.replacewith\":\"data.e\"}\nreplicas: 2 # {\"keptn.git-promotion.replacewith\":\"data.replicas\"}\n",
+		},
+		{
+			name: "template replaced line by line",
+			args: args{
+				fileData: "name: {{ .Chart.Name }}\r\ntag: {{ .Values.tag }} # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\r\n",
+				tags:     map[string]string{"data.tag": "$1"},
+			},
+			wantResult: "name: {{ .Chart.Name }}\r\ntag: $1 # {\"keptn.git-promotion.replacewith\":\"data.tag\"}\r\n",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
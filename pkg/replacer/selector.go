@@ -0,0 +1,192 @@
+package replacer
+
+import (
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"keptn/git-promotion-service/pkg/model"
+	pathpkg "path"
+	"strconv"
+	"strings"
+)
+
+// selectorStep is a key of a mapping or (if index is not negative) an element of a list, numeric tokens of a JSON
+// Pointer have both
+type selectorStep struct {
+	key   string
+	index int
+}
+
+type selectedValue struct {
+	selector string
+	steps    []selectorStep
+	value    string
+}
+
+// CheckSelector returns an error if selector is not a valid path like image.tag, containers[0].image or
+// annotations["app.kubernetes.io/version"] or a JSON Pointer like /containers/0/image
+func CheckSelector(selector string) error {
+	_, err := parseSelector(selector)
+	return err
+}
+
+func parseSelector(selector string) (steps []selectorStep, err error) {
+	if strings.HasPrefix(selector, "/") {
+		return parsePointer(selector)
+	}
+	s := selector
+	// optional root of a JSONPath like $.image.tag
+	if strings.HasPrefix(s, "$") {
+		s = strings.TrimPrefix(s[1:], ".")
+	}
+	if s == "" {
+		return nil, errors.New(fmt.Sprintf("invalid selector %s: no key", selector))
+	}
+	for i := 0; len(s) > 0; i++ {
+		if s[0] == '[' {
+			end := strings.IndexByte(s, ']')
+			if len(s) > 2 && (s[1] == '"' || s[1] == '\'') {
+				end = strings.Index(s[2:], string(s[1])+"]")
+				if end < 0 {
+					return nil, errors.New(fmt.Sprintf("invalid selector %s: unterminated key", selector))
+				}
+				steps = append(steps, selectorStep{key: s[2 : end+2], index: -1})
+				s = s[end+4:]
+				continue
+			}
+			if end < 0 {
+				return nil, errors.New(fmt.Sprintf("invalid selector %s: unterminated index", selector))
+			}
+			index, err := strconv.Atoi(s[1:end])
+			if err != nil || index < 0 {
+				return nil, errors.New(fmt.Sprintf("invalid selector %s: invalid index %s", selector, s[1:end]))
+			}
+			steps = append(steps, selectorStep{index: index})
+			s = s[end+1:]
+			continue
+		}
+		if i > 0 {
+			if s[0] != '.' {
+				return nil, errors.New(fmt.Sprintf("invalid selector %s: unexpected %s", selector, s))
+			}
+			s = s[1:]
+		}
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			return nil, errors.New(fmt.Sprintf("invalid selector %s: empty key", selector))
+		}
+		steps = append(steps, selectorStep{key: s[:end], index: -1})
+		s = s[end:]
+	}
+	return steps, nil
+}
+
+// parsePointer parses a JSON Pointer (RFC 6901), numeric tokens select an element of a list or a key of a mapping
+func parsePointer(pointer string) (steps []selectorStep, err error) {
+	for _, token := range strings.Split(pointer[1:], "/") {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(token), "~") {
+			return nil, errors.New(fmt.Sprintf("invalid selector %s: invalid escape in %s", pointer, token))
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		step := selectorStep{key: token, index: -1}
+		if index, err := strconv.Atoi(token); err == nil && index >= 0 && strconv.Itoa(index) == token {
+			step.index = index
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// MatchFiles reports whether file (relative to the target of the path) matches one of the glob patterns, patterns
+// without a slash are matched against the file name
+func MatchFiles(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		name := file
+		if !strings.Contains(pattern, "/") {
+			name = pathpkg.Base(file)
+		}
+		if matched, _ := pathpkg.Match(strings.TrimPrefix(pattern, "/"), name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceSelected sets the values selected by the replacements whose files match file (relative to the target of the
+// path) to the event fields. Files with the extension .json are changed as json, all others as yaml. Fields which are
+// not part of the event and paths which are not found are skipped.
+func ReplaceSelected(file, fileData string, replacements []model.Replacement, fields map[string]string) (result string, err error) {
+	var values []selectedValue
+	for _, r := range replacements {
+		if !MatchFiles(r.Files, file) {
+			continue
+		}
+		value, ok := lookupValue(fields, *r.Field)
+		if !ok {
+			logger.WithField("func", "ReplaceSelected").Warnf("field %s for %s of %s not found in event", *r.Field, *r.Path, file)
+			continue
+		}
+		steps, err := parseSelector(*r.Path)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, selectedValue{selector: *r.Path, steps: steps, value: value})
+	}
+	if len(values) == 0 {
+		return fileData, nil
+	}
+	if strings.EqualFold(pathpkg.Ext(file), ".json") {
+		return setJSONValues(file, fileData, values)
+	}
+	return setYamlValues(file, fileData, values)
+}
+
+func setYamlValues(file, fileData string, values []selectedValue) (string, error) {
+	doc, roots, err := parseYamlDocument(fileData)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("could not parse %s: %s", file, err.Error()))
+	}
+	for _, v := range values {
+		found := false
+		for _, root := range roots {
+			if n := selectNode(root, v.steps); n != nil {
+				found = true
+				if err := doc.setScalar(n, v.value); err != nil {
+					return "", errors.New(fmt.Sprintf("could not set %s of %s: %s", v.selector, file, err.Error()))
+				}
+			}
+		}
+		if !found {
+			logger.WithField("func", "setYamlValues").Warnf("%s not found in %s", v.selector, file)
+		}
+	}
+	return doc.apply()
+}
+
+// selectNode returns the node of the document root selected by steps, nil if it does not exist
+func selectNode(root *yaml.Node, steps []selectorStep) *yaml.Node {
+	n := root
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+	for _, step := range steps {
+		switch {
+		case step.index >= 0 && n.Kind == yaml.SequenceNode && step.index < len(n.Content):
+			n = n.Content[step.index]
+		case (step.index < 0 || step.key != "") && n.Kind == yaml.MappingNode:
+			if _, n = mappingValue(n, step.key); n == nil {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+	return n
+}
@@ -0,0 +1,165 @@
+package replacer
+
+import (
+	"keptn/git-promotion-service/pkg/model"
+	"reflect"
+	"testing"
+)
+
+func Test_parseSelector(t *testing.T) {
+	tests := []struct {
+		selector  string
+		wantSteps []selectorStep
+		wantErr   bool
+	}{
+		{selector: "image.tag", wantSteps: []selectorStep{{key: "image", index: -1}, {key: "tag", index: -1}}},
+		{selector: "$.containers[1].image", wantSteps: []selectorStep{{key: "containers", index: -1}, {index: 1}, {key: "image", index: -1}}},
+		{selector: "$[0]", wantSteps: []selectorStep{{index: 0}}},
+		{selector: `annotations["app.kubernetes.io/version"].x`, wantSteps: []selectorStep{{key: "annotations", index: -1},
+			{key: "app.kubernetes.io/version", index: -1}, {key: "x", index: -1}}},
+		{selector: "labels['a.b']", wantSteps: []selectorStep{{key: "labels", index: -1}, {key: "a.b", index: -1}}},
+		{selector: "/containers/0/image", wantSteps: []selectorStep{{key: "containers", index: -1}, {key: "0", index: 0},
+			{key: "image", index: -1}}},
+		{selector: "/a~1b/~01/01", wantSteps: []selectorStep{{key: "a/b", index: -1}, {key: "~1", index: -1}, {key: "01", index: -1}}},
+		{selector: "/", wantSteps: []selectorStep{{key: "", index: -1}}},
+		{selector: "/a~2", wantErr: true},
+		{selector: "/a~", wantErr: true},
+		{selector: "", wantErr: true},
+		{selector: "$", wantErr: true},
+		{selector: "image..tag", wantErr: true},
+		{selector: ".image", wantErr: true},
+		{selector: "image.", wantErr: true},
+		{selector: "list[x]", wantErr: true},
+		{selector: "list[0", wantErr: true},
+		{selector: "list[0]x", wantErr: true},
+		{selector: `map["x]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			gotSteps, err := parseSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSelector() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotSteps, tt.wantSteps) {
+				t.Errorf("parseSelector() = %v, want %v", gotSteps, tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestMatchFiles(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		file     string
+		want     bool
+	}{
+		{patterns: []string{"values.yaml"}, file: "values.yaml", want: true},
+		{patterns: []string{"values.yaml"}, file: "charts/app/values.yaml", want: true},
+		{patterns: []string{"*.json", "values-*.yaml"}, file: "charts/values-prod.yaml", want: true},
+		{patterns: []string{"charts/*/values.yaml"}, file: "charts/app/values.yaml", want: true},
+		{patterns: []string{"/charts/*/values.yaml"}, file: "charts/app/values.yaml", want: true},
+		{patterns: []string{"charts/values.yaml"}, file: "charts/app/values.yaml", want: false},
+		{patterns: []string{"*.yaml"}, file: "Chart.lock", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := MatchFiles(tt.patterns, tt.file); got != tt.want {
+				t.Errorf("MatchFiles(%v) = %v, want %v", tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceSelected(t *testing.T) {
+	str := func(s string) *string {
+		return &s
+	}
+	replacements := []model.Replacement{
+		{Files: []string{"values.yaml"}, Path: str("image.tag"), Field: str("data.tag")},
+		{Files: []string{"values.yaml"}, Path: str(`podAnnotations["keptn.sh/context"]`), Field: str("shkeptncontext")},
+		{Files: []string{"*.yaml"}, Path: str("sidecars[1].image"), Field: str("data.sidecar")},
+		{Files: []string{"values.yaml"}, Path: str("image.repository"), Field: str("data.missing")},
+		{Files: []string{"values.yaml"}, Path: str("podAnnotations.flow"), Field: str("data.flow")},
+		{Files: []string{"numbers.yaml"}, Path: str("image.tag"), Field: str("data.numberTag")},
+	}
+	fields := map[string]string{
+		"data.tag":       "1.0.1",
+		"data.sidecar":   "envoy:1.22",
+		"data.flow":      "a, b",
+		"data.numberTag": "1.10",
+		"shkeptncontext": "a7d2c1f0",
+	}
+	tests := []struct {
+		name       string
+		file       string
+		fileData   string
+		wantResult string
+		wantErr    bool
+	}{
+		{
+			name: "vendored values",
+			file: "charts/app/values.yaml",
+			fileData: `# vendored chart
+image:
+  repository: nginx
+  tag: "1.0.0" # pinned
+podAnnotations: {keptn.sh/context: none, flow: x}
+sidecars:
+  - image: busybox
+  - image: envoy:1.21
+`,
+			wantResult: `# vendored chart
+image:
+  repository: nginx
+  tag: "1.0.1" # pinned
+podAnnotations: {keptn.sh/context: a7d2c1f0, flow: "a, b"}
+sidecars:
+  - image: busybox
+  - image: envoy:1.22
+`,
+		},
+		{
+			name:       "string value which looks like a number",
+			file:       "numbers.yaml",
+			fileData:   "image:\n  tag: latest\n",
+			wantResult: "image:\n  tag: \"1.10\"\n",
+		},
+		{
+			name:       "path not found",
+			file:       "other.yaml",
+			fileData:   "sidecars: []\n",
+			wantResult: "sidecars: []\n",
+		},
+		{
+			name:       "no matching file",
+			file:       "templates/deployment.tpl",
+			fileData:   "{{ invalid yaml",
+			wantResult: "{{ invalid yaml",
+		},
+		{
+			name:     "not a scalar",
+			file:     "values.yaml",
+			fileData: "image:\n  tag: [1]\n",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid yaml",
+			file:     "values.yaml",
+			fileData: "image: [",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotResult, err := ReplaceSelected(tt.file, tt.fileData, replacements, fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReplaceSelected() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotResult != tt.wantResult {
+				t.Errorf("ReplaceSelected() = %q, want %q", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
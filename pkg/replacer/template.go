@@ -0,0 +1,109 @@
+package replacer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"strings"
+	"text/template"
+)
+
+// noValue is written by text/template for keys which are not part of the fields
+const noValue = "<no value>"
+
+// templateFuncs are the functions available in value templates, the arguments are ordered for pipelines like
+// {{ .data.image.tag | trimPrefix "v" }}
+var templateFuncs = template.FuncMap{
+	"default": func(def string, value interface{}) string {
+		if value == nil || value == "" {
+			return def
+		}
+		return fmt.Sprint(value)
+	},
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+	"sha256": func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+}
+
+// isTemplate reports whether key is a template instead of the key of a field
+func isTemplate(key string) bool {
+	return strings.Contains(key, "{{")
+}
+
+// CheckTemplate returns an error if key is a template which can't be parsed
+func CheckTemplate(key string) error {
+	if !isTemplate(key) {
+		return nil
+	}
+	_, err := template.New("value").Funcs(templateFuncs).Parse(key)
+	return err
+}
+
+// lookupValue returns the value of the field key. Keys containing {{ are Go templates evaluated with the fields, e.g.
+// {{ .data.image.repository }}:{{ .data.image.tag | default "latest" }}
+// The fields are accessible with their dotted keys and with the function field (e.g. {{ field "data.my-key" }}).
+// Templates using fields which are not part of the event without a default are skipped like missing fields.
+func lookupValue(fields map[string]string, key string) (value string, ok bool) {
+	if !isTemplate(key) {
+		value, ok = fields[key]
+		return value, ok
+	}
+	value, err := evaluateTemplate(fields, key)
+	if err != nil {
+		logger.WithField("func", "lookupValue").Warnf("could not evaluate %s: %s", key, err.Error())
+		return "", false
+	}
+	return value, true
+}
+
+func evaluateTemplate(fields map[string]string, text string) (string, error) {
+	t, err := template.New("value").Funcs(templateFuncs).Funcs(template.FuncMap{
+		"field": func(key string) interface{} {
+			if value, ok := fields[key]; ok {
+				return value
+			}
+			return nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, nestFields(fields)); err != nil {
+		return "", err
+	}
+	if strings.Contains(b.String(), noValue) {
+		return "", errors.New("field not found")
+	}
+	return b.String(), nil
+}
+
+// nestFields returns the fields as nested maps, keys which are also the prefix of other keys (e.g. data.image and
+// data.image.tag) are only accessible as map
+func nestFields(fields map[string]string) map[string]interface{} {
+	root := map[string]interface{}{}
+	for key, value := range fields {
+		parts := strings.Split(key, ".")
+		m := root
+		for _, p := range parts[:len(parts)-1] {
+			child, ok := m[p].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				m[p] = child
+			}
+			m = child
+		}
+		if _, ok := m[parts[len(parts)-1]].(map[string]interface{}); !ok {
+			m[parts[len(parts)-1]] = value
+		}
+	}
+	return root
+}
@@ -0,0 +1,81 @@
+package replacer
+
+import "testing"
+
+func Test_lookupValue(t *testing.T) {
+	fields := map[string]string{
+		"data.image.repository": "ghcr.io/podtato",
+		"data.image.tag":        "v1.0.1",
+		"data.stage":            "Production",
+		"data.my-key":           "dashed",
+		"data.image":            "shadowed by the map",
+		"shkeptncontext":        "a7d2c1f0",
+	}
+	tests := []struct {
+		name      string
+		key       string
+		wantValue string
+		wantOk    bool
+	}{
+		{name: "field", key: "data.image.tag", wantValue: "v1.0.1", wantOk: true},
+		{name: "missing field", key: "data.missing", wantOk: false},
+		{name: "concatenation", key: "{{ .data.image.repository }}:{{ .data.image.tag }}", wantValue: "ghcr.io/podtato:v1.0.1", wantOk: true},
+		{name: "functions", key: `{{ .data.image.tag | trimPrefix "v" }}-{{ lower .data.stage }}`, wantValue: "1.0.1-production", wantOk: true},
+		{name: "default", key: "{{ .data.missing | default `latest` }}", wantValue: "latest", wantOk: true},
+		{name: "default not used", key: `{{ .data.image.tag | default "latest" }}`, wantValue: "v1.0.1", wantOk: true},
+		{name: "field function", key: `{{ field "data.my-key" | upper }}`, wantValue: "DASHED", wantOk: true},
+		{name: "missing field function", key: `{{ field "data.x" | default "none" }}`, wantValue: "none", wantOk: true},
+		{name: "sha256", key: "{{ sha256 .shkeptncontext }}", wantValue: "c319360ed42f57b0653e4344fb02ed48feb81019f5678bfa98d49d4ef6fa2a49", wantOk: true},
+		{name: "replace", key: `{{ replace "/" "-" .data.image.repository }}`, wantValue: "ghcr.io-podtato", wantOk: true},
+		{name: "missing field without default", key: "{{ .data.missing }}", wantOk: false},
+		{name: "invalid template", key: "{{ .data.image.tag ", wantOk: false},
+		{name: "invalid function argument", key: "{{ lower .data.missing }}", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotValue, gotOk := lookupValue(fields, tt.key)
+			if gotValue != tt.wantValue || gotOk != tt.wantOk {
+				t.Errorf("lookupValue() = %q, %v, want %q, %v", gotValue, gotOk, tt.wantValue, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestReplaceFileWithTemplates(t *testing.T) {
+	tags := map[string]string{
+		"data.image.repository": "ghcr.io/podtato",
+		"data.image.tag":        "1.0.1",
+	}
+	tests := []struct {
+		name       string
+		path       string
+		fileData   string
+		wantResult string
+	}{
+		{
+			name:       "yaml",
+			path:       "values.yaml",
+			fileData:   "image: x # {\"keptn.git-promotion.replacewith\":\"{{ .data.image.repository }}:{{ .data.image.tag }}\"}\n",
+			wantResult: "image: ghcr.io/podtato:1.0.1 # {\"keptn.git-promotion.replacewith\":\"{{ .data.image.repository }}:{{ .data.image.tag }}\"}\n",
+		},
+		{
+			name:       "yaml replaced line by line",
+			path:       "templates/deployment.yaml",
+			fileData:   "name: {{ .Chart.Name }}\nchannel: x # {\"keptn.git-promotion.replacewith\":\"{{ .data.channel | default \\\"stable\\\" }}\"}\n",
+			wantResult: "name: {{ .Chart.Name }}\nchannel: stable # {\"keptn.git-promotion.replacewith\":\"{{ .data.channel | default \\\"stable\\\" }}\"}\n",
+		},
+		{
+			name:       "properties",
+			path:       "application.properties",
+			fileData:   "# keptn.git-promotion.replacewith: {{ .data.image.repository }}:{{ .data.image.tag }}\nimage=x\n",
+			wantResult: "# keptn.git-promotion.replacewith: {{ .data.image.repository }}:{{ .data.image.tag }}\nimage=ghcr.io/podtato:1.0.1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotResult := ReplaceFile(tt.path, tt.fileData, tags); gotResult != tt.wantResult {
+				t.Errorf("ReplaceFile() = %q, want %q", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
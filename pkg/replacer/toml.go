@@ -0,0 +1,75 @@
+package replacer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tomlLiteral matches booleans, numbers and dates which are written without quotes
+var tomlLiteral = regexp.MustCompile(`^(true|false|[+-]?(inf|nan)|[+-]?\d[\d_]*(\.\d[\d_]*)?([eE][+-]?\d[\d_]*)?|` +
+	`0x[\dA-Fa-f_]+|0o[0-7_]+|0b[01_]+|\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?)?|` +
+	`\d{2}:\d{2}:\d{2}(\.\d+)?)$`)
+
+// tomlFormat are TOML files, only single line values which are no arrays or inline tables are supported
+type tomlFormat struct{}
+
+func (tomlFormat) value(lines []string, i int) (start, end, last int, ok bool) {
+	line := lines[i]
+	if strings.HasPrefix(strings.TrimSpace(line), "[") {
+		return 0, 0, 0, false
+	}
+	// keys may be quoted and dotted
+	p := 0
+	for ; p < len(line) && line[p] != '='; p++ {
+		if line[p] == '"' || line[p] == '\'' {
+			q := quotedEnd(line, p)
+			if q < 0 {
+				return 0, 0, 0, false
+			}
+			p = q - 1
+		}
+	}
+	if p == len(line) {
+		return 0, 0, 0, false
+	}
+	start = p + 1 + len(line[p+1:]) - len(strings.TrimLeft(line[p+1:], " \t"))
+	rest := line[start:]
+	switch {
+	case strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''") || strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "{"):
+		return 0, 0, 0, false
+	case strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "'"):
+		if end = quotedEnd(line, start); end < 0 {
+			return 0, 0, 0, false
+		}
+		return start, end, i, true
+	}
+	end = len(line)
+	if c := strings.IndexByte(rest, '#'); c >= 0 {
+		end = start + c
+	}
+	return start, start + len(strings.TrimRight(line[start:end], " \t")), i, true
+}
+
+// format keeps the quotes of current, booleans, numbers and dates are only written without quotes if they replace one
+func (tomlFormat) format(value, current string) string {
+	control := strings.IndexFunc(value, func(r rune) bool {
+		return (r < 0x20 && r != '\t') || r == 0x7f
+	}) >= 0
+	switch {
+	case strings.HasPrefix(current, "'") && !strings.Contains(value, "'") && !control:
+		return "'" + value + "'"
+	case !strings.HasPrefix(current, "'") && !strings.HasPrefix(current, `"`) && tomlLiteral.MatchString(value):
+		return value
+	default:
+		// json strings are valid basic strings
+		return jsonString(value)
+	}
+}
+
+func (tomlFormat) comments() string {
+	return "#"
+}
+
+func (tomlFormat) inlineComments() bool {
+	return true
+}
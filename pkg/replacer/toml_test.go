@@ -0,0 +1,44 @@
+package replacer
+
+import "testing"
+
+func TestReplaceFileToml(t *testing.T) {
+	tags := map[string]string{
+		"data.image.tag": "1.0.1",
+		"data.replicas":  "3",
+		"data.message":   "it's\tdone",
+	}
+	tests := []struct {
+		name       string
+		fileData   string
+		wantResult string
+	}{
+		{
+			name: "strings and literals",
+			fileData: "[image]\n# keptn.git-promotion.replacewith: data.image.tag\ntag = \"1.0.0\"\n" +
+				"\"quoted.key\" = '1.0.0' # keptn.git-promotion.replacewith: data.image.tag\n" +
+				"replicas = 1 # keptn.git-promotion.replacewith: data.replicas\n" +
+				"version = 1.0 # keptn.git-promotion.replacewith: data.image.tag\n" +
+				"message = 'old' # keptn.git-promotion.replacewith: data.message\n",
+			wantResult: "[image]\n# keptn.git-promotion.replacewith: data.image.tag\ntag = \"1.0.1\"\n" +
+				"\"quoted.key\" = '1.0.1' # keptn.git-promotion.replacewith: data.image.tag\n" +
+				"replicas = 3 # keptn.git-promotion.replacewith: data.replicas\n" +
+				"version = \"1.0.1\" # keptn.git-promotion.replacewith: data.image.tag\n" +
+				"message = \"it's\\tdone\" # keptn.git-promotion.replacewith: data.message\n",
+		},
+		{
+			name: "unsupported values",
+			fileData: "# keptn.git-promotion.replacewith: data.image.tag\ntags = [\"1.0.0\"]\n" +
+				"text = \"\"\"\nx\"\"\" # keptn.git-promotion.replacewith: data.image.tag\n",
+			wantResult: "# keptn.git-promotion.replacewith: data.image.tag\ntags = [\"1.0.0\"]\n" +
+				"text = \"\"\"\nx\"\"\" # keptn.git-promotion.replacewith: data.image.tag\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotResult := ReplaceFile("config.toml", tt.fileData, tags); gotResult != tt.wantResult {
+				t.Errorf("ReplaceFile() = %q, want %q", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package replacer
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// yamlDocument applies formatting preserving edits to a yaml file. The values are located with the nodes parsed by
+// yaml.v3, but instead of encoding the nodes again only the text of the changed values is replaced, so comments,
+// indentation, blank lines and the quoting of all other values are kept.
+type yamlDocument struct {
+	content string
+	// lineOffsets contains the byte offset of the start of each line
+	lineOffsets []int
+	newline     string
+	edits       []textEdit
+}
+
+// textEdit replaces the bytes from start to end with text
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// parseYamlDocument returns the root nodes of all documents in content
+func parseYamlDocument(content string) (doc *yamlDocument, roots []*yaml.Node, err error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var root yaml.Node
+		if err := decoder.Decode(&root); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		roots = append(roots, &root)
+	}
+	doc = &yamlDocument{content: content, lineOffsets: []int{0}, newline: "\n"}
+	for i, c := range content {
+		if c == '\n' {
+			doc.lineOffsets = append(doc.lineOffsets, i+1)
+		}
+	}
+	if strings.Contains(content, "\r\n") {
+		doc.newline = "\r\n"
+	}
+	return doc, roots, nil
+}
+
+// offset converts the 1-based line and column (in characters) of a node to a byte offset
+func (d *yamlDocument) offset(line, column int) int {
+	if line < 1 || line > len(d.lineOffsets) {
+		return len(d.content)
+	}
+	offset := d.lineOffsets[line-1]
+	for i := 1; i < column && offset < len(d.content); i++ {
+		_, size := utf8.DecodeRuneInString(d.content[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// scalarSpan returns the byte range of the value of n in the content including quotes
+func (d *yamlDocument) scalarSpan(n *yaml.Node) (start, end int, err error) {
+	if n.Kind != yaml.ScalarNode {
+		return 0, 0, errors.New(fmt.Sprintf("value in line %d is not a scalar", n.Line))
+	}
+	start = d.offset(n.Line, n.Column)
+	// the node starts with its properties (e.g. &anchor !!str value)
+	properties := 0
+	if n.Anchor != "" {
+		properties++
+	}
+	if n.Style&yaml.TaggedStyle != 0 {
+		properties++
+	}
+	for ; properties > 0; properties-- {
+		for start < len(d.content) && !strings.ContainsRune(" \t\r\n", rune(d.content[start])) {
+			start++
+		}
+		for start < len(d.content) && strings.ContainsRune(" \t\r\n", rune(d.content[start])) {
+			start++
+		}
+	}
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(d.content); i++ {
+			if d.content[i] == '\\' {
+				i++
+			} else if d.content[i] == '"' {
+				return start, i + 1, nil
+			}
+		}
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(d.content); i++ {
+			if d.content[i] == '\'' && i+1 < len(d.content) && d.content[i+1] == '\'' {
+				i++
+			} else if d.content[i] == '\'' {
+				return start, i + 1, nil
+			}
+		}
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0:
+		// plain scalars are written as is unless they span multiple lines
+		if strings.HasPrefix(d.content[start:], n.Value) {
+			return start, start + len(n.Value), nil
+		}
+	}
+	return 0, 0, errors.New(fmt.Sprintf("unsupported value format in line %d", n.Line))
+}
+
+// setScalar replaces the value of n, the quoting style of the current value is kept if possible
+func (d *yamlDocument) setScalar(n *yaml.Node, value string) error {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" && n.Value == "" && n.Style == 0 && n.Anchor == "" {
+		// an empty value starts right after the colon of its key (e.g. tag: # comment), it is a placeholder for a string
+		start := d.offset(n.Line, n.Column)
+		text := formatYamlScalar(value, "!!str", 0)
+		if start > 0 && d.content[start-1] == ':' {
+			text = " " + text
+		}
+		d.insert(start, text)
+		return nil
+	}
+	start, end, err := d.scalarSpan(n)
+	if err != nil {
+		return err
+	}
+	tag := n.ShortTag()
+	if n.Style&yaml.TaggedStyle != 0 {
+		// the explicit tag of the value is kept
+		tag = ""
+	}
+	d.edits = append(d.edits, textEdit{start: start, end: end, text: formatYamlScalar(value, tag, n.Style)})
+	return nil
+}
+
+func (d *yamlDocument) insert(offset int, text string) {
+	d.edits = append(d.edits, textEdit{start: offset, end: offset, text: text})
+}
+
+// insertLines inserts lines after the given line
+func (d *yamlDocument) insertLines(after int, lines ...string) {
+	text := strings.Join(lines, d.newline)
+	if after < len(d.lineOffsets) {
+		d.insert(d.lineOffsets[after], text+d.newline)
+	} else {
+		d.insert(len(d.content), d.newline+text)
+	}
+}
+
+// apply returns the content with all edits
+func (d *yamlDocument) apply() (result string, err error) {
+	return applyEdits(d.content, d.edits)
+}
+
+// applyEdits returns content with all edits, text inserted at the same offset keeps the order of the edits
+func applyEdits(content string, edits []textEdit) (result string, err error) {
+	sorted := make([]textEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].start < sorted[j].start
+	})
+	var b strings.Builder
+	last := 0
+	for _, e := range sorted {
+		if e.start < last {
+			return "", errors.New(fmt.Sprintf("conflicting changes at offset %d", e.start))
+		}
+		b.WriteString(content[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.WriteString(content[last:])
+	return b.String(), nil
+}
+
+// formatYamlScalar returns value in the given style. Plain values are written as is (like the line based replacement)
+// unless they would change the structure of the document or would not be read with the given tag (e.g. the string
+// 1.10 would be read as float 1.1), then they are double quoted. An empty tag accepts any tag.
+func formatYamlScalar(value, tag string, style yaml.Style) string {
+	switch {
+	case style&yaml.SingleQuotedStyle != 0 && !strings.ContainsAny(value, "\r\n"):
+		return "'" + strings.Replace(value, "'", "''", -1) + "'"
+	case style&yaml.DoubleQuotedStyle != 0 || !isPlainYamlScalar(value, tag):
+		// json strings are valid double quoted yaml scalars
+		return jsonString(value)
+	default:
+		return value
+	}
+}
+
+// isPlainYamlScalar reports whether value is read back unchanged as plain scalar with the given tag. Values with commas
+// or brackets are quoted as well, they would end the value in a flow collection.
+func isPlainYamlScalar(value, tag string) bool {
+	if value == "" || strings.ContainsAny(value, "\r\n,[]{}") || strings.TrimSpace(value) != value {
+		return false
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("key: "+value), &node); err != nil || len(node.Content) != 1 {
+		return false
+	}
+	mapping := node.Content[0]
+	return mapping.Kind == yaml.MappingNode && len(mapping.Content) == 2 && mapping.Content[1].Kind == yaml.ScalarNode &&
+		mapping.Content[1].Style == 0 && mapping.Content[1].Value == value && mapping.Content[1].LineComment == "" &&
+		(tag == "" || mapping.Content[1].ShortTag() == tag && (tag != "!!str" || !isYaml11Bool(value)))
+}
+
+// isYaml11Bool reports whether value is a boolean in yaml 1.1, which is still used by helm and kubectl (e.g. yes and on)
+func isYaml11Bool(value string) bool {
+	switch strings.ToLower(value) {
+	case "y", "yes", "n", "no", "on", "off", "true", "false":
+		return true
+	}
+	return false
+}
+
+// mappingValue returns the value of key in mapping and the key node, nil if the key does not exist
+func mappingValue(mapping *yaml.Node, key string) (keyNode, value *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// endLine returns the last line of the text of n, including the lines of nested nodes and of scalars spanning multiple
+// lines (e.g. block scalars)
+func (d *yamlDocument) endLine(n *yaml.Node) int {
+	switch {
+	case (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) && n.Style&yaml.FlowStyle != 0:
+		if end, err := d.flowEnd(d.offset(n.Line, n.Column)); err == nil {
+			return d.line(end)
+		}
+	case len(n.Content) > 0:
+		return d.endLine(n.Content[len(n.Content)-1])
+	case n.Kind == yaml.ScalarNode:
+		// the continuation lines of a scalar are indented more than the line it starts in, blank lines in between
+		// are part of it
+		indent := d.indentation(n.Line)
+		end := n.Line
+		for l := n.Line + 1; l <= len(d.lineOffsets); l++ {
+			if text := d.lineText(l); strings.TrimSpace(text) == "" {
+				continue
+			} else if d.indentation(l) <= indent {
+				break
+			}
+			end = l
+		}
+		return end
+	}
+	return n.Line
+}
+
+// line returns the 1-based line of offset
+func (d *yamlDocument) line(offset int) int {
+	return sort.Search(len(d.lineOffsets), func(i int) bool { return d.lineOffsets[i] > offset })
+}
+
+// lineText returns the 1-based line without line break
+func (d *yamlDocument) lineText(line int) string {
+	end := len(d.content)
+	if line < len(d.lineOffsets) {
+		end = d.lineOffsets[line]
+	}
+	return strings.TrimRight(d.content[d.lineOffsets[line-1]:end], "\r\n")
+}
+
+// indentation returns the number of spaces the 1-based line starts with
+func (d *yamlDocument) indentation(line int) int {
+	text := d.lineText(line)
+	return len(text) - len(strings.TrimLeft(text, " "))
+}
+
+// flowEnd returns the offset of the bracket closing the flow collection starting at start
+func (d *yamlDocument) flowEnd(start int) (end int, err error) {
+	depth := 0
+	for i := start; i < len(d.content); i++ {
+		switch d.content[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			if depth--; depth == 0 {
+				return i, nil
+			}
+		case '"':
+			for i++; i < len(d.content) && d.content[i] != '"'; i++ {
+				if d.content[i] == '\\' {
+					i++
+				}
+			}
+		case '\'':
+			for i++; i < len(d.content); i++ {
+				if d.content[i] == '\'' {
+					if i+1 >= len(d.content) || d.content[i+1] != '\'' {
+						break
+					}
+					i++
+				}
+			}
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("unterminated flow collection at offset %d", start))
+}
@@ -0,0 +1,36 @@
+package replacer
+
+import (
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func Test_formatYamlScalar(t *testing.T) {
+	tests := []struct {
+		value string
+		tag   string
+		style yaml.Style
+		want  string
+	}{
+		{value: "1.2.3", tag: "!!str", want: "1.2.3"},
+		{value: "1.10", tag: "!!str", want: `"1.10"`},
+		{value: "true", tag: "!!str", want: `"true"`},
+		{value: "yes", tag: "!!str", want: `"yes"`},
+		{value: "null", tag: "!!str", want: `"null"`},
+		{value: "010", tag: "!!str", want: `"010"`},
+		{value: "0x10", tag: "!!str", want: `"0x10"`},
+		{value: "1e3", tag: "!!str", want: `"1e3"`},
+		{value: "1.10", tag: "!!float", want: "1.10"},
+		{value: "yes", tag: "!!bool", want: `"yes"`},
+		{value: "1e3", tag: "", want: "1e3"},
+		{value: "a: b", tag: "", want: `"a: b"`},
+		{value: "1.10", tag: "!!str", style: yaml.SingleQuotedStyle, want: "'1.10'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.tag, func(t *testing.T) {
+			if got := formatYamlScalar(tt.value, tt.tag, tt.style); got != tt.want {
+				t.Errorf("formatYamlScalar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const azureApiVersion = "6.0"
+const azureEmptyObjectId = "0000000000000000000000000000000000000000"
+
+type azureClient struct {
+	// organizationURL is https://dev.azure.com/<organization> (or the collection url of an Azure DevOps Server)
+	organizationURL string
+	project         string
+	repository      string
+	rest            restClient
+}
+
+func newAzureClient(httpClient *http.Client, accessToken string, repositoryUrl string, customApiURL *string) (client *azureClient, err error) {
+	organizationURL, project, repository, err := getAzureOrganizationProjectRepository(repositoryUrl)
+	if err != nil {
+		return nil, err
+	}
+	if customApiURL != nil && *customApiURL != "" {
+		organizationURL = strings.TrimSuffix(*customApiURL, "/")
+	}
+	return &azureClient{
+		organizationURL: organizationURL,
+		project:         project,
+		repository:      repository,
+		rest: restClient{
+			baseURL:    fmt.Sprintf("%s/%s/_apis/git/repositories/%s", organizationURL, url.PathEscape(project), url.PathEscape(repository)),
+			httpClient: httpClient,
+			headers:    map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(":"+accessToken))},
+		},
+	}, nil
+}
+
+// getAzureOrganizationProjectRepository splits a repository url like https://dev.azure.com/<organization>/<project>/_git/<repository>
+// (or https://<organization>.visualstudio.com/<project>/_git/<repository>) into the url of the organization, the project and the repository
+func getAzureOrganizationProjectRepository(raw string) (organizationURL, project, repository string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", "", err
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, s := range segments {
+		if s == "_git" && i > 0 && i+1 < len(segments) {
+			organizationURL = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+			if i > 1 {
+				organizationURL += "/" + strings.Join(segments[:i-1], "/")
+			}
+			project, _ = url.PathUnescape(segments[i-1])
+			repository, _ = url.PathUnescape(segments[i+1])
+			return organizationURL, project, repository, nil
+		}
+	}
+	return "", "", "", errors.New(fmt.Sprintf("repository url %s is not an azure devops git repository url", raw))
+}
+
+// query adds the mandatory api-version to the given query parameters
+func (c *azureClient) query(values url.Values) url.Values {
+	if values == nil {
+		values = url.Values{}
+	}
+	values.Set("api-version", azureApiVersion)
+	return values
+}
+
+func azureBranchRef(branch string) string {
+	return "refs/heads/" + branch
+}
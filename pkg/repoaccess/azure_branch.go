@@ -0,0 +1,94 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type azureRef struct {
+	Name     string `json:"name"`
+	ObjectID string `json:"objectId"`
+}
+
+type azureRefs struct {
+	Value []azureRef `json:"value"`
+}
+
+type azureRefUpdate struct {
+	Name        string `json:"name"`
+	OldObjectID string `json:"oldObjectId"`
+	NewObjectID string `json:"newObjectId,omitempty"`
+}
+
+type azureRefUpdateResults struct {
+	Value []struct {
+		Name          string `json:"name"`
+		Success       bool   `json:"success"`
+		UpdateStatus  string `json:"updateStatus"`
+		CustomMessage string `json:"customMessage"`
+	} `json:"value"`
+}
+
+func (c *azureClient) BranchExists(branchName string) (exists bool, err error) {
+	ref, err := c.getBranchRef(branchName)
+	return ref != nil, err
+}
+
+// getBranchRef returns nil if the branch does not exist
+func (c *azureClient) getBranchRef(branchName string) (ref *azureRef, err error) {
+	var refs azureRefs
+	if err := c.rest.do(http.MethodGet, "/refs", c.query(url.Values{"filter": {"heads/" + branchName}}), nil, &refs); err != nil {
+		return nil, err
+	}
+	// the filter is a prefix match
+	for _, r := range refs.Value {
+		if r.Name == azureBranchRef(branchName) {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *azureClient) CreateBranch(sourceBranch, targetBranch string) (err error) {
+	source, err := c.getBranchRef(sourceBranch)
+	if err != nil {
+		return err
+	} else if source == nil {
+		return errors.New(fmt.Sprintf("branch %s not found", sourceBranch))
+	}
+	return c.updateRef(azureRefUpdate{Name: azureBranchRef(targetBranch), OldObjectID: azureEmptyObjectId, NewObjectID: source.ObjectID})
+}
+
+func (c *azureClient) DeleteBranch(branch string) (err error) {
+	ref, err := c.getBranchRef(branch)
+	if err != nil || ref == nil {
+		return err
+	}
+	return c.updateRef(azureRefUpdate{Name: ref.Name, OldObjectID: ref.ObjectID, NewObjectID: azureEmptyObjectId})
+}
+
+func (c *azureClient) updateRef(update azureRefUpdate) (err error) {
+	var results azureRefUpdateResults
+	if err := c.rest.do(http.MethodPost, "/refs", c.query(nil), []azureRefUpdate{update}, &results); err != nil {
+		return err
+	}
+	for _, r := range results.Value {
+		if !r.Success {
+			return errors.New(fmt.Sprintf("update of ref %s failed with status %s: %s", r.Name, r.UpdateStatus, r.CustomMessage))
+		}
+	}
+	return nil
+}
+
+func (c *azureClient) DefaultBranch() (branch string, err error) {
+	var repository struct {
+		DefaultBranch string `json:"defaultBranch"`
+	}
+	if err := c.rest.do(http.MethodGet, "", c.query(nil), nil, &repository); err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(repository.DefaultBranch, "refs/heads/"), nil
+}
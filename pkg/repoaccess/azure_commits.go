@@ -0,0 +1,152 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type azureCommitDiffs struct {
+	AheadCount  int `json:"aheadCount"`
+	BehindCount int `json:"behindCount"`
+}
+
+type azureItem struct {
+	ObjectID      string `json:"objectId"`
+	GitObjectType string `json:"gitObjectType"`
+	Path          string `json:"path"`
+}
+
+type azureItems struct {
+	Value []azureItem `json:"value"`
+}
+
+type azureChange struct {
+	ChangeType string `json:"changeType"`
+	Item       struct {
+		Path string `json:"path"`
+	} `json:"item"`
+	NewContent *azureNewContent `json:"newContent,omitempty"`
+}
+
+type azureNewContent struct {
+	Content     string `json:"content"`
+	ContentType string `json:"contentType"`
+}
+
+type azurePushCommit struct {
+	Comment string        `json:"comment"`
+	Changes []azureChange `json:"changes"`
+}
+
+type azurePush struct {
+	RefUpdates []azureRefUpdate  `json:"refUpdates"`
+	Commits    []azurePushCommit `json:"commits"`
+}
+
+func (c *azureClient) CheckForNewCommits(toBranch, fromBranch string) (newCommits bool, err error) {
+	var diffs azureCommitDiffs
+	if err := c.rest.do(http.MethodGet, "/diffs/commits", c.query(url.Values{
+		"baseVersion":       {toBranch},
+		"baseVersionType":   {"branch"},
+		"targetVersion":     {fromBranch},
+		"targetVersionType": {"branch"},
+		"$top":              {"1"},
+	}), nil, &diffs); err != nil {
+		return false, err
+	}
+	logger.WithField("func", "CheckForNewCommits").Infof("found %d commits in azure repo %s/%s from branch %s to %s", diffs.AheadCount, c.project, c.repository, fromBranch, toBranch)
+	return diffs.AheadCount > 0, nil
+}
+
+func (c *azureClient) GetFilesForBranch(branch, path string) (files []RepositoryFile, err error) {
+	logger.WithField("func", "GetFilesForBranch").Infof("starting with branch %s and path %s", branch, path)
+	var items azureItems
+	if err := c.rest.do(http.MethodGet, "/items", c.query(url.Values{
+		"scopePath":                     {"/" + strings.Trim(path, "/")},
+		"recursionLevel":                {"Full"},
+		"versionDescriptor.version":     {branch},
+		"versionDescriptor.versionType": {"branch"},
+	}), nil, &items); isNotFound(err) {
+		return files, nil
+	} else if err != nil {
+		return files, err
+	}
+	for _, item := range items.Value {
+		if item.GitObjectType != "blob" {
+			continue
+		}
+		content, err := c.rest.send(http.MethodGet, fmt.Sprintf("/blobs/%s", item.ObjectID), c.query(url.Values{"$format": {"octetstream"}}), "", nil)
+		if err != nil {
+			return files, err
+		}
+		logger.WithField("func", "GetFilesForBranch").Infof("found file in path %s", item.Path)
+		files = append(files, RepositoryFile{
+			Content: string(content),
+			Path:    strings.TrimPrefix(item.Path, "/"),
+			SHA:     item.ObjectID,
+		})
+	}
+	return files, nil
+}
+
+// SyncFilesWithBranch pushes all differences between currentTargetFiles and newTargetFiles with a single commit
+func (c *azureClient) SyncFilesWithBranch(branch string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	head, err := c.GetBranchHead(branch)
+	if err != nil {
+		return 0, err
+	}
+	return c.SyncFilesWithBranchAt(branch, head, currentTargetFiles, newTargetFiles)
+}
+
+func (c *azureClient) GetBranchHead(branch string) (commit string, err error) {
+	ref, err := c.getBranchRef(branch)
+	if err != nil {
+		return "", err
+	} else if ref == nil {
+		return "", errors.New(fmt.Sprintf("branch %s not found", branch))
+	}
+	return ref.ObjectID, nil
+}
+
+// SyncFilesWithBranchAt pushes the commit with head as old object id of the ref update, azure rejects the push with
+// 409 (conflict) if the branch points to another commit
+func (c *azureClient) SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	logger.WithField("func", "SyncfilesWithBranch").Infof("starting for branch %s at %s and %d currentTargetFiles and %d newTargetFiles", branch, head, len(currentTargetFiles), len(newTargetFiles))
+	commit := azurePushCommit{Comment: "(build) sync files"}
+	for _, change := range diffRepositoryFiles(currentTargetFiles, newTargetFiles) {
+		azChange := azureChange{}
+		azChange.Item.Path = "/" + change.Path
+		switch {
+		case change.Content == nil:
+			azChange.ChangeType = "delete"
+		case change.Current == nil:
+			azChange.ChangeType = "add"
+		default:
+			azChange.ChangeType = "edit"
+		}
+		if change.Content != nil {
+			azChange.NewContent = &azureNewContent{
+				Content:     base64.StdEncoding.EncodeToString([]byte(*change.Content)),
+				ContentType: "base64encoded",
+			}
+		}
+		commit.Changes = append(commit.Changes, azChange)
+	}
+	if len(commit.Changes) == 0 {
+		return 0, nil
+	}
+	if err := c.rest.do(http.MethodPost, "/pushes", c.query(nil), azurePush{
+		RefUpdates: []azureRefUpdate{{Name: azureBranchRef(branch), OldObjectID: head}},
+		Commits:    []azurePushCommit{commit},
+	}, nil); isStatus(err, http.StatusConflict) {
+		return 0, ErrBranchMoved
+	} else if err != nil {
+		return 0, err
+	}
+	return len(commit.Changes), nil
+}
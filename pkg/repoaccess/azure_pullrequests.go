@@ -0,0 +1,144 @@
+package repoaccess
+
+import (
+	"fmt"
+	"keptn/git-promotion-service/pkg/model"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// azure devops rejects pull request descriptions longer than 4000 characters
+const azureMaxDescriptionLength = 4000
+
+type azurePullRequest struct {
+	PullRequestID int    `json:"pullRequestId,omitempty"`
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	SourceRefName string `json:"sourceRefName,omitempty"`
+	TargetRefName string `json:"targetRefName,omitempty"`
+	Status        string `json:"status,omitempty"`
+	// read only fields used for merging
+	LastMergeSourceCommit *azureCommitRef `json:"lastMergeSourceCommit,omitempty"`
+	LastMergeCommit       *azureCommitRef `json:"lastMergeCommit,omitempty"`
+	CreatedBy             *azureIdentity  `json:"createdBy,omitempty"`
+}
+
+type azureCommitRef struct {
+	CommitID string `json:"commitId"`
+}
+
+type azureIdentity struct {
+	ID string `json:"id"`
+}
+
+// azureMergeStrategies maps the merge methods to the merge strategies of azure devops
+var azureMergeStrategies = map[string]string{
+	model.MergeMethodMerge:  "noFastForward",
+	model.MergeMethodSquash: "squash",
+	model.MergeMethodRebase: "rebase",
+}
+
+type azurePullRequests struct {
+	Value []azurePullRequest `json:"value"`
+}
+
+func (c *azureClient) toPullRequest(pr azurePullRequest) *PullRequest {
+	return &PullRequest{
+		Number: pr.PullRequestID,
+		Title:  pr.Title,
+		URL:    fmt.Sprintf("%s/%s/_git/%s/pullrequest/%d", c.organizationURL, url.PathEscape(c.project), url.PathEscape(c.repository), pr.PullRequestID),
+	}
+}
+
+func (c *azureClient) GetOpenPullRequest(fromBranch, toBranch string) (pr *PullRequest, err error) {
+	var prs azurePullRequests
+	if err := c.rest.do(http.MethodGet, "/pullrequests", c.query(url.Values{
+		"searchCriteria.sourceRefName": {azureBranchRef(fromBranch)},
+		"searchCriteria.targetRefName": {azureBranchRef(toBranch)},
+		"searchCriteria.status":        {"active"},
+	}), nil, &prs); err != nil {
+		return nil, err
+	}
+	if len(prs.Value) == 0 {
+		return nil, nil
+	}
+	return c.toPullRequest(prs.Value[0]), nil
+}
+
+func (c *azureClient) EditPullRequest(pr *PullRequest, title, body string) error {
+	return c.rest.do(http.MethodPatch, fmt.Sprintf("/pullrequests/%d", pr.Number), c.query(nil), azurePullRequest{
+		Title:       title,
+		Description: truncate(body, azureMaxDescriptionLength),
+	}, nil)
+}
+
+func (c *azureClient) CreatePullRequest(fromBranch, toBranch, title, body string) (pr *PullRequest, err error) {
+	var created azurePullRequest
+	if err := c.rest.do(http.MethodPost, "/pullrequests", c.query(nil), azurePullRequest{
+		Title:         title,
+		Description:   truncate(body, azureMaxDescriptionLength),
+		SourceRefName: azureBranchRef(fromBranch),
+		TargetRefName: azureBranchRef(toBranch),
+	}, &created); err != nil {
+		return nil, err
+	}
+	return c.toPullRequest(created), nil
+}
+
+// truncate returns at most length bytes of s, multibyte characters are not cut
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	for length > 0 && !utf8.RuneStart(s[length]) {
+		length--
+	}
+	return s[:length]
+}
+
+func (c *azureClient) GetPullRequestState(number int) (state PullRequestState, err error) {
+	var pr azurePullRequest
+	if err := c.rest.do(http.MethodGet, fmt.Sprintf("/pullrequests/%d", number), c.query(nil), nil, &pr); err != nil {
+		return "", err
+	}
+	switch pr.Status {
+	case "completed":
+		return PullRequestMerged, nil
+	case "abandoned":
+		return PullRequestClosed, nil
+	default:
+		return PullRequestOpen, nil
+	}
+}
+
+// MergePullRequest completes the pull request, if policies (e.g. required builds) block the completion auto-complete
+// is enabled. Azure DevOps completes pull requests asynchronously, so the merge commit may not be known yet.
+func (c *azureClient) MergePullRequest(pr *PullRequest, method string) error {
+	var current azurePullRequest
+	if err := c.rest.do(http.MethodGet, fmt.Sprintf("/pullrequests/%d", pr.Number), c.query(nil), nil, &current); err != nil {
+		return err
+	}
+	completionOptions := map[string]interface{}{"mergeStrategy": azureMergeStrategies[method]}
+	var completed azurePullRequest
+	err := c.rest.do(http.MethodPatch, fmt.Sprintf("/pullrequests/%d", pr.Number), c.query(nil), map[string]interface{}{
+		"status":                "completed",
+		"lastMergeSourceCommit": current.LastMergeSourceCommit,
+		"completionOptions":     completionOptions,
+	}, &completed)
+	if (isStatus(err, http.StatusBadRequest) || isStatus(err, http.StatusConflict)) && current.CreatedBy != nil {
+		return c.rest.do(http.MethodPatch, fmt.Sprintf("/pullrequests/%d", pr.Number), c.query(nil), map[string]interface{}{
+			"autoCompleteSetBy": current.CreatedBy,
+			"completionOptions": completionOptions,
+		}, nil)
+	} else if err != nil {
+		return err
+	}
+	if completed.Status == "completed" {
+		pr.Merged = true
+		if completed.LastMergeCommit != nil {
+			pr.MergeCommitSHA = completed.LastMergeCommit.CommitID
+		}
+	}
+	return nil
+}
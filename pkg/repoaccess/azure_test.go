@@ -0,0 +1,328 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fakeAzure is a minimal in-memory stand-in for the azure devops git api of repository org/project/repo
+type fakeAzure struct {
+	fakeRepository
+	pullRequests []azurePullRequest
+	// blockCompletion simulates branch policies that are not fulfilled
+	blockCompletion bool
+	mergeStrategy   string
+	autoComplete    bool
+}
+
+func (f *fakeAzure) objectId(branch string) string {
+	return fmt.Sprintf("%040d", f.heads[branch]+1)
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(":token")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Query().Get("api-version") != azureApiVersion {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	const prefix = "/org/project/_apis/git/repositories/repo"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		respond(w, true, map[string]string{"defaultBranch": "refs/heads/main"})
+	case r.Method == http.MethodGet && path == "/refs":
+		refs := azureRefs{}
+		for name := range f.branches {
+			if strings.HasPrefix("heads/"+name, query.Get("filter")) {
+				refs.Value = append(refs.Value, azureRef{Name: "refs/heads/" + name, ObjectID: f.objectId(name)})
+			}
+		}
+		respond(w, true, refs)
+	case r.Method == http.MethodPost && path == "/refs":
+		var updates []azureRefUpdate
+		decode(f.t, r, &updates)
+		for _, u := range updates {
+			name := strings.TrimPrefix(u.Name, "refs/heads/")
+			if u.NewObjectID == azureEmptyObjectId {
+				f.deleteBranch(name)
+			} else {
+				f.createBranch("main", name)
+			}
+		}
+		respond(w, true, azureRefUpdateResults{})
+	case r.Method == http.MethodGet && path == "/diffs/commits":
+		diffs := azureCommitDiffs{}
+		if f.hasNewCommits(query.Get("baseVersion"), query.Get("targetVersion")) {
+			diffs.AheadCount = 1
+		}
+		respond(w, true, diffs)
+	case r.Method == http.MethodGet && path == "/items":
+		branch := query.Get("versionDescriptor.version")
+		scopePath := strings.TrimPrefix(query.Get("scopePath"), "/")
+		items := azureItems{}
+		if _, ok := f.branch(branch)[scopePath]; ok {
+			items.Value = append(items.Value, azureItem{ObjectID: branch + ":" + scopePath, GitObjectType: "blob", Path: "/" + scopePath})
+		} else {
+			for _, p := range f.filesInDirectory(branch, scopePath) {
+				items.Value = append(items.Value, azureItem{ObjectID: branch + ":" + p, GitObjectType: "blob", Path: "/" + p})
+			}
+		}
+		respond(w, len(items.Value) > 0, items)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/blobs/"):
+		id := strings.SplitN(strings.TrimPrefix(path, "/blobs/"), ":", 2)
+		_, _ = w.Write([]byte(f.branch(id[0])[id[1]]))
+	case r.Method == http.MethodPost && path == "/pushes":
+		var push azurePush
+		decode(f.t, r, &push)
+		branch := strings.TrimPrefix(push.RefUpdates[0].Name, "refs/heads/")
+		if push.RefUpdates[0].OldObjectID != f.objectId(branch) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		for _, c := range push.Commits[0].Changes {
+			if c.ChangeType == "delete" {
+				delete(f.branch(branch), strings.TrimPrefix(c.Item.Path, "/"))
+			} else {
+				content, _ := base64.StdEncoding.DecodeString(c.NewContent.Content)
+				f.branch(branch)[strings.TrimPrefix(c.Item.Path, "/")] = string(content)
+			}
+		}
+		f.commit(branch)
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodGet && path == "/pullrequests":
+		prs := azurePullRequests{}
+		for _, pr := range f.pullRequests {
+			if pr.SourceRefName == query.Get("searchCriteria.sourceRefName") && pr.TargetRefName == query.Get("searchCriteria.targetRefName") {
+				prs.Value = append(prs.Value, pr)
+			}
+		}
+		respond(w, true, prs)
+	case r.Method == http.MethodPost && path == "/pullrequests":
+		var pr azurePullRequest
+		decode(f.t, r, &pr)
+		pr.PullRequestID = len(f.pullRequests) + 1
+		pr.Status, pr.CreatedBy = "active", &azureIdentity{ID: "user-id"}
+		f.pullRequests = append(f.pullRequests, pr)
+		respond(w, true, pr)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/pullrequests/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/pullrequests/"))
+		respond(w, true, f.pullRequests[id-1])
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/pullrequests/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/pullrequests/"))
+		var update map[string]interface{}
+		decode(f.t, r, &update)
+		pr := &f.pullRequests[id-1]
+		if options, ok := update["completionOptions"].(map[string]interface{}); ok {
+			f.mergeStrategy, _ = options["mergeStrategy"].(string)
+		}
+		switch {
+		case update["status"] == "completed" && f.blockCompletion:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		case update["status"] == "completed":
+			pr.Status, pr.LastMergeCommit = "completed", &azureCommitRef{CommitID: "merge-sha"}
+		case update["autoCompleteSetBy"] != nil:
+			f.autoComplete = true
+		default:
+			pr.Title, pr.Description = update["title"].(string), update["description"].(string)
+		}
+		respond(w, true, pr)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func Test_getAzureOrganizationProjectRepository(t *testing.T) {
+	tests := []struct {
+		name                string
+		raw                 string
+		wantOrganizationURL string
+		wantProject         string
+		wantRepository      string
+		wantErr             bool
+	}{
+		{
+			name:                "dev.azure.com",
+			raw:                 "https://dev.azure.com/org/my%20project/_git/repo",
+			wantOrganizationURL: "https://dev.azure.com/org",
+			wantProject:         "my project",
+			wantRepository:      "repo",
+		},
+		{
+			name:                "visualstudio.com",
+			raw:                 "https://org.visualstudio.com/project/_git/repo",
+			wantOrganizationURL: "https://org.visualstudio.com",
+			wantProject:         "project",
+			wantRepository:      "repo",
+		},
+		{
+			name:                "azure devops server collection",
+			raw:                 "https://tfs.example.com/tfs/DefaultCollection/project/_git/repo",
+			wantOrganizationURL: "https://tfs.example.com/tfs/DefaultCollection",
+			wantProject:         "project",
+			wantRepository:      "repo",
+		},
+		{
+			name:    "github url",
+			raw:     "https://github.com/owner/repo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOrganizationURL, gotProject, gotRepository, err := getAzureOrganizationProjectRepository(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getAzureOrganizationProjectRepository() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotOrganizationURL != tt.wantOrganizationURL || gotProject != tt.wantProject || gotRepository != tt.wantRepository {
+				t.Errorf("getAzureOrganizationProjectRepository() = %v, %v, %v, want %v, %v, %v", gotOrganizationURL, gotProject, gotRepository, tt.wantOrganizationURL, tt.wantProject, tt.wantRepository)
+			}
+		})
+	}
+}
+
+func Test_truncate(t *testing.T) {
+	tests := []struct {
+		s      string
+		length int
+		want   string
+	}{
+		{s: "short", length: 10, want: "short"},
+		{s: "abcdef", length: 3, want: "abc"},
+		{s: "abä", length: 3, want: "ab"},
+		{s: "abäc", length: 4, want: "abä"},
+		{s: "a🚀", length: 4, want: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := truncate(tt.s, tt.length); got != tt.want || !utf8.ValidString(got) {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.length, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAzureClient(t *testing.T) {
+	fake := &fakeAzure{fakeRepository: newFakeRepository(t, map[string]string{
+		"dev/values.yaml":     "tag: 1.0.1",
+		"staging/values.yaml": "tag: 1.0.0",
+		"staging/old.yaml":    "old: true",
+	})}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := newAzureClient(nil, "token", server.URL+"/org/project/_git/repo", nil)
+	if err != nil {
+		t.Fatalf("newAzureClient() error = %v", err)
+	}
+	if branch, err := client.DefaultBranch(); err != nil || branch != "main" {
+		t.Fatalf("DefaultBranch() = %s, %v, want main", branch, err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Fatalf("BranchExists() = %v, %v, want false", exists, err)
+	}
+	if err := client.CreateBranch("main", "promote/dev_staging"); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	current, err := client.GetFilesForBranch("promote/dev_staging", "staging")
+	if err != nil {
+		t.Fatalf("GetFilesForBranch() error = %v", err)
+	}
+	if want := []string{"staging/old.yaml", "staging/values.yaml"}; !reflect.DeepEqual(filePaths(current), want) {
+		t.Errorf("GetFilesForBranch() = %v, want %v", filePaths(current), want)
+	}
+	if single, err := client.GetFilesForBranch("main", "/dev/values.yaml"); err != nil || len(single) != 1 || single[0].Content != "tag: 1.0.1" {
+		t.Errorf("GetFilesForBranch() for single file = %v, %v", single, err)
+	}
+	if missing, err := client.GetFilesForBranch("main", "prod"); err != nil || len(missing) != 0 {
+		t.Errorf("GetFilesForBranch() for missing path = %v, %v", missing, err)
+	}
+
+	changes, err := client.SyncFilesWithBranch("promote/dev_staging", current, []RepositoryFile{
+		{Path: "staging/values.yaml", Content: "tag: 1.0.1"},
+		{Path: "staging/new.yaml", Content: "new: true"},
+	})
+	if err != nil {
+		t.Fatalf("SyncFilesWithBranch() error = %v", err)
+	}
+	wantFiles := map[string]string{
+		"dev/values.yaml":     "tag: 1.0.1",
+		"staging/values.yaml": "tag: 1.0.1",
+		"staging/new.yaml":    "new: true",
+	}
+	if changes != 3 || fake.commits != 1 || !reflect.DeepEqual(fake.branch("promote/dev_staging"), wantFiles) {
+		t.Errorf("SyncFilesWithBranch() changes = %d, commits = %d, branch content = %v, want 3 changes in 1 commit and %v", changes, fake.commits, fake.branch("promote/dev_staging"), wantFiles)
+	}
+	if newCommits, err := client.CheckForNewCommits("main", "promote/dev_staging"); err != nil || !newCommits {
+		t.Errorf("CheckForNewCommits() = %v, %v, want true", newCommits, err)
+	}
+
+	head, err := client.GetBranchHead("promote/dev_staging")
+	if err != nil {
+		t.Fatalf("GetBranchHead() error = %v", err)
+	}
+	direct := []RepositoryFile{{Path: "staging/direct.yaml", Content: "direct: true"}}
+	fake.commit("promote/dev_staging")
+	if _, err := client.SyncFilesWithBranchAt("promote/dev_staging", head, nil, direct); !errors.Is(err, ErrBranchMoved) {
+		t.Errorf("SyncFilesWithBranchAt() with moved branch error = %v, want %v", err, ErrBranchMoved)
+	}
+	if head, err = client.GetBranchHead("promote/dev_staging"); err != nil {
+		t.Fatalf("GetBranchHead() error = %v", err)
+	}
+	if changes, err := client.SyncFilesWithBranchAt("promote/dev_staging", head, nil, direct); err != nil || changes != 1 || fake.branch("promote/dev_staging")["staging/direct.yaml"] != "direct: true" {
+		t.Errorf("SyncFilesWithBranchAt() = %d, %v, branch content = %v", changes, err, fake.branch("promote/dev_staging"))
+	}
+
+	pr, err := client.CreatePullRequest("promote/dev_staging", "main", "keptn: title", "body")
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if want := server.URL + "/org/project/_git/repo/pullrequest/1"; pr.Number != 1 || pr.URL != want {
+		t.Errorf("CreatePullRequest() = %+v, want url %s", pr, want)
+	}
+	open, err := client.GetOpenPullRequest("promote/dev_staging", "main")
+	if err != nil || open == nil || open.Number != pr.Number {
+		t.Fatalf("GetOpenPullRequest() = %+v, %v", open, err)
+	}
+	if err := client.EditPullRequest(open, "keptn: new title", "new body"); err != nil {
+		t.Fatalf("EditPullRequest() error = %v", err)
+	}
+	if fake.pullRequests[0].Title != "keptn: new title" || fake.pullRequests[0].Description != "new body" {
+		t.Errorf("EditPullRequest() did not update pull request: %+v", fake.pullRequests[0])
+	}
+	for _, state := range []string{"active", "completed", "abandoned"} {
+		fake.pullRequests[0].Status = state
+		want := map[string]PullRequestState{"active": PullRequestOpen, "completed": PullRequestMerged, "abandoned": PullRequestClosed}[state]
+		if got, err := client.GetPullRequestState(pr.Number); err != nil || got != want {
+			t.Errorf("GetPullRequestState() with status %s = %v, %v, want %v", state, got, err, want)
+		}
+	}
+	fake.pullRequests[0].Status, fake.blockCompletion = "active", true
+	if err := client.MergePullRequest(pr, "rebase"); err != nil || pr.Merged || !fake.autoComplete || fake.mergeStrategy != "rebase" {
+		t.Errorf("MergePullRequest() with policies = %v, merged %v, auto-complete %v, strategy %s", err, pr.Merged, fake.autoComplete, fake.mergeStrategy)
+	}
+	fake.blockCompletion = false
+	if err := client.MergePullRequest(pr, "merge"); err != nil || !pr.Merged || pr.MergeCommitSHA != "merge-sha" || fake.mergeStrategy != "noFastForward" {
+		t.Errorf("MergePullRequest() = %v, merged %v with %s, strategy %s", err, pr.Merged, pr.MergeCommitSHA, fake.mergeStrategy)
+	}
+	if err := client.DeleteBranch("promote/dev_staging"); err != nil {
+		t.Fatalf("DeleteBranch() error = %v", err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Errorf("BranchExists() after delete = %v, %v, want false", exists, err)
+	}
+}
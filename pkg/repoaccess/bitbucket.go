@@ -0,0 +1,97 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type bitbucketClient struct {
+	project    string
+	repository string
+	rest       restClient
+}
+
+func newBitbucketClient(httpClient *http.Client, accessToken string, repositoryUrl string, customApiURL *string) (client *bitbucketClient, err error) {
+	baseURL, project, repository, err := getBitbucketBaseUrlProjectRepository(repositoryUrl)
+	if err != nil {
+		return nil, err
+	}
+	if customApiURL != nil && *customApiURL != "" {
+		baseURL = strings.TrimSuffix(*customApiURL, "/")
+	}
+	return &bitbucketClient{
+		project:    project,
+		repository: repository,
+		rest: restClient{
+			baseURL:    baseURL,
+			httpClient: httpClient,
+			headers:    map[string]string{"Authorization": "Bearer " + accessToken},
+		},
+	}, nil
+}
+
+// getBitbucketBaseUrlProjectRepository supports the browse url (https://<host>[/<context>]/projects/<key>/repos/<slug>)
+// and the clone url (https://<host>[/<context>]/scm/<key>/<slug>.git) of a repository
+func getBitbucketBaseUrlProjectRepository(raw string) (baseURL, project, repository string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", "", err
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+2 < len(segments); i++ {
+		if segments[i] == "projects" && i+3 < len(segments) && segments[i+2] == "repos" {
+			project, repository = segments[i+1], segments[i+3]
+		} else if segments[i] == "scm" {
+			project, repository = segments[i+1], strings.TrimSuffix(segments[i+2], ".git")
+		} else {
+			continue
+		}
+		contextPath := strings.Join(segments[:i], "/")
+		if contextPath != "" {
+			contextPath = "/" + contextPath
+		}
+		return fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, contextPath), strings.ToUpper(project), repository, nil
+	}
+	return "", "", "", errors.New(fmt.Sprintf("repository url %s is neither a bitbucket browse nor clone url", raw))
+}
+
+func (c *bitbucketClient) repositoryPath(api string, format string, a ...interface{}) string {
+	return fmt.Sprintf("/rest/%s/1.0/projects/%s/repos/%s", api, url.PathEscape(c.project), url.PathEscape(c.repository)) + fmt.Sprintf(format, a...)
+}
+
+func (c *bitbucketClient) repositoryRef() bitbucketRepository {
+	return bitbucketRepository{
+		Slug:    c.repository,
+		Project: bitbucketProject{Key: c.project},
+	}
+}
+
+// escapeFilePath escapes every segment of path but keeps the slashes
+func escapeFilePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func bitbucketBranchRef(branch string) string {
+	return "refs/heads/" + branch
+}
+
+type bitbucketProject struct {
+	Key string `json:"key"`
+}
+
+type bitbucketRepository struct {
+	Slug    string           `json:"slug"`
+	Project bitbucketProject `json:"project"`
+}
+
+type bitbucketPage struct {
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
@@ -0,0 +1,65 @@
+package repoaccess
+
+import (
+	"net/http"
+	"net/url"
+)
+
+type bitbucketBranch struct {
+	ID           string `json:"id"`
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit"`
+}
+
+type bitbucketBranches struct {
+	bitbucketPage
+	Values []bitbucketBranch `json:"values"`
+}
+
+func (c *bitbucketClient) BranchExists(branchName string) (exists bool, err error) {
+	branch, err := c.getBranch(branchName)
+	return branch != nil, err
+}
+
+func (c *bitbucketClient) getBranch(branchName string) (branch *bitbucketBranch, err error) {
+	var branches bitbucketBranches
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/branches"), url.Values{
+		"filterText":   {branchName},
+		"boostMatches": {"true"},
+	}, nil, &branches); err != nil {
+		return nil, err
+	}
+	for _, b := range branches.Values {
+		if b.DisplayID == branchName {
+			return &b, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *bitbucketClient) CreateBranch(sourceBranch, targetBranch string) (err error) {
+	return c.rest.do(http.MethodPost, c.repositoryPath("api", "/branches"), nil, map[string]string{
+		"name":       targetBranch,
+		"startPoint": bitbucketBranchRef(sourceBranch),
+	}, nil)
+}
+
+func (c *bitbucketClient) DeleteBranch(branch string) (err error) {
+	return c.rest.do(http.MethodDelete, c.repositoryPath("branch-utils", "/branches"), nil, map[string]interface{}{
+		"name":   bitbucketBranchRef(branch),
+		"dryRun": false,
+	}, nil)
+}
+
+func (c *bitbucketClient) DefaultBranch() (branch string, err error) {
+	var b bitbucketBranch
+	// default-branch replaced branches/default in Bitbucket 8
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/default-branch"), nil, nil, &b); isNotFound(err) {
+		if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/branches/default"), nil, nil, &b); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	}
+	return b.DisplayID, nil
+}
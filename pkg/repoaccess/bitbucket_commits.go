@@ -0,0 +1,189 @@
+package repoaccess
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type bitbucketCommits struct {
+	bitbucketPage
+	Values []struct {
+		ID string `json:"id"`
+	} `json:"values"`
+}
+
+type bitbucketFiles struct {
+	bitbucketPage
+	Values []string `json:"values"`
+}
+
+type bitbucketBrowse struct {
+	Type string `json:"type"`
+}
+
+type bitbucketCommit struct {
+	ID string `json:"id"`
+}
+
+func (c *bitbucketClient) CheckForNewCommits(toBranch, fromBranch string) (newCommits bool, err error) {
+	var commits bitbucketCommits
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/compare/commits"), url.Values{
+		"from":  {bitbucketBranchRef(fromBranch)},
+		"to":    {bitbucketBranchRef(toBranch)},
+		"limit": {"1"},
+	}, nil, &commits); err != nil {
+		return false, err
+	}
+	logger.WithField("func", "CheckForNewCommits").Infof("found commits: %t in bitbucket repo %s/%s from branch %s to %s", len(commits.Values) > 0, c.project, c.repository, fromBranch, toBranch)
+	return len(commits.Values) > 0, nil
+}
+
+func (c *bitbucketClient) GetFilesForBranch(branch, path string) (files []RepositoryFile, err error) {
+	logger.WithField("func", "GetFilesForBranch").Infof("starting with branch %s and path %s", branch, path)
+	path = strings.Trim(path, "/")
+	var browse bitbucketBrowse
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/browse/%s", escapeFilePath(path)), url.Values{
+		"at":   {bitbucketBranchRef(branch)},
+		"type": {"true"},
+	}, nil, &browse); isNotFound(err) {
+		return files, nil
+	} else if err != nil {
+		return files, err
+	}
+	if browse.Type == "FILE" {
+		if file, err := c.getFile(branch, path); err != nil {
+			return files, err
+		} else {
+			return append(files, file), nil
+		}
+	}
+	start := 0
+	for {
+		var page bitbucketFiles
+		if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/files/%s", escapeFilePath(path)), url.Values{
+			"at":    {bitbucketBranchRef(branch)},
+			"start": {strconv.Itoa(start)},
+			"limit": {"1000"},
+		}, nil, &page); err != nil {
+			return files, err
+		}
+		for _, relativePath := range page.Values {
+			if file, err := c.getFile(branch, path+"/"+relativePath); err != nil {
+				return files, err
+			} else {
+				files = append(files, file)
+			}
+		}
+		if page.IsLastPage {
+			return files, nil
+		}
+		start = page.NextPageStart
+	}
+}
+
+func (c *bitbucketClient) getFile(branch, path string) (file RepositoryFile, err error) {
+	content, err := c.rest.send(http.MethodGet, fmt.Sprintf("/projects/%s/repos/%s/raw/%s", url.PathEscape(c.project), url.PathEscape(c.repository), escapeFilePath(path)),
+		url.Values{"at": {bitbucketBranchRef(branch)}}, "", nil)
+	if err != nil {
+		return file, err
+	}
+	logger.WithField("func", "GetFilesForBranch").Infof("found file in path %s", path)
+	return RepositoryFile{
+		Content: string(content),
+		Path:    path,
+	}, nil
+}
+
+// SyncFilesWithBranch commits every changed file separately because the bitbucket rest api only supports single file
+// commits. Deleting files is not supported by the api, so nothing is committed if files only available in
+// currentTargetFiles would have to be deleted.
+func (c *bitbucketClient) SyncFilesWithBranch(branch string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	logger.WithField("func", "SyncfilesWithBranch").Infof("starting for branch %s and %d currentTargetFiles and %d newTargetFiles", branch, len(currentTargetFiles), len(newTargetFiles))
+	fileChanges := diffRepositoryFiles(currentTargetFiles, newTargetFiles)
+	if len(fileChanges) == 0 {
+		return 0, nil
+	}
+	var deleted []string
+	for _, change := range fileChanges {
+		if change.Content == nil {
+			deleted = append(deleted, change.Path)
+		}
+	}
+	if len(deleted) > 0 {
+		return 0, errors.New(fmt.Sprintf("deleting files is not supported by provider bitbucket-server, delete %s in branch %s manually", strings.Join(deleted, ", "), branch))
+	}
+	head, err := c.getBranch(branch)
+	if err != nil {
+		return 0, err
+	} else if head == nil {
+		return 0, errors.New(fmt.Sprintf("branch %s not found", branch))
+	}
+	sourceCommitId := head.LatestCommit
+	for _, change := range fileChanges {
+		logger.WithField("func", "SyncfilesWithBranch").Infof("committing file %s in branch %s", change.Path, branch)
+		if sourceCommitId, err = c.commitFile(branch, change, sourceCommitId); err != nil {
+			return changes, err
+		}
+		changes++
+	}
+	return changes, nil
+}
+
+func (c *bitbucketClient) commitFile(branch string, change fileChange, sourceCommitId string) (commitId string, err error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fields := map[string]string{
+		"branch":  branch,
+		"message": "(build) update file " + change.Path,
+	}
+	if change.Current != nil {
+		fields["sourceCommitId"] = sourceCommitId
+	}
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return "", err
+		}
+	}
+	// sent as file part, so binary content is not interpreted as text
+	if part, err := writer.CreateFormFile("content", path.Base(change.Path)); err != nil {
+		return "", err
+	} else if _, err := part.Write([]byte(*change.Content)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	data, err := c.rest.send(http.MethodPut, c.repositoryPath("api", "/browse/%s", escapeFilePath(change.Path)), nil, writer.FormDataContentType(), body)
+	if err != nil {
+		return "", err
+	}
+	var commit bitbucketCommit
+	if err := json.Unmarshal(data, &commit); err != nil {
+		return "", err
+	}
+	return commit.ID, nil
+}
+
+func (c *bitbucketClient) GetBranchHead(branch string) (commit string, err error) {
+	head, err := c.getBranch(branch)
+	if err != nil {
+		return "", err
+	} else if head == nil {
+		return "", errors.New(fmt.Sprintf("branch %s not found", branch))
+	}
+	return head.LatestCommit, nil
+}
+
+// SyncFilesWithBranchAt is not supported, the single file commits of bitbucket server can't be applied atomically
+func (c *bitbucketClient) SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	return 0, errors.New("committing changes at a given head is not supported by provider bitbucket-server")
+}
@@ -0,0 +1,139 @@
+package repoaccess
+
+import (
+	"errors"
+	"keptn/git-promotion-service/pkg/model"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type bitbucketRef struct {
+	ID         string              `json:"id"`
+	Repository bitbucketRepository `json:"repository"`
+}
+
+type bitbucketPullRequest struct {
+	ID          int          `json:"id"`
+	Version     int          `json:"version"`
+	State       string       `json:"state,omitempty"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	FromRef     bitbucketRef `json:"fromRef"`
+	ToRef       bitbucketRef `json:"toRef"`
+	Properties  struct {
+		MergeCommit struct {
+			ID string `json:"id"`
+		} `json:"mergeCommit"`
+	} `json:"properties"`
+	Links struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// bitbucketMergeStrategies maps the merge methods to the strategy ids of bitbucket
+var bitbucketMergeStrategies = map[string]string{
+	model.MergeMethodMerge:  "no-ff",
+	model.MergeMethodSquash: "squash",
+	model.MergeMethodRebase: "rebase-ff-only",
+}
+
+type bitbucketPullRequests struct {
+	bitbucketPage
+	Values []bitbucketPullRequest `json:"values"`
+}
+
+func (pr bitbucketPullRequest) toPullRequest() *PullRequest {
+	result := &PullRequest{
+		Number: pr.ID,
+		Title:  pr.Title,
+	}
+	if len(pr.Links.Self) > 0 {
+		result.URL = pr.Links.Self[0].Href
+	}
+	return result
+}
+
+func (c *bitbucketClient) GetOpenPullRequest(fromBranch, toBranch string) (pr *PullRequest, err error) {
+	var prs bitbucketPullRequests
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/pull-requests"), url.Values{
+		"at":        {bitbucketBranchRef(fromBranch)},
+		"direction": {"OUTGOING"},
+		"state":     {"OPEN"},
+	}, nil, &prs); err != nil {
+		return nil, err
+	}
+	for _, p := range prs.Values {
+		if p.ToRef.ID == bitbucketBranchRef(toBranch) {
+			return p.toPullRequest(), nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *bitbucketClient) EditPullRequest(pr *PullRequest, title, body string) error {
+	var current bitbucketPullRequest
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/pull-requests/%d", pr.Number), nil, nil, &current); err != nil {
+		return err
+	}
+	return c.rest.do(http.MethodPut, c.repositoryPath("api", "/pull-requests/%d", pr.Number), nil, map[string]interface{}{
+		"version":     current.Version,
+		"title":       title,
+		"description": body,
+	}, nil)
+}
+
+func (c *bitbucketClient) CreatePullRequest(fromBranch, toBranch, title, body string) (pr *PullRequest, err error) {
+	var created bitbucketPullRequest
+	if err := c.rest.do(http.MethodPost, c.repositoryPath("api", "/pull-requests"), nil, map[string]interface{}{
+		"title":       title,
+		"description": body,
+		"fromRef":     bitbucketRef{ID: bitbucketBranchRef(fromBranch), Repository: c.repositoryRef()},
+		"toRef":       bitbucketRef{ID: bitbucketBranchRef(toBranch), Repository: c.repositoryRef()},
+	}, &created); err != nil {
+		return nil, err
+	}
+	if created.ID == 0 {
+		return nil, errors.New("no pull request returned by bitbucket")
+	}
+	return created.toPullRequest(), nil
+}
+
+func (c *bitbucketClient) GetPullRequestState(number int) (state PullRequestState, err error) {
+	var pr bitbucketPullRequest
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/pull-requests/%d", number), nil, nil, &pr); err != nil {
+		return "", err
+	}
+	switch pr.State {
+	case "MERGED":
+		return PullRequestMerged, nil
+	case "DECLINED":
+		return PullRequestClosed, nil
+	default:
+		return PullRequestOpen, nil
+	}
+}
+
+// MergePullRequest merges with the strategy of method (must be enabled in the repository settings). If merge checks
+// veto the merge, the auto-merge of Bitbucket Data Center 8.15+ is enabled.
+func (c *bitbucketClient) MergePullRequest(pr *PullRequest, method string) error {
+	var current bitbucketPullRequest
+	if err := c.rest.do(http.MethodGet, c.repositoryPath("api", "/pull-requests/%d", pr.Number), nil, nil, &current); err != nil {
+		return err
+	}
+	var merged bitbucketPullRequest
+	err := c.rest.do(http.MethodPost, c.repositoryPath("api", "/pull-requests/%d/merge", pr.Number), url.Values{
+		"version": {strconv.Itoa(current.Version)},
+	}, map[string]string{"strategyId": bitbucketMergeStrategies[method]}, &merged)
+	if isStatus(err, http.StatusConflict) {
+		return c.rest.do(http.MethodPost, c.repositoryPath("api", "/pull-requests/%d/auto-merge", pr.Number), nil, map[string]string{
+			"strategyId": bitbucketMergeStrategies[method],
+		}, nil)
+	} else if err != nil {
+		return err
+	}
+	pr.Merged, pr.MergeCommitSHA = true, merged.Properties.MergeCommit.ID
+	return nil
+}
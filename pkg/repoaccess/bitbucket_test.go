@@ -0,0 +1,306 @@
+package repoaccess
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeBitbucket is a minimal in-memory stand-in for the bitbucket server rest api of repository PRJ/repo
+type fakeBitbucket struct {
+	fakeRepository
+	pullRequests []bitbucketPullRequest
+	// vetoMerge simulates merge checks that are not fulfilled
+	vetoMerge     bool
+	mergeStrategy string
+	autoMerge     bool
+}
+
+func (f *fakeBitbucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := r.URL.Path
+	query := r.URL.Query()
+	const apiPrefix = "/rest/api/1.0/projects/PRJ/repos/repo"
+	switch {
+	case r.Method == http.MethodGet && path == apiPrefix+"/default-branch":
+		// not available before Bitbucket 8
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodGet && path == apiPrefix+"/branches/default":
+		respond(w, true, bitbucketBranch{ID: "refs/heads/main", DisplayID: "main"})
+	case r.Method == http.MethodGet && path == apiPrefix+"/branches":
+		branches := bitbucketBranches{}
+		for name := range f.branches {
+			if strings.Contains(name, query.Get("filterText")) {
+				branches.Values = append(branches.Values, bitbucketBranch{ID: "refs/heads/" + name, DisplayID: name, LatestCommit: strconv.Itoa(f.heads[name])})
+			}
+		}
+		respond(w, true, branches)
+	case r.Method == http.MethodPost && path == apiPrefix+"/branches":
+		var body map[string]string
+		decode(f.t, r, &body)
+		f.createBranch(strings.TrimPrefix(body["startPoint"], "refs/heads/"), body["name"])
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodDelete && path == "/rest/branch-utils/1.0/projects/PRJ/repos/repo/branches":
+		var body map[string]interface{}
+		decode(f.t, r, &body)
+		f.deleteBranch(strings.TrimPrefix(body["name"].(string), "refs/heads/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && path == apiPrefix+"/compare/commits":
+		commits := bitbucketCommits{}
+		if f.hasNewCommits(strings.TrimPrefix(query.Get("to"), "refs/heads/"), strings.TrimPrefix(query.Get("from"), "refs/heads/")) {
+			commits.Values = append(commits.Values, struct {
+				ID string `json:"id"`
+			}{ID: "abc"})
+		}
+		respond(w, true, commits)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, apiPrefix+"/browse/"):
+		filePath := strings.TrimPrefix(path, apiPrefix+"/browse/")
+		branch := f.branch(strings.TrimPrefix(query.Get("at"), "refs/heads/"))
+		if _, ok := branch[filePath]; ok {
+			respond(w, true, bitbucketBrowse{Type: "FILE"})
+		} else {
+			respond(w, len(f.filesInDirectory(strings.TrimPrefix(query.Get("at"), "refs/heads/"), filePath)) > 0, bitbucketBrowse{Type: "DIRECTORY"})
+		}
+	case r.Method == http.MethodGet && strings.HasPrefix(path, apiPrefix+"/files/"):
+		directory := strings.TrimPrefix(path, apiPrefix+"/files/")
+		files := bitbucketFiles{bitbucketPage: bitbucketPage{IsLastPage: true}}
+		for _, p := range f.filesInDirectory(strings.TrimPrefix(query.Get("at"), "refs/heads/"), directory) {
+			files.Values = append(files.Values, strings.TrimPrefix(p, directory+"/"))
+		}
+		respond(w, true, files)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/projects/PRJ/repos/repo/raw/"):
+		content, ok := f.branch(strings.TrimPrefix(query.Get("at"), "refs/heads/"))[strings.TrimPrefix(path, "/projects/PRJ/repos/repo/raw/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	case r.Method == http.MethodPut && strings.HasPrefix(path, apiPrefix+"/browse/"):
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			f.t.Errorf("could not parse multipart form: %s", err)
+		}
+		branch := r.FormValue("branch")
+		filePath := strings.TrimPrefix(path, apiPrefix+"/browse/")
+		if _, exists := f.branch(branch)[filePath]; exists && r.FormValue("sourceCommitId") != strconv.Itoa(f.heads[branch]) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		content, _, err := r.FormFile("content")
+		if err != nil {
+			f.t.Errorf("content is not sent as file: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(content)
+		f.branch(branch)[filePath] = string(data)
+		f.commit(branch)
+		respond(w, true, bitbucketCommit{ID: strconv.Itoa(f.heads[branch])})
+	case r.Method == http.MethodGet && path == apiPrefix+"/pull-requests":
+		prs := bitbucketPullRequests{}
+		for _, pr := range f.pullRequests {
+			if pr.FromRef.ID == query.Get("at") {
+				prs.Values = append(prs.Values, pr)
+			}
+		}
+		respond(w, true, prs)
+	case r.Method == http.MethodPost && path == apiPrefix+"/pull-requests":
+		var pr bitbucketPullRequest
+		decode(f.t, r, &pr)
+		pr.ID = len(f.pullRequests) + 1
+		pr.Links.Self = append(pr.Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: fmt.Sprintf("https://bitbucket.example.com/projects/PRJ/repos/repo/pull-requests/%d", pr.ID)})
+		f.pullRequests = append(f.pullRequests, pr)
+		respond(w, true, pr)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/auto-merge"):
+		f.autoMerge = true
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/merge"):
+		id, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, apiPrefix+"/pull-requests/"), "/merge"))
+		pr := &f.pullRequests[id-1]
+		var body map[string]string
+		decode(f.t, r, &body)
+		if f.vetoMerge || query.Get("version") != strconv.Itoa(pr.Version) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.mergeStrategy = body["strategyId"]
+		pr.State = "MERGED"
+		pr.Properties.MergeCommit.ID = "merge-sha"
+		respond(w, true, pr)
+	case strings.HasPrefix(path, apiPrefix+"/pull-requests/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, apiPrefix+"/pull-requests/"))
+		pr := &f.pullRequests[id-1]
+		if r.Method == http.MethodPut {
+			var update bitbucketPullRequest
+			decode(f.t, r, &update)
+			if update.Version != pr.Version {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			pr.Title, pr.Description, pr.Version = update.Title, update.Description, pr.Version+1
+		}
+		respond(w, true, pr)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func Test_getBitbucketBaseUrlProjectRepository(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		wantBaseURL    string
+		wantProject    string
+		wantRepository string
+		wantErr        bool
+	}{
+		{
+			name:           "browse url",
+			raw:            "https://bitbucket.example.com/projects/PRJ/repos/repo/browse",
+			wantBaseURL:    "https://bitbucket.example.com",
+			wantProject:    "PRJ",
+			wantRepository: "repo",
+		},
+		{
+			name:           "clone url with context path",
+			raw:            "https://example.com/bitbucket/scm/prj/repo.git",
+			wantBaseURL:    "https://example.com/bitbucket",
+			wantProject:    "PRJ",
+			wantRepository: "repo",
+		},
+		{
+			name:    "invalid url",
+			raw:     "https://bitbucket.example.com/prj/repo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBaseURL, gotProject, gotRepository, err := getBitbucketBaseUrlProjectRepository(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getBitbucketBaseUrlProjectRepository() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotBaseURL != tt.wantBaseURL || gotProject != tt.wantProject || gotRepository != tt.wantRepository {
+				t.Errorf("getBitbucketBaseUrlProjectRepository() = %v, %v, %v, want %v, %v, %v", gotBaseURL, gotProject, gotRepository, tt.wantBaseURL, tt.wantProject, tt.wantRepository)
+			}
+		})
+	}
+}
+
+func TestBitbucketClient(t *testing.T) {
+	fake := &fakeBitbucket{fakeRepository: newFakeRepository(t, map[string]string{
+		"dev/values.yaml":     "tag: 1.0.1",
+		"staging/values.yaml": "tag: 1.0.0",
+		"staging/old.yaml":    "old: true",
+	})}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := newBitbucketClient(nil, "token", server.URL+"/projects/PRJ/repos/repo", nil)
+	if err != nil {
+		t.Fatalf("newBitbucketClient() error = %v", err)
+	}
+	if branch, err := client.DefaultBranch(); err != nil || branch != "main" {
+		t.Fatalf("DefaultBranch() = %s, %v, want main", branch, err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Fatalf("BranchExists() = %v, %v, want false", exists, err)
+	}
+	if err := client.CreateBranch("main", "promote/dev_staging"); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	current, err := client.GetFilesForBranch("promote/dev_staging", "staging")
+	if err != nil {
+		t.Fatalf("GetFilesForBranch() error = %v", err)
+	}
+	if want := []string{"staging/old.yaml", "staging/values.yaml"}; !reflect.DeepEqual(filePaths(current), want) {
+		t.Errorf("GetFilesForBranch() = %v, want %v", filePaths(current), want)
+	}
+	if single, err := client.GetFilesForBranch("main", "dev/values.yaml"); err != nil || len(single) != 1 || single[0].Content != "tag: 1.0.1" {
+		t.Errorf("GetFilesForBranch() for single file = %v, %v", single, err)
+	}
+	if missing, err := client.GetFilesForBranch("main", "prod"); err != nil || len(missing) != 0 {
+		t.Errorf("GetFilesForBranch() for missing path = %v, %v", missing, err)
+	}
+
+	// nothing is committed if a file would have to be deleted
+	if changes, err := client.SyncFilesWithBranch("promote/dev_staging", current, []RepositoryFile{
+		{Path: "staging/values.yaml", Content: "tag: 1.0.1"},
+		{Path: "staging/new.yaml", Content: "new: true"},
+	}); err == nil || !strings.Contains(err.Error(), "staging/old.yaml") || changes != 0 || fake.branch("promote/dev_staging")["staging/values.yaml"] != "tag: 1.0.0" {
+		t.Errorf("SyncFilesWithBranch() with deleted file = %d, %v, branch content = %v", changes, err, fake.branch("promote/dev_staging"))
+	}
+	changes, err := client.SyncFilesWithBranch("promote/dev_staging", current, []RepositoryFile{
+		{Path: "staging/values.yaml", Content: "tag: 1.0.1"},
+		{Path: "staging/new.yaml", Content: "new: true"},
+		{Path: "staging/old.yaml", Content: "old: true"},
+	})
+	if err != nil {
+		t.Fatalf("SyncFilesWithBranch() error = %v", err)
+	}
+	wantFiles := map[string]string{
+		"dev/values.yaml":     "tag: 1.0.1",
+		"staging/values.yaml": "tag: 1.0.1",
+		"staging/new.yaml":    "new: true",
+		"staging/old.yaml":    "old: true",
+	}
+	if changes != 2 || !reflect.DeepEqual(fake.branch("promote/dev_staging"), wantFiles) {
+		t.Errorf("SyncFilesWithBranch() changes = %d, branch content = %v, want 2 changes and %v", changes, fake.branch("promote/dev_staging"), wantFiles)
+	}
+	if newCommits, err := client.CheckForNewCommits("main", "promote/dev_staging"); err != nil || !newCommits {
+		t.Errorf("CheckForNewCommits() = %v, %v, want true", newCommits, err)
+	}
+
+	pr, err := client.CreatePullRequest("promote/dev_staging", "main", "keptn: title", "body")
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 1 || pr.URL != "https://bitbucket.example.com/projects/PRJ/repos/repo/pull-requests/1" {
+		t.Errorf("CreatePullRequest() = %+v", pr)
+	}
+	if other, err := client.GetOpenPullRequest("promote/dev_staging", "develop"); err != nil || other != nil {
+		t.Errorf("GetOpenPullRequest() for other target = %+v, %v, want nil", other, err)
+	}
+	open, err := client.GetOpenPullRequest("promote/dev_staging", "main")
+	if err != nil || open == nil || open.Number != pr.Number {
+		t.Fatalf("GetOpenPullRequest() = %+v, %v", open, err)
+	}
+	if err := client.EditPullRequest(open, "keptn: new title", "new body"); err != nil {
+		t.Fatalf("EditPullRequest() error = %v", err)
+	}
+	if fake.pullRequests[0].Title != "keptn: new title" || fake.pullRequests[0].Description != "new body" {
+		t.Errorf("EditPullRequest() did not update pull request: %+v", fake.pullRequests[0])
+	}
+	for _, state := range []string{"OPEN", "MERGED", "DECLINED"} {
+		fake.pullRequests[0].State = state
+		want := map[string]PullRequestState{"OPEN": PullRequestOpen, "MERGED": PullRequestMerged, "DECLINED": PullRequestClosed}[state]
+		if got, err := client.GetPullRequestState(pr.Number); err != nil || got != want {
+			t.Errorf("GetPullRequestState() with state %s = %v, %v, want %v", state, got, err, want)
+		}
+	}
+	fake.pullRequests[0].State, fake.vetoMerge = "OPEN", true
+	if err := client.MergePullRequest(pr, "squash"); err != nil || pr.Merged || !fake.autoMerge {
+		t.Errorf("MergePullRequest() with veto = %v, merged %v, auto-merge %v", err, pr.Merged, fake.autoMerge)
+	}
+	fake.vetoMerge = false
+	if err := client.MergePullRequest(pr, "squash"); err != nil || !pr.Merged || pr.MergeCommitSHA != "merge-sha" || fake.mergeStrategy != "squash" {
+		t.Errorf("MergePullRequest() = %v, merged %v with %s, strategy %s", err, pr.Merged, pr.MergeCommitSHA, fake.mergeStrategy)
+	}
+	if err := client.DeleteBranch("promote/dev_staging"); err != nil {
+		t.Fatalf("DeleteBranch() error = %v", err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Errorf("BranchExists() after delete = %v, %v, want false", exists, err)
+	}
+}
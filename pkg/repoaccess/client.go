@@ -1,47 +1,201 @@
 package repoaccess
 
 import (
-	"context"
-	"github.com/google/go-github/github"
-	"golang.org/x/oauth2"
-	"net/url"
+	"errors"
+	"fmt"
+	"keptn/git-promotion-service/pkg/model"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
-type Client struct {
-	githubInstance githubInstance
+// Client abstracts the operations the promoters need from a git hosting provider
+type Client interface {
+	BranchExists(branchName string) (exists bool, err error)
+	CreateBranch(sourceBranch, targetBranch string) (err error)
+	DeleteBranch(branch string) (err error)
+	DefaultBranch() (branch string, err error)
+	CheckForNewCommits(toBranch, fromBranch string) (newCommits bool, err error)
+	GetFilesForBranch(branch, path string) (files []RepositoryFile, err error)
+	SyncFilesWithBranch(branch string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error)
+	// GetBranchHead returns the commit the branch currently points to
+	GetBranchHead(branch string) (commit string, err error)
+	// SyncFilesWithBranchAt commits the changes on top of head and returns ErrBranchMoved if the branch no longer points
+	// to head, so changes read from head are never applied to a newer state of the branch
+	SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error)
+	GetOpenPullRequest(fromBranch, toBranch string) (pr *PullRequest, err error)
+	EditPullRequest(pr *PullRequest, title, body string) error
+	CreatePullRequest(fromBranch, toBranch, title, body string) (pr *PullRequest, err error)
+	GetPullRequestState(number int) (state PullRequestState, err error)
+	// MergePullRequest merges pr with method (see model.MergeMethodMerge, ...) and sets pr.Merged and pr.MergeCommitSHA.
+	// If the provider can't merge yet (e.g. required checks are pending) its auto-merge is enabled and pr.Merged stays false.
+	MergePullRequest(pr *PullRequest, method string) error
 }
 
-type githubInstance struct {
-	owner      string
-	repository string
-	context    context.Context
-	client     *github.Client
+// TagClient is implemented by the clients of providers supporting the tag strategy, check for it with a type assertion
+type TagClient interface {
+	// GetTag returns nil if the tag does not exist
+	GetTag(name string) (tag *Tag, err error)
+	// ResolveCommit returns the commit a branch or tag points to, empty if there is no such branch or tag
+	ResolveCommit(ref string) (commit string, err error)
+	// IsAncestor reports whether ancestor is reachable from commit (a commit is its own ancestor)
+	IsAncestor(ancestor, commit string) (isAncestor bool, err error)
+	// CreateTag creates an annotated tag pointing to commit, an existing tag with the same name is replaced. previous is
+	// the tag read with GetTag (nil if it did not exist), ErrTagMoved is returned if the tag was changed since then.
+	CreateTag(name, commit, message string, previous *Tag) (err error)
 }
 
-func NewClient(accessToken string, url string) (client Client, err error) {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: accessToken},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+type Tag struct {
+	Name string
+	// Commit is the commit the tag points to (the target of annotated tags)
+	Commit string
+}
+
+type RepositoryFile struct {
+	// Content holds the raw bytes of the file, it is not necessarily valid UTF-8
+	Content string
+	Path    string
+	SHA     string
+	// Mode is the git file mode of files which are not regular files (100644), e.g. 100755 for executable files. It is
+	// only set by providers which keep the mode when syncing files.
+	Mode string
+}
+
+// regularFileMode is the git file mode of regular files
+const regularFileMode = "100644"
+
+// fileMode returns the git file mode of the file
+func (f RepositoryFile) fileMode() string {
+	if f.Mode == "" {
+		return regularFileMode
+	}
+	return f.Mode
+}
+
+// binaryDetectionLength is the number of bytes checked for NUL bytes (same as git)
+const binaryDetectionLength = 8000
 
-	client.githubInstance.client = github.NewClient(tc)
-	client.githubInstance.context = ctx
-	if owner, repo, err := getGithubOwnerRepository(url); err != nil {
-		return client, err
-	} else {
-		client.githubInstance.owner = owner
-		client.githubInstance.repository = repo
-		return client, nil
+// IsBinary reports whether the file content is not text, binary files must be copied verbatim
+func (f RepositoryFile) IsBinary() bool {
+	head := f.Content
+	if len(head) > binaryDetectionLength {
+		head = head[:binaryDetectionLength]
 	}
+	return strings.IndexByte(head, 0) >= 0 || !utf8.ValidString(f.Content)
 }
 
-func getGithubOwnerRepository(raw string) (owner, repository string, err error) {
-	u, err := url.Parse(raw)
+type PullRequest struct {
+	Number int
+	Title  string
+	URL    string
+	// Merged is set if the provider applied the changes directly to the target branch instead of opening a pull request
+	// or if the pull request was merged by MergePullRequest
+	Merged bool
+	// MergeCommitSHA is the commit created by MergePullRequest
+	MergeCommitSHA string
+}
+
+// PullRequestState is the provider independent state of a pull request
+type PullRequestState string
+
+const (
+	PullRequestOpen   PullRequestState = "open"
+	PullRequestMerged PullRequestState = "merged"
+	// PullRequestClosed is a pull request closed without merging (declined, abandoned)
+	PullRequestClosed PullRequestState = "closed"
+)
+
+// ErrPullRequestsNotSupported is returned by CreatePullRequest of providers without pull requests (e.g. plain git)
+var ErrPullRequestsNotSupported = errors.New("pull requests are not supported by the provider")
+
+// ErrBranchMoved is returned by SyncFilesWithBranchAt if another commit was pushed to the branch in the meantime
+var ErrBranchMoved = errors.New("branch was changed by another commit")
+
+// ErrTagMoved is returned by CreateTag if the tag was created, moved or deleted since it was read
+var ErrTagMoved = errors.New("tag was changed concurrently")
+
+// Credentials contains everything read from the secret of a target that is needed to access the provider
+type Credentials struct {
+	// Username is only used by providers authenticating with basic auth (optional)
+	Username    string
+	AccessToken string
+	// CABundle contains additional PEM encoded certificates to trust (optional)
+	CABundle []byte
+	// GithubApp replaces AccessToken for provider github if set
+	GithubApp *GithubAppCredentials
+	// SSHPrivateKey is used for ssh remotes, only supported by provider git
+	SSHPrivateKey []byte
+	// SSHKnownHosts contains the accepted host keys in known_hosts format, required with SSHPrivateKey
+	SSHKnownHosts []byte
+}
+
+// NewClient returns the Client implementation for the provider configured in target
+func NewClient(target model.Target, credentials Credentials) (client Client, err error) {
+	if target.Provider == nil || target.Repo == nil {
+		return nil, errors.New("provider and repository are mandatory")
+	}
+	if len(credentials.SSHPrivateKey) > 0 && *target.Provider != model.ProviderGit {
+		return nil, errors.New(fmt.Sprintf("provider %s does not support ssh keys", *target.Provider))
+	}
+	httpClient, err := newHTTPClient(credentials.CABundle)
 	if err != nil {
-		return owner, repository, err
+		return nil, err
+	}
+	switch *target.Provider {
+	case model.ProviderGithub:
+		return newGithubClient(httpClient, credentials, *target.Repo, target.APIUrl)
+	case model.ProviderGitlab:
+		return newGitlabClient(httpClient, credentials.AccessToken, *target.Repo, target.APIUrl)
+	case model.ProviderBitbucketServer:
+		return newBitbucketClient(httpClient, credentials.AccessToken, *target.Repo, target.APIUrl)
+	case model.ProviderAzureDevOps:
+		return newAzureClient(httpClient, credentials.AccessToken, *target.Repo, target.APIUrl)
+	case model.ProviderGitea:
+		return newGiteaClient(httpClient, credentials.AccessToken, *target.Repo, target.APIUrl)
+	case model.ProviderGit:
+		return newGitClient(credentials, *target.Repo, target.DirectPush != nil && *target.DirectPush)
+	default:
+		return nil, errors.New(fmt.Sprintf("provider %s not supported", *target.Provider))
+	}
+}
+
+// fileChange describes a single file modification needed to sync a branch
+type fileChange struct {
+	Path string
+	// Current is nil if the file does not exist in the branch yet
+	Current *RepositoryFile
+	// Content is nil if the file has to be deleted
+	Content *string
+	// Mode is the git file mode of the new file
+	Mode string
+}
+
+// diffRepositoryFiles returns the changes (sorted by path) necessary to turn currentTargetFiles into newTargetFiles
+func diffRepositoryFiles(currentTargetFiles, newTargetFiles []RepositoryFile) (changes []fileChange) {
+	newTargetFilesMap := make(map[string]RepositoryFile)
+	for _, f := range newTargetFiles {
+		newTargetFilesMap[f.Path] = f
+	}
+	currentTargetFilesMap := make(map[string]RepositoryFile)
+	for _, f := range currentTargetFiles {
+		currentTargetFilesMap[f.Path] = f
+	}
+	for k, v := range newTargetFilesMap {
+		content := v.Content
+		if current, ok := currentTargetFilesMap[k]; !ok {
+			changes = append(changes, fileChange{Path: k, Content: &content, Mode: v.fileMode()})
+		} else if current.Content != v.Content || current.fileMode() != v.fileMode() {
+			changes = append(changes, fileChange{Path: k, Current: &current, Content: &content, Mode: v.fileMode()})
+		}
+	}
+	for k, v := range currentTargetFilesMap {
+		if _, ok := newTargetFilesMap[k]; !ok {
+			current := v
+			changes = append(changes, fileChange{Path: k, Current: &current})
+		}
 	}
-	splittedUrl := strings.Split(u.Path, "/")
-	return splittedUrl[1], splittedUrl[2], nil
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
 }
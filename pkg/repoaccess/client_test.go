@@ -1,40 +1,104 @@
 package repoaccess
 
-import "testing"
+import (
+	"keptn/git-promotion-service/pkg/model"
+	"testing"
+)
 
-func Test_getGithubOwnerRepository(t *testing.T) {
-	type args struct {
-		raw string
-	}
+func strptr(str string) *string {
+	return &str
+}
+
+func TestNewClient(t *testing.T) {
 	tests := []struct {
-		name           string
-		args           args
-		wantOwner      string
-		wantRepository string
-		wantErr        bool
+		name        string
+		target      model.Target
+		credentials Credentials
+		wantErr     bool
 	}{
 		{
-			name: "testsunshine",
-			args: args{
-				raw: "https://github.com/markuslackner/keptn-argo-dev",
+			name: "github",
+			target: model.Target{
+				Repo:     strptr("https://github.com/markuslackner/keptn-argo-dev"),
+				Provider: strptr("github"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "ssh key with github",
+			target: model.Target{
+				Repo:     strptr("https://github.com/markuslackner/keptn-argo-dev"),
+				Provider: strptr("github"),
+			},
+			credentials: Credentials{SSHPrivateKey: []byte("key")},
+			wantErr:     true,
+		},
+		{
+			name: "unknown provider",
+			target: model.Target{
+				Repo:     strptr("https://example.com/markuslackner/keptn-argo-dev"),
+				Provider: strptr("unknown"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing provider",
+			target: model.Target{
+				Repo: strptr("https://github.com/markuslackner/keptn-argo-dev"),
 			},
-			wantOwner:      "markuslackner",
-			wantRepository: "keptn-argo-dev",
-			wantErr:        false,
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotOwner, gotRepository, err := getGithubOwnerRepository(tt.args.raw)
+			credentials := tt.credentials
+			if credentials.SSHPrivateKey == nil {
+				credentials.AccessToken = "token"
+			}
+			client, err := NewClient(tt.target, credentials)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("getGithubOwnerRepository() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if gotOwner != tt.wantOwner {
-				t.Errorf("getGithubOwnerRepository() gotOwner = %v, want %v", gotOwner, tt.wantOwner)
+			if !tt.wantErr && client == nil {
+				t.Errorf("NewClient() returned nil client")
 			}
-			if gotRepository != tt.wantRepository {
-				t.Errorf("getGithubOwnerRepository() gotRepository = %v, want %v", gotRepository, tt.wantRepository)
+		})
+	}
+}
+
+func TestRepositoryFile_IsBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{
+			name:    "yaml",
+			content: "image:\n  tag: 1.0.0 # {\"value\": \"data.tag\"}\n",
+		},
+		{
+			name:    "utf-8",
+			content: "beschreibung: Grüße ✓\n",
+		},
+		{
+			name: "empty",
+		},
+		{
+			name:    "gzip",
+			content: "\x1f\x8b\x08\x00\x00\x00\x00\x00",
+			want:    true,
+		},
+		{
+			name:    "invalid utf-8 without nul",
+			content: "\x89PNG\r\n\x1a\n",
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (RepositoryFile{Content: tt.content}).IsBinary(); got != tt.want {
+				t.Errorf("IsBinary() = %v, want %v", got, tt.want)
 			}
 		})
 	}
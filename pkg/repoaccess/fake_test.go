@@ -0,0 +1,177 @@
+package repoaccess
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRepository holds the in-memory state shared by the provider api stand-ins used in the tests
+type fakeRepository struct {
+	t        *testing.T
+	branches map[string]map[string]string
+	heads    map[string]int
+	// tags maps tag names to commit ids (see headID)
+	tags        map[string]string
+	tagMessages map[string]string
+	// failTagCreations is the number of tag creations which fail
+	failTagCreations int
+	commits          int
+}
+
+func newFakeRepository(t *testing.T, files map[string]string) fakeRepository {
+	return fakeRepository{
+		t:           t,
+		branches:    map[string]map[string]string{"main": files},
+		heads:       map[string]int{"main": 0},
+		tags:        map[string]string{},
+		tagMessages: map[string]string{},
+	}
+}
+
+func (f *fakeRepository) branch(name string) map[string]string {
+	return f.branches[name]
+}
+
+func (f *fakeRepository) createBranch(source, target string) {
+	files := map[string]string{}
+	for k, v := range f.branches[source] {
+		files[k] = v
+	}
+	f.branches[target] = files
+	f.heads[target] = f.heads[source]
+}
+
+func (f *fakeRepository) deleteBranch(name string) {
+	delete(f.branches, name)
+	delete(f.heads, name)
+}
+
+func (f *fakeRepository) commit(branch string) {
+	f.commits++
+	f.heads[branch] = f.commits
+}
+
+// headID returns the id of the last commit of branch
+func (f *fakeRepository) headID(branch string) string {
+	return "commit-" + strconv.Itoa(f.heads[branch])
+}
+
+// resolve returns the head of a branch or the commit of a tag, branches win
+func (f *fakeRepository) resolve(ref string) (commit string, ok bool) {
+	if f.branch(ref) != nil {
+		return f.headID(ref), true
+	}
+	commit, ok = f.tags[ref]
+	return commit, ok
+}
+
+// isAncestor is a simplification which treats the history of all branches as linear
+func (f *fakeRepository) isAncestor(ancestor, commit string) bool {
+	a, _ := strconv.Atoi(strings.TrimPrefix(ancestor, "commit-"))
+	c, _ := strconv.Atoi(strings.TrimPrefix(commit, "commit-"))
+	return a <= c
+}
+
+// hasNewCommits is a simplification which treats branches with different content as diverged
+func (f *fakeRepository) hasNewCommits(base, head string) bool {
+	return !reflect.DeepEqual(f.branch(base), f.branch(head))
+}
+
+// filesInDirectory returns the sorted paths of all files below directory
+func (f *fakeRepository) filesInDirectory(branch, directory string) (paths []string) {
+	for k := range f.branch(branch) {
+		if strings.HasPrefix(k, strings.Trim(directory, "/")+"/") {
+			paths = append(paths, k)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func decode(t *testing.T, r *http.Request, v interface{}) {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		t.Errorf("could not decode request body: %s", err)
+	}
+}
+
+func respond(w http.ResponseWriter, found bool, v interface{}) {
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func unescape(s string) string {
+	u, _ := url.PathUnescape(s)
+	return u
+}
+
+func filePaths(files []RepositoryFile) (paths []string) {
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// testTagClient creates and moves tag prod-hello with client, the fake starts with branch main only
+func testTagClient(t *testing.T, client TagClient, fake *fakeRepository) {
+	first := fake.headID("main")
+	if tag, err := client.GetTag("prod-hello"); err != nil || tag != nil {
+		t.Fatalf("GetTag() for missing tag = %+v, %v", tag, err)
+	}
+	if commit, err := client.ResolveCommit("missing"); err != nil || commit != "" {
+		t.Fatalf("ResolveCommit() for missing ref = %s, %v", commit, err)
+	}
+	if err := client.CreateTag("prod-hello", first, "promote", nil); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	tag, err := client.GetTag("prod-hello")
+	if err != nil || tag == nil || tag.Commit != first {
+		t.Fatalf("GetTag() = %+v, %v, want commit %s", tag, err, first)
+	}
+	if err := client.CreateTag("prod-hello", first, "promote", nil); !errors.Is(err, ErrTagMoved) {
+		t.Errorf("CreateTag() for tag created in the meantime error = %v, want %v", err, ErrTagMoved)
+	}
+	fake.commit("main")
+	second, err := client.ResolveCommit("main")
+	if err != nil || second != fake.headID("main") {
+		t.Fatalf("ResolveCommit() = %s, %v, want %s", second, err, fake.headID("main"))
+	}
+	if commit, err := client.ResolveCommit("prod-hello"); err != nil || commit != first {
+		t.Errorf("ResolveCommit() for tag = %s, %v, want %s", commit, err, first)
+	}
+	if isAncestor, err := client.IsAncestor(first, second); err != nil || !isAncestor {
+		t.Errorf("IsAncestor(first, second) = %v, %v, want true", isAncestor, err)
+	}
+	if isAncestor, err := client.IsAncestor(second, first); err != nil || isAncestor {
+		t.Errorf("IsAncestor(second, first) = %v, %v, want false", isAncestor, err)
+	}
+	if err := client.CreateTag("prod-hello", second, "promote", tag); err != nil {
+		t.Fatalf("CreateTag() for existing tag error = %v", err)
+	}
+	if fake.tags["prod-hello"] != second {
+		t.Errorf("tag points to %s, want %s", fake.tags["prod-hello"], second)
+	}
+	// the tag was moved since it was read
+	if err := client.CreateTag("prod-hello", first, "promote again", tag); !errors.Is(err, ErrTagMoved) || fake.tags["prod-hello"] != second {
+		t.Errorf("CreateTag() with moved tag error = %v, tag points to %s, want %v and %s", err, fake.tags["prod-hello"], ErrTagMoved, second)
+	}
+	// the tag is kept if the new one can't be created after deleting it
+	fake.failTagCreations = 1
+	if err := client.CreateTag("prod-hello", first, "promote again", &Tag{Name: "prod-hello", Commit: second}); err == nil {
+		t.Errorf("CreateTag() with failing creation succeeded")
+	}
+	if fake.tags["prod-hello"] != second || fake.tagMessages["prod-hello"] != "promote" {
+		t.Errorf("tag points to %s with message %s after failed move, want %s with message promote", fake.tags["prod-hello"], fake.tagMessages["prod-hello"], second)
+	}
+}
@@ -0,0 +1,144 @@
+package repoaccess
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const gitAuthorName = "keptn git-promotion-service"
+const gitAuthorEmail = "git-promotion-service@keptn.sh"
+
+// gitClient works on a local clone of the target repository and only needs the git binary and a git remote.
+// It is used for git servers without pull request api.
+type gitClient struct {
+	remote string
+	// directPush pushes the changes of a pull request directly to the target branch
+	directPush bool
+	env        []string
+	// config is passed to every git command with -c
+	config    []string
+	workspace string
+	// sshDirectory contains the private key and known hosts written from the credentials
+	sshDirectory string
+}
+
+func newGitClient(credentials Credentials, remote string, directPush bool) (client *gitClient, err error) {
+	if remote == "" {
+		return nil, errors.New("git remote is missing")
+	}
+	client = &gitClient{
+		remote:     remote,
+		directPush: directPush,
+		env:        []string{"GIT_TERMINAL_PROMPT=0"},
+	}
+	if credentials.AccessToken != "" && (strings.HasPrefix(remote, "https://") || strings.HasPrefix(remote, "http://")) {
+		username := credentials.Username
+		if username == "" {
+			username = "git"
+		}
+		// passed with -c instead of GIT_CONFIG_COUNT, which needs git 2.31 and is silently ignored by older versions
+		client.config = append(client.config,
+			"http.extraHeader=Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+credentials.AccessToken)))
+	}
+	if len(credentials.SSHPrivateKey) > 0 {
+		if err := client.configureSSH(credentials.SSHPrivateKey, credentials.SSHKnownHosts); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// configureSSH writes the key files and lets git use them via GIT_SSH_COMMAND. Only the host keys in knownHosts are
+// accepted.
+func (c *gitClient) configureSSH(privateKey, knownHosts []byte) error {
+	if len(bytes.TrimSpace(knownHosts)) == 0 {
+		return errors.New(fmt.Sprintf("known_hosts is missing, the host key of %s can't be verified", c.remote))
+	}
+	directory, err := os.MkdirTemp("", "git-promotion-ssh-")
+	if err != nil {
+		return err
+	}
+	c.sshDirectory = directory
+	keyFile := filepath.Join(directory, "id")
+	// ssh refuses keys without trailing newline
+	if !bytes.HasSuffix(privateKey, []byte("\n")) {
+		privateKey = append(append([]byte{}, privateKey...), '\n')
+	}
+	if err := os.WriteFile(keyFile, privateKey, 0600); err != nil {
+		return err
+	}
+	knownHostsFile := filepath.Join(directory, "known_hosts")
+	if err := os.WriteFile(knownHostsFile, knownHosts, 0600); err != nil {
+		return err
+	}
+	c.env = append(c.env, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i '%s' -o IdentitiesOnly=yes -o BatchMode=yes -o UserKnownHostsFile='%s' -o StrictHostKeyChecking=yes",
+		keyFile, knownHostsFile))
+	return nil
+}
+
+// Close removes the local clone and the ssh key
+func (c *gitClient) Close() error {
+	var err error
+	for _, directory := range []string{c.workspace, c.sshDirectory} {
+		if directory == "" {
+			continue
+		}
+		if removeErr := os.RemoveAll(directory); removeErr != nil {
+			err = removeErr
+		}
+	}
+	return err
+}
+
+// fetch initializes the local clone (without checkout) on first use and updates all remote tracking branches and tags
+func (c *gitClient) fetch() error {
+	if c.workspace == "" {
+		workspace, err := os.MkdirTemp("", "git-promotion-")
+		if err != nil {
+			return err
+		}
+		c.workspace = workspace
+		if _, err := c.git(nil, nil, "init", "--quiet"); err != nil {
+			return err
+		}
+		if _, err := c.git(nil, nil, "remote", "add", "origin", c.remote); err != nil {
+			return err
+		}
+	}
+	_, err := c.git(nil, nil, "fetch", "--quiet", "--prune", "origin", "+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*")
+	return err
+}
+
+// git runs the git binary in the workspace and returns stdout
+func (c *gitClient) git(stdin []byte, env []string, args ...string) (stdout []byte, err error) {
+	var configArgs []string
+	for _, config := range c.config {
+		configArgs = append(configArgs, "-c", config)
+	}
+	cmd := exec.Command("git", append(configArgs, args...)...)
+	cmd.Dir = c.workspace
+	cmd.Env = append(append(os.Environ(), c.env...), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		logger.WithField("func", "git").Errorf("git %s failed: %s", args[0], stderr.String())
+		return nil, errors.New(fmt.Sprintf("git %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String())))
+	}
+	return out.Bytes(), nil
+}
+
+func remoteBranchRef(branch string) string {
+	return "refs/remotes/origin/" + branch
+}
@@ -0,0 +1,52 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func (c *gitClient) BranchExists(branchName string) (exists bool, err error) {
+	if err := c.fetch(); err != nil {
+		return false, err
+	}
+	out, err := c.git(nil, nil, "ls-remote", "--heads", "origin", "refs/heads/"+branchName)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
+
+func (c *gitClient) CreateBranch(sourceBranch, targetBranch string) (err error) {
+	if err := c.fetch(); err != nil {
+		return err
+	}
+	_, err = c.git(nil, nil, "push", "--quiet", "origin", remoteBranchRef(sourceBranch)+":refs/heads/"+targetBranch)
+	return err
+}
+
+func (c *gitClient) DeleteBranch(branch string) (err error) {
+	if err := c.fetch(); err != nil {
+		return err
+	}
+	_, err = c.git(nil, nil, "push", "--quiet", "origin", ":refs/heads/"+branch)
+	return err
+}
+
+// DefaultBranch returns the branch HEAD of the remote points to
+func (c *gitClient) DefaultBranch() (branch string, err error) {
+	if err := c.fetch(); err != nil {
+		return "", err
+	}
+	out, err := c.git(nil, nil, "ls-remote", "--symref", "origin", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	// ref: refs/heads/main<TAB>HEAD
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "ref: refs/heads/") && strings.HasSuffix(line, "\tHEAD") {
+			return strings.TrimSuffix(strings.TrimPrefix(line, "ref: refs/heads/"), "\tHEAD"), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("could not determine default branch of %s", c.remote))
+}
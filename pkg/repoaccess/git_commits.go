@@ -0,0 +1,171 @@
+package repoaccess
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func (c *gitClient) CheckForNewCommits(toBranch, fromBranch string) (newCommits bool, err error) {
+	if err := c.fetch(); err != nil {
+		return false, err
+	}
+	out, err := c.git(nil, nil, "rev-list", "--count", remoteBranchRef(toBranch)+".."+remoteBranchRef(fromBranch))
+	if err != nil {
+		return false, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return false, err
+	}
+	logger.WithField("func", "CheckForNewCommits").Infof("found %d commits in git repo %s from branch %s to %s", count, c.remote, fromBranch, toBranch)
+	return count > 0, nil
+}
+
+func (c *gitClient) GetFilesForBranch(branch, path string) (files []RepositoryFile, err error) {
+	logger.WithField("func", "GetFilesForBranch").Infof("starting with branch %s and path %s", branch, path)
+	if err := c.fetch(); err != nil {
+		return files, err
+	}
+	args := []string{"ls-tree", "-r", "-z", "--full-tree", remoteBranchRef(branch)}
+	if path = strings.Trim(path, "/"); path != "" {
+		args = append(args, "--", path)
+	}
+	out, err := c.git(nil, nil, args...)
+	if err != nil {
+		return files, err
+	}
+	var objects bytes.Buffer
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// <mode> SP <type> SP <object> TAB <file>
+		parts := strings.SplitN(entry, "\t", 2)
+		fields := strings.Fields(parts[0])
+		// submodules (type commit) and symlinks are skipped
+		if len(parts) != 2 || len(fields) != 3 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		file := RepositoryFile{Path: parts[1], SHA: fields[2]}
+		if fields[0] != regularFileMode {
+			file.Mode = fields[0]
+		}
+		files = append(files, file)
+		objects.WriteString(fields[2] + "\n")
+	}
+	if len(files) == 0 {
+		return files, nil
+	}
+	contents, err := c.git(objects.Bytes(), nil, "cat-file", "--batch")
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(bytes.NewReader(contents))
+	for i := range files {
+		// <object> SP <type> SP <size> LF <contents> LF
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, errors.New(fmt.Sprintf("unexpected cat-file output %s", header))
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		content := make([]byte, size+1)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return nil, err
+		}
+		files[i].Content = string(content[:size])
+		logger.WithField("func", "GetFilesForBranch").Infof("found file in path %s", files[i].Path)
+	}
+	return files, nil
+}
+
+// SyncFilesWithBranch builds a single commit with all differences on top of the current head of the branch and pushes
+// it. The push is rejected if the branch was changed in the meantime.
+func (c *gitClient) SyncFilesWithBranch(branch string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	head, err := c.GetBranchHead(branch)
+	if err != nil {
+		return 0, err
+	}
+	return c.SyncFilesWithBranchAt(branch, head, currentTargetFiles, newTargetFiles)
+}
+
+func (c *gitClient) GetBranchHead(branch string) (commit string, err error) {
+	if err := c.fetch(); err != nil {
+		return "", err
+	}
+	out, err := c.git(nil, nil, "rev-parse", "--verify", remoteBranchRef(branch))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// SyncFilesWithBranchAt builds the commit on top of head, the remote rejects the push (no force) if the branch was
+// moved in the meantime because the commit is no fast forward anymore
+func (c *gitClient) SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	logger.WithField("func", "SyncfilesWithBranch").Infof("starting for branch %s at %s and %d currentTargetFiles and %d newTargetFiles", branch, head, len(currentTargetFiles), len(newTargetFiles))
+	fileChanges := diffRepositoryFiles(currentTargetFiles, newTargetFiles)
+	if len(fileChanges) == 0 {
+		return 0, nil
+	}
+	if c.workspace == "" {
+		// head has to be available locally
+		if err := c.fetch(); err != nil {
+			return 0, err
+		}
+	}
+	index, err := os.CreateTemp(c.workspace, "index-")
+	if err != nil {
+		return 0, err
+	}
+	_ = index.Close()
+	defer os.Remove(index.Name())
+	indexEnv := []string{"GIT_INDEX_FILE=" + index.Name()}
+	if _, err := c.git(nil, indexEnv, "read-tree", head); err != nil {
+		return 0, err
+	}
+	for _, change := range fileChanges {
+		if change.Content == nil {
+			logger.WithField("func", "SyncfilesWithBranch").Infof("deleting file %s in branch %s", change.Path, branch)
+			if _, err := c.git(nil, indexEnv, "update-index", "--force-remove", "--", change.Path); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		logger.WithField("func", "SyncfilesWithBranch").Infof("writing file %s in branch %s", change.Path, branch)
+		blob, err := c.git([]byte(*change.Content), nil, "hash-object", "-w", "--stdin")
+		if err != nil {
+			return 0, err
+		}
+		if _, err := c.git(nil, indexEnv, "update-index", "--add", "--cacheinfo", change.Mode+","+strings.TrimSpace(string(blob))+","+change.Path); err != nil {
+			return 0, err
+		}
+	}
+	tree, err := c.git(nil, indexEnv, "write-tree")
+	if err != nil {
+		return 0, err
+	}
+	commit, err := c.git(nil, []string{
+		"GIT_AUTHOR_NAME=" + gitAuthorName, "GIT_AUTHOR_EMAIL=" + gitAuthorEmail,
+		"GIT_COMMITTER_NAME=" + gitAuthorName, "GIT_COMMITTER_EMAIL=" + gitAuthorEmail,
+	}, "commit-tree", strings.TrimSpace(string(tree)), "-p", head, "-m", "(build) sync files")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.git(nil, nil, "push", "--quiet", "origin", strings.TrimSpace(string(commit))+":refs/heads/"+branch); err != nil && strings.Contains(err.Error(), "[rejected]") {
+		return 0, ErrBranchMoved
+	} else if err != nil {
+		return 0, err
+	}
+	return len(fileChanges), nil
+}
@@ -0,0 +1,39 @@
+package repoaccess
+
+import (
+	logger "github.com/sirupsen/logrus"
+)
+
+// GetOpenPullRequest always returns nil because plain git has no pull requests
+func (c *gitClient) GetOpenPullRequest(fromBranch, toBranch string) (pr *PullRequest, err error) {
+	return nil, nil
+}
+
+func (c *gitClient) EditPullRequest(pr *PullRequest, title, body string) error {
+	return nil
+}
+
+// CreatePullRequest fast-forwards toBranch to fromBranch if direct push is enabled, otherwise ErrPullRequestsNotSupported
+// is returned and the changes stay in fromBranch
+func (c *gitClient) CreatePullRequest(fromBranch, toBranch, title, body string) (pr *PullRequest, err error) {
+	if !c.directPush {
+		return nil, ErrPullRequestsNotSupported
+	}
+	if err := c.fetch(); err != nil {
+		return nil, err
+	}
+	// no force push => rejected by the remote if it is not a fast-forward
+	if _, err := c.git(nil, nil, "push", "--quiet", "origin", remoteBranchRef(fromBranch)+":refs/heads/"+toBranch); err != nil {
+		return nil, err
+	}
+	logger.WithField("func", "CreatePullRequest").Infof("pushed branch %s directly to %s in git repo %s", fromBranch, toBranch, c.remote)
+	return &PullRequest{Title: title, Merged: true}, nil
+}
+
+func (c *gitClient) GetPullRequestState(number int) (state PullRequestState, err error) {
+	return "", ErrPullRequestsNotSupported
+}
+
+func (c *gitClient) MergePullRequest(pr *PullRequest, method string) error {
+	return ErrPullRequestsNotSupported
+}
@@ -0,0 +1,80 @@
+package repoaccess
+
+import (
+	"strconv"
+	"strings"
+)
+
+func (c *gitClient) GetTag(name string) (tag *Tag, err error) {
+	if err := c.fetch(); err != nil {
+		return nil, err
+	}
+	commit, err := c.refCommit("refs/tags/" + name)
+	if err != nil || commit == "" {
+		return nil, err
+	}
+	return &Tag{Name: name, Commit: commit}, nil
+}
+
+// ResolveCommit prefers branches over tags with the same name
+func (c *gitClient) ResolveCommit(ref string) (commit string, err error) {
+	if err := c.fetch(); err != nil {
+		return "", err
+	}
+	if commit, err = c.refCommit(remoteBranchRef(ref)); err != nil || commit != "" {
+		return commit, err
+	}
+	return c.refCommit("refs/tags/" + ref)
+}
+
+// refCommit returns the commit ref points to (the target for annotated tags), empty if ref does not exist
+func (c *gitClient) refCommit(ref string) (commit string, err error) {
+	_, commit, err = c.refObjects(ref)
+	return commit, err
+}
+
+// refObjects returns the object ref points to and its commit (the tag object and its target for annotated tags)
+func (c *gitClient) refObjects(ref string) (object, commit string, err error) {
+	out, err := c.git(nil, nil, "for-each-ref", "--format=%(objectname) %(*objectname)", ref)
+	if err != nil {
+		return "", "", err
+	}
+	// <object> SP <target of annotated tag>
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", "", nil
+	}
+	return fields[0], fields[len(fields)-1], nil
+}
+
+func (c *gitClient) IsAncestor(ancestor, commit string) (isAncestor bool, err error) {
+	// commits reachable from ancestor but not from commit
+	out, err := c.git(nil, nil, "rev-list", "--count", commit+".."+ancestor)
+	if err != nil {
+		return false, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	return err == nil && count == 0, err
+}
+
+// CreateTag pushes the tag with a lease on the remote tag as it was fetched by GetTag, so the push is rejected if the
+// tag was changed in the meantime
+func (c *gitClient) CreateTag(name, commit, message string, previous *Tag) (err error) {
+	ref := "refs/tags/" + name
+	// the local tag is the remote tag of the last fetch
+	object, current, err := c.refObjects(ref)
+	if err != nil {
+		return err
+	} else if (previous == nil && object != "") || (previous != nil && current != previous.Commit) {
+		return ErrTagMoved
+	}
+	if _, err := c.git(nil, []string{"GIT_COMMITTER_NAME=" + gitAuthorName, "GIT_COMMITTER_EMAIL=" + gitAuthorEmail},
+		"tag", "--annotate", "--force", "--message", message, name, commit); err != nil {
+		return err
+	}
+	// an empty lease only accepts a tag which does not exist
+	if _, err = c.git(nil, nil, "push", "--quiet", "--force-with-lease="+ref+":"+object, "origin", ref+":"+ref); err != nil && strings.Contains(err.Error(), "stale info") {
+		return ErrTagMoved
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type giteaClient struct {
+	owner      string
+	repository string
+	rest       restClient
+}
+
+func newGiteaClient(httpClient *http.Client, accessToken string, repositoryUrl string, customApiURL *string) (client *giteaClient, err error) {
+	apiURL, owner, repository, err := getGiteaApiUrlOwnerRepository(repositoryUrl)
+	if err != nil {
+		return nil, err
+	}
+	if customApiURL != nil && *customApiURL != "" {
+		apiURL = strings.TrimSuffix(*customApiURL, "/")
+	}
+	return &giteaClient{
+		owner:      owner,
+		repository: repository,
+		rest: restClient{
+			baseURL:    apiURL + "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repository),
+			httpClient: httpClient,
+			headers:    map[string]string{"Authorization": "token " + accessToken},
+		},
+	}, nil
+}
+
+// getGiteaApiUrlOwnerRepository uses the last two path segments of a repository url like
+// https://<host>[/<sub-path>]/<owner>/<repository>[.git] as owner and repository, everything in front is the root url of the instance
+func getGiteaApiUrlOwnerRepository(raw string) (apiURL, owner, repository string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", "", err
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || segments[len(segments)-2] == "" {
+		return "", "", "", errors.New(fmt.Sprintf("repository url %s does not contain an owner and a repository", raw))
+	}
+	root := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	if len(segments) > 2 {
+		root += "/" + strings.Join(segments[:len(segments)-2], "/")
+	}
+	return root + "/api/v1", segments[len(segments)-2], strings.TrimSuffix(segments[len(segments)-1], ".git"), nil
+}
@@ -0,0 +1,36 @@
+package repoaccess
+
+import (
+	"net/http"
+	"net/url"
+)
+
+func (c *giteaClient) BranchExists(branchName string) (exists bool, err error) {
+	if err := c.rest.do(http.MethodGet, "/branches/"+url.PathEscape(branchName), nil, nil, nil); isNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *giteaClient) CreateBranch(sourceBranch, targetBranch string) (err error) {
+	return c.rest.do(http.MethodPost, "/branches", nil, map[string]string{
+		"new_branch_name": targetBranch,
+		"old_branch_name": sourceBranch,
+	}, nil)
+}
+
+func (c *giteaClient) DeleteBranch(branch string) (err error) {
+	return c.rest.do(http.MethodDelete, "/branches/"+url.PathEscape(branch), nil, nil, nil)
+}
+
+func (c *giteaClient) DefaultBranch() (branch string, err error) {
+	var repository struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := c.rest.do(http.MethodGet, "", nil, nil, &repository); err != nil {
+		return "", err
+	}
+	return repository.DefaultBranch, nil
+}
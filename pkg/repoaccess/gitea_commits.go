@@ -0,0 +1,150 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	logger "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type giteaCompare struct {
+	TotalCommits int `json:"total_commits"`
+}
+
+type giteaContent struct {
+	Type     string `json:"type"`
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+type giteaChangeFile struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Content   string `json:"content,omitempty"`
+	SHA       string `json:"sha,omitempty"`
+}
+
+type giteaChangeFiles struct {
+	Branch  string            `json:"branch"`
+	Message string            `json:"message"`
+	Files   []giteaChangeFile `json:"files"`
+}
+
+func (c *giteaClient) CheckForNewCommits(toBranch, fromBranch string) (newCommits bool, err error) {
+	var compare giteaCompare
+	if err := c.rest.do(http.MethodGet, "/compare/"+url.PathEscape(toBranch)+"..."+url.PathEscape(fromBranch), nil, nil, &compare); err != nil {
+		return false, err
+	}
+	logger.WithField("func", "CheckForNewCommits").Infof("found %d commits in gitea repo %s/%s from branch %s to %s", compare.TotalCommits, c.owner, c.repository, fromBranch, toBranch)
+	return compare.TotalCommits > 0, nil
+}
+
+func (c *giteaClient) GetFilesForBranch(branch, path string) (files []RepositoryFile, err error) {
+	logger.WithField("func", "GetFilesForBranch").Infof("starting with branch %s and path %s", branch, path)
+	var raw json.RawMessage
+	if err := c.rest.do(http.MethodGet, "/contents/"+escapeFilePath(path), url.Values{"ref": {branch}}, nil, &raw); isNotFound(err) {
+		return files, nil
+	} else if err != nil {
+		return files, err
+	}
+	// the contents api returns an object for files and an array for directories
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "{") {
+		var content giteaContent
+		if err := json.Unmarshal(raw, &content); err != nil {
+			return files, err
+		}
+		if file, err := content.toRepositoryFile(); err != nil {
+			return files, err
+		} else {
+			return append(files, file), nil
+		}
+	}
+	var entries []giteaContent
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return files, err
+	}
+	for _, e := range entries {
+		logger.WithField("func", "GetFilesForBranch").Infof("processing entry with path %s", e.Path)
+		if e.Type == "file" || e.Type == "dir" {
+			if entryFiles, err := c.GetFilesForBranch(branch, e.Path); err != nil {
+				return files, err
+			} else {
+				files = append(files, entryFiles...)
+			}
+		} else {
+			logger.WithField("func", "GetFilesForBranch").Infof("unknown file type %s", e.Type)
+		}
+	}
+	return files, nil
+}
+
+func (content giteaContent) toRepositoryFile() (file RepositoryFile, err error) {
+	decoded := []byte(content.Content)
+	if content.Encoding == "base64" {
+		if decoded, err = base64.StdEncoding.DecodeString(content.Content); err != nil {
+			return file, err
+		}
+	}
+	logger.WithField("func", "GetFilesForBranch").Infof("found file in path %s", content.Path)
+	return RepositoryFile{
+		Content: string(decoded),
+		Path:    content.Path,
+		SHA:     content.SHA,
+	}, nil
+}
+
+// SyncFilesWithBranch commits all differences between currentTargetFiles and newTargetFiles with a single commit
+func (c *giteaClient) SyncFilesWithBranch(branch string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	logger.WithField("func", "SyncfilesWithBranch").Infof("starting for branch %s and %d currentTargetFiles and %d newTargetFiles", branch, len(currentTargetFiles), len(newTargetFiles))
+	changeFiles := giteaChangeFiles{
+		Branch:  branch,
+		Message: "(build) sync files",
+	}
+	for _, change := range diffRepositoryFiles(currentTargetFiles, newTargetFiles) {
+		file := giteaChangeFile{Path: change.Path}
+		switch {
+		case change.Content == nil:
+			file.Operation = "delete"
+		case change.Current == nil:
+			file.Operation = "create"
+		default:
+			file.Operation = "update"
+		}
+		if change.Current != nil {
+			file.SHA = change.Current.SHA
+		}
+		if change.Content != nil {
+			file.Content = base64.StdEncoding.EncodeToString([]byte(*change.Content))
+		}
+		changeFiles.Files = append(changeFiles.Files, file)
+	}
+	if len(changeFiles.Files) == 0 {
+		return 0, nil
+	}
+	if err := c.rest.do(http.MethodPost, "/contents", nil, changeFiles, nil); err != nil {
+		return 0, err
+	}
+	return len(changeFiles.Files), nil
+}
+
+func (c *giteaClient) GetBranchHead(branch string) (commit string, err error) {
+	var b struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := c.rest.do(http.MethodGet, "/branches/"+url.PathEscape(branch), nil, nil, &b); err != nil {
+		return "", err
+	}
+	return b.Commit.ID, nil
+}
+
+// SyncFilesWithBranchAt is not supported, the contents api has no expected head parameter to guard the branch
+func (c *giteaClient) SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	return 0, errors.New("committing changes at a given head is not supported by provider gitea")
+}
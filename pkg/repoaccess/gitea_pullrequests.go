@@ -0,0 +1,108 @@
+package repoaccess
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const giteaPageSize = 50
+
+type giteaBranchRef struct {
+	Ref string `json:"ref"`
+}
+
+type giteaPullRequest struct {
+	Number  int            `json:"number"`
+	Title   string         `json:"title"`
+	HTMLURL string         `json:"html_url"`
+	Head    giteaBranchRef `json:"head"`
+	Base    giteaBranchRef `json:"base"`
+	State   string         `json:"state,omitempty"`
+	Merged  bool           `json:"merged,omitempty"`
+	// MergeCommitSHA is read only
+	MergeCommitSHA string `json:"merge_commit_sha,omitempty"`
+}
+
+func (pr giteaPullRequest) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number: pr.Number,
+		Title:  pr.Title,
+		URL:    pr.HTMLURL,
+	}
+}
+
+func (c *giteaClient) GetOpenPullRequest(fromBranch, toBranch string) (pr *PullRequest, err error) {
+	for page := 1; ; page++ {
+		var prs []giteaPullRequest
+		if err := c.rest.do(http.MethodGet, "/pulls", url.Values{
+			"state": {"open"},
+			"limit": {strconv.Itoa(giteaPageSize)},
+			"page":  {strconv.Itoa(page)},
+		}, nil, &prs); err != nil {
+			return nil, err
+		}
+		for _, p := range prs {
+			if p.Head.Ref == fromBranch && p.Base.Ref == toBranch {
+				return p.toPullRequest(), nil
+			}
+		}
+		if len(prs) < giteaPageSize {
+			return nil, nil
+		}
+	}
+}
+
+func (c *giteaClient) EditPullRequest(pr *PullRequest, title, body string) error {
+	return c.rest.do(http.MethodPatch, fmt.Sprintf("/pulls/%d", pr.Number), nil, map[string]string{
+		"title": title,
+		"body":  body,
+	}, nil)
+}
+
+func (c *giteaClient) CreatePullRequest(fromBranch, toBranch, title, body string) (pr *PullRequest, err error) {
+	var created giteaPullRequest
+	if err := c.rest.do(http.MethodPost, "/pulls", nil, map[string]string{
+		"head":  fromBranch,
+		"base":  toBranch,
+		"title": title,
+		"body":  body,
+	}, &created); err != nil {
+		return nil, err
+	}
+	return created.toPullRequest(), nil
+}
+
+func (c *giteaClient) GetPullRequestState(number int) (state PullRequestState, err error) {
+	var pr giteaPullRequest
+	if err := c.rest.do(http.MethodGet, fmt.Sprintf("/pulls/%d", number), nil, nil, &pr); err != nil {
+		return "", err
+	}
+	if pr.Merged {
+		return PullRequestMerged, nil
+	} else if pr.State == "closed" {
+		return PullRequestClosed, nil
+	}
+	return PullRequestOpen, nil
+}
+
+// MergePullRequest merges with method, if the pull request is not mergeable yet (e.g. pending checks) it is merged when
+// the checks succeed (Gitea 1.21+)
+func (c *giteaClient) MergePullRequest(pr *PullRequest, method string) error {
+	err := c.rest.do(http.MethodPost, fmt.Sprintf("/pulls/%d/merge", pr.Number), nil, map[string]interface{}{"Do": method}, nil)
+	if isStatus(err, http.StatusMethodNotAllowed) {
+		return c.rest.do(http.MethodPost, fmt.Sprintf("/pulls/%d/merge", pr.Number), nil, map[string]interface{}{
+			"Do":                        method,
+			"merge_when_checks_succeed": true,
+		}, nil)
+	} else if err != nil {
+		return err
+	}
+	var merged giteaPullRequest
+	if err := c.rest.do(http.MethodGet, fmt.Sprintf("/pulls/%d", pr.Number), nil, nil, &merged); err != nil {
+		return err
+	}
+	pr.Merged, pr.MergeCommitSHA = merged.Merged, merged.MergeCommitSHA
+	return nil
+}
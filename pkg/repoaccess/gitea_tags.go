@@ -0,0 +1,91 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type giteaTag struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Commit  struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+func (c *giteaClient) GetTag(name string) (tag *Tag, err error) {
+	var t giteaTag
+	if err := c.rest.do(http.MethodGet, "/tags/"+url.PathEscape(name), nil, nil, &t); isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &Tag{Name: t.Name, Commit: t.Commit.SHA}, nil
+}
+
+// ResolveCommit prefers branches over tags with the same name
+func (c *giteaClient) ResolveCommit(ref string) (commit string, err error) {
+	if commit, err = c.GetBranchHead(ref); err == nil || !isNotFound(err) {
+		return commit, err
+	}
+	if tag, err := c.GetTag(ref); err != nil || tag == nil {
+		return "", err
+	} else {
+		return tag.Commit, nil
+	}
+}
+
+// IsAncestor compares commit with ancestor, there are no commits only reachable from ancestor if it is an ancestor
+func (c *giteaClient) IsAncestor(ancestor, commit string) (isAncestor bool, err error) {
+	var compare giteaCompare
+	if err := c.rest.do(http.MethodGet, "/compare/"+url.PathEscape(commit)+"..."+url.PathEscape(ancestor), nil, nil, &compare); err != nil {
+		return false, err
+	}
+	return compare.TotalCommits == 0, nil
+}
+
+// CreateTag deletes an existing tag first, the tags api can't move tags. Deleting and creating the tag is not atomic:
+// the tag is only deleted if it still points to the commit of previous and is only created if nobody created it again
+// after the deletion. If the new tag can't be created the existing tag is restored.
+func (c *giteaClient) CreateTag(name, commit, message string, previous *Tag) (err error) {
+	var existing giteaTag
+	if err := c.rest.do(http.MethodGet, "/tags/"+url.PathEscape(name), nil, nil, &existing); isNotFound(err) {
+		existing.Name = ""
+	} else if err != nil {
+		return err
+	}
+	if (previous == nil) != (existing.Name == "") || (previous != nil && existing.Commit.SHA != previous.Commit) {
+		return ErrTagMoved
+	}
+	if existing.Name != "" {
+		if err := c.rest.do(http.MethodDelete, "/tags/"+url.PathEscape(name), nil, nil, nil); err != nil && !isNotFound(err) {
+			return err
+		}
+		// another promotion could have created the tag again after the deletion
+		if tag, err := c.GetTag(name); err != nil {
+			return err
+		} else if tag != nil {
+			return ErrTagMoved
+		}
+	}
+	if err := c.createTag(name, commit, message); err != nil {
+		if existing.Name == "" {
+			return err
+		}
+		if restoreErr := c.createTag(name, existing.Commit.SHA, existing.Message); restoreErr != nil {
+			return errors.New(fmt.Sprintf("could not create tag %s: %s, restoring it at %s failed: %s", name, err.Error(), existing.Commit.SHA, restoreErr.Error()))
+		}
+		return errors.New(fmt.Sprintf("could not create tag %s, kept it at %s: %s", name, existing.Commit.SHA, err.Error()))
+	}
+	return nil
+}
+
+func (c *giteaClient) createTag(name, commit, message string) error {
+	return c.rest.do(http.MethodPost, "/tags", nil, map[string]string{
+		"tag_name": name,
+		"target":   commit,
+		"message":  message,
+	}, nil)
+}
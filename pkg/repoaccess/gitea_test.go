@@ -0,0 +1,316 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeGitea is a minimal in-memory stand-in for the gitea api of repository owner/repo
+type fakeGitea struct {
+	fakeRepository
+	pullRequests []giteaPullRequest
+	// pendingChecks simulates required status checks that are not finished
+	pendingChecks bool
+	mergeOptions  map[string]interface{}
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "token token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/gitea/api/v1/repos/owner/repo"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		respond(w, true, map[string]string{"default_branch": "main"})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/branches/"):
+		branch := strings.TrimPrefix(path, "/branches/")
+		respond(w, f.branch(branch) != nil, map[string]interface{}{"commit": map[string]string{"id": f.headID(branch)}})
+	case r.Method == http.MethodPost && path == "/branches":
+		var body map[string]string
+		decode(f.t, r, &body)
+		f.createBranch(body["old_branch_name"], body["new_branch_name"])
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/branches/"):
+		f.deleteBranch(strings.TrimPrefix(path, "/branches/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/tags/"):
+		name := strings.TrimPrefix(path, "/tags/")
+		commit, ok := f.tags[name]
+		respond(w, ok, map[string]interface{}{"name": name, "message": f.tagMessages[name], "commit": map[string]string{"sha": commit}})
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/tags/"):
+		name := strings.TrimPrefix(path, "/tags/")
+		_, ok := f.tags[name]
+		delete(f.tags, name)
+		respond(w, ok, map[string]string{})
+	case r.Method == http.MethodPost && path == "/tags" && f.failTagCreations > 0:
+		f.failTagCreations--
+		w.WriteHeader(http.StatusInternalServerError)
+	case r.Method == http.MethodPost && path == "/tags":
+		var body map[string]string
+		decode(f.t, r, &body)
+		if _, ok := f.tags[body["tag_name"]]; ok || body["message"] == "" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.tags[body["tag_name"]], f.tagMessages[body["tag_name"]] = body["target"], body["message"]
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/compare/"):
+		refs := strings.SplitN(strings.TrimPrefix(path, "/compare/"), "...", 2)
+		compare := giteaCompare{}
+		if strings.HasPrefix(refs[0], "commit-") && !f.isAncestor(refs[1], refs[0]) {
+			compare.TotalCommits = 1
+		} else if !strings.HasPrefix(refs[0], "commit-") && f.hasNewCommits(refs[0], refs[1]) {
+			compare.TotalCommits = 1
+		}
+		respond(w, true, compare)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/contents/"):
+		branch := query.Get("ref")
+		filePath := strings.TrimPrefix(path, "/contents/")
+		if content, ok := f.branch(branch)[filePath]; ok {
+			respond(w, true, giteaContent{Type: "file", Path: filePath, SHA: "sha-" + filePath, Content: base64.StdEncoding.EncodeToString([]byte(content)), Encoding: "base64"})
+			return
+		}
+		var entries []giteaContent
+		seen := map[string]bool{}
+		for _, p := range f.filesInDirectory(branch, filePath) {
+			child := strings.SplitN(strings.TrimPrefix(p, filePath+"/"), "/", 2)
+			entry := giteaContent{Type: "file", Path: filePath + "/" + child[0]}
+			if len(child) > 1 {
+				entry.Type = "dir"
+			}
+			if !seen[entry.Path] {
+				seen[entry.Path] = true
+				entries = append(entries, entry)
+			}
+		}
+		respond(w, len(entries) > 0, entries)
+	case r.Method == http.MethodPost && path == "/contents":
+		var changeFiles giteaChangeFiles
+		decode(f.t, r, &changeFiles)
+		for _, file := range changeFiles.Files {
+			if file.Operation != "create" && file.SHA != "sha-"+file.Path {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		for _, file := range changeFiles.Files {
+			if file.Operation == "delete" {
+				delete(f.branch(changeFiles.Branch), file.Path)
+			} else {
+				content, _ := base64.StdEncoding.DecodeString(file.Content)
+				f.branch(changeFiles.Branch)[file.Path] = string(content)
+			}
+		}
+		f.commit(changeFiles.Branch)
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodGet && path == "/pulls":
+		respond(w, true, f.pullRequests)
+	case r.Method == http.MethodPost && path == "/pulls":
+		var body map[string]string
+		decode(f.t, r, &body)
+		pr := giteaPullRequest{
+			Number:  len(f.pullRequests) + 1,
+			Title:   body["title"],
+			HTMLURL: fmt.Sprintf("https://gitea.example.com/owner/repo/pulls/%d", len(f.pullRequests)+1),
+			Head:    giteaBranchRef{Ref: body["head"]},
+			Base:    giteaBranchRef{Ref: body["base"]},
+		}
+		f.pullRequests = append(f.pullRequests, pr)
+		respond(w, true, pr)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/merge"):
+		id, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/pulls/"), "/merge"))
+		f.mergeOptions = nil
+		decode(f.t, r, &f.mergeOptions)
+		if f.mergeOptions["merge_when_checks_succeed"] == true {
+			respond(w, true, map[string]string{})
+			return
+		} else if f.pendingChecks {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		f.pullRequests[id-1].State, f.pullRequests[id-1].Merged, f.pullRequests[id-1].MergeCommitSHA = "closed", true, "merge-sha"
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/pulls/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/pulls/"))
+		respond(w, true, f.pullRequests[id-1])
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/pulls/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/pulls/"))
+		var body map[string]string
+		decode(f.t, r, &body)
+		f.pullRequests[id-1].Title = body["title"]
+		respond(w, true, f.pullRequests[id-1])
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func Test_getGiteaApiUrlOwnerRepository(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		wantApiURL     string
+		wantOwner      string
+		wantRepository string
+		wantErr        bool
+	}{
+		{
+			name:           "root installation",
+			raw:            "https://gitea.example.com/owner/repo.git",
+			wantApiURL:     "https://gitea.example.com/api/v1",
+			wantOwner:      "owner",
+			wantRepository: "repo",
+		},
+		{
+			name:           "installation with sub path",
+			raw:            "http://example.com/gitea/owner/repo",
+			wantApiURL:     "http://example.com/gitea/api/v1",
+			wantOwner:      "owner",
+			wantRepository: "repo",
+		},
+		{
+			name:    "missing repository",
+			raw:     "https://gitea.example.com/owner",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotApiURL, gotOwner, gotRepository, err := getGiteaApiUrlOwnerRepository(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getGiteaApiUrlOwnerRepository() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotApiURL != tt.wantApiURL || gotOwner != tt.wantOwner || gotRepository != tt.wantRepository {
+				t.Errorf("getGiteaApiUrlOwnerRepository() = %v, %v, %v, want %v, %v, %v", gotApiURL, gotOwner, gotRepository, tt.wantApiURL, tt.wantOwner, tt.wantRepository)
+			}
+		})
+	}
+}
+
+func TestGiteaClient(t *testing.T) {
+	fake := &fakeGitea{fakeRepository: newFakeRepository(t, map[string]string{
+		"dev/values.yaml":           "tag: 1.0.1",
+		"staging/values.yaml":       "tag: 1.0.0",
+		"staging/old.yaml":          "old: true",
+		"staging/templates/cm.yaml": "kind: ConfigMap",
+	})}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := newGiteaClient(nil, "token", server.URL+"/gitea/owner/repo", nil)
+	if err != nil {
+		t.Fatalf("newGiteaClient() error = %v", err)
+	}
+	if branch, err := client.DefaultBranch(); err != nil || branch != "main" {
+		t.Fatalf("DefaultBranch() = %s, %v, want main", branch, err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Fatalf("BranchExists() = %v, %v, want false", exists, err)
+	}
+	if err := client.CreateBranch("main", "promote/dev_staging"); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	current, err := client.GetFilesForBranch("promote/dev_staging", "staging")
+	if err != nil {
+		t.Fatalf("GetFilesForBranch() error = %v", err)
+	}
+	if want := []string{"staging/old.yaml", "staging/templates/cm.yaml", "staging/values.yaml"}; !reflect.DeepEqual(filePaths(current), want) {
+		t.Errorf("GetFilesForBranch() = %v, want %v", filePaths(current), want)
+	}
+	if missing, err := client.GetFilesForBranch("main", "prod"); err != nil || len(missing) != 0 {
+		t.Errorf("GetFilesForBranch() for missing path = %v, %v", missing, err)
+	}
+
+	changes, err := client.SyncFilesWithBranch("promote/dev_staging", current, []RepositoryFile{
+		{Path: "staging/values.yaml", Content: "tag: 1.0.1"},
+		{Path: "staging/templates/cm.yaml", Content: "kind: ConfigMap"},
+		{Path: "staging/new.yaml", Content: "new: true"},
+	})
+	if err != nil {
+		t.Fatalf("SyncFilesWithBranch() error = %v", err)
+	}
+	wantFiles := map[string]string{
+		"dev/values.yaml":           "tag: 1.0.1",
+		"staging/values.yaml":       "tag: 1.0.1",
+		"staging/new.yaml":          "new: true",
+		"staging/templates/cm.yaml": "kind: ConfigMap",
+	}
+	if changes != 3 || fake.commits != 1 || !reflect.DeepEqual(fake.branch("promote/dev_staging"), wantFiles) {
+		t.Errorf("SyncFilesWithBranch() changes = %d, commits = %d, branch content = %v, want 3 changes in 1 commit and %v", changes, fake.commits, fake.branch("promote/dev_staging"), wantFiles)
+	}
+	if newCommits, err := client.CheckForNewCommits("main", "promote/dev_staging"); err != nil || !newCommits {
+		t.Errorf("CheckForNewCommits() = %v, %v, want true", newCommits, err)
+	}
+
+	head, err := client.GetBranchHead("promote/dev_staging")
+	if err != nil || head != fake.headID("promote/dev_staging") {
+		t.Fatalf("GetBranchHead() = %s, %v", head, err)
+	}
+	direct := []RepositoryFile{{Path: "staging/direct.yaml", Content: "direct: true"}}
+	if _, err := client.SyncFilesWithBranchAt("promote/dev_staging", head, nil, direct); err == nil || fake.branch("promote/dev_staging")["staging/direct.yaml"] != "" {
+		t.Errorf("SyncFilesWithBranchAt() error = %v, want error without commit", err)
+	}
+
+	pr, err := client.CreatePullRequest("promote/dev_staging", "main", "keptn: title", "body")
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 1 || pr.URL != "https://gitea.example.com/owner/repo/pulls/1" {
+		t.Errorf("CreatePullRequest() = %+v", pr)
+	}
+	if other, err := client.GetOpenPullRequest("promote/dev_staging", "develop"); err != nil || other != nil {
+		t.Errorf("GetOpenPullRequest() for other base = %+v, %v, want nil", other, err)
+	}
+	open, err := client.GetOpenPullRequest("promote/dev_staging", "main")
+	if err != nil || open == nil || open.Number != pr.Number {
+		t.Fatalf("GetOpenPullRequest() = %+v, %v", open, err)
+	}
+	if err := client.EditPullRequest(open, "keptn: new title", "new body"); err != nil {
+		t.Fatalf("EditPullRequest() error = %v", err)
+	}
+	if fake.pullRequests[0].Title != "keptn: new title" {
+		t.Errorf("EditPullRequest() did not update pull request: %+v", fake.pullRequests[0])
+	}
+	for _, merged := range []bool{false, true} {
+		fake.pullRequests[0].State, fake.pullRequests[0].Merged = "closed", merged
+		want := map[bool]PullRequestState{false: PullRequestClosed, true: PullRequestMerged}[merged]
+		if got, err := client.GetPullRequestState(pr.Number); err != nil || got != want {
+			t.Errorf("GetPullRequestState() with merged %v = %v, %v, want %v", merged, got, err, want)
+		}
+	}
+	fake.pullRequests[0].State, fake.pullRequests[0].Merged, fake.pendingChecks = "open", false, true
+	if err := client.MergePullRequest(pr, "squash"); err != nil || pr.Merged || fake.mergeOptions["merge_when_checks_succeed"] != true {
+		t.Errorf("MergePullRequest() with pending checks = %v, merged %v, options %v", err, pr.Merged, fake.mergeOptions)
+	}
+	fake.pendingChecks = false
+	if err := client.MergePullRequest(pr, "squash"); err != nil || !pr.Merged || pr.MergeCommitSHA != "merge-sha" || fake.mergeOptions["Do"] != "squash" {
+		t.Errorf("MergePullRequest() = %v, merged %v with %s, options %v", err, pr.Merged, pr.MergeCommitSHA, fake.mergeOptions)
+	}
+	if err := client.DeleteBranch("promote/dev_staging"); err != nil {
+		t.Fatalf("DeleteBranch() error = %v", err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Errorf("BranchExists() after delete = %v, %v, want false", exists, err)
+	}
+}
+
+func TestGiteaClient_Tags(t *testing.T) {
+	fake := &fakeGitea{fakeRepository: newFakeRepository(t, map[string]string{"dev/values.yaml": "tag: 1.0.1"})}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client, err := newGiteaClient(nil, "token", server.URL+"/gitea/owner/repo", nil)
+	if err != nil {
+		t.Fatalf("newGiteaClient() error = %v", err)
+	}
+	testTagClient(t, client, &fake.fakeRepository)
+}
@@ -0,0 +1,86 @@
+package repoaccess
+
+import (
+	"context"
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const githubHost = "github.com"
+
+type githubClient struct {
+	owner      string
+	repository string
+	context    context.Context
+	client     *github.Client
+}
+
+func newGithubClient(httpClient *http.Client, credentials Credentials, repositoryUrl string, apiURL *string) (client *githubClient, err error) {
+	ctx := context.Background()
+	if httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	}
+	baseURL, uploadURL, err := getGithubApiUrls(repositoryUrl, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	var ts oauth2.TokenSource
+	if credentials.GithubApp != nil {
+		if ts, err = newGithubAppTokenSource(httpClient, *credentials.GithubApp, baseURL); err != nil {
+			return nil, err
+		}
+	} else {
+		ts = oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: credentials.AccessToken},
+		)
+	}
+	tc := oauth2.NewClient(ctx, ts)
+
+	client = &githubClient{
+		context: ctx,
+	}
+	if baseURL == "" {
+		client.client = github.NewClient(tc)
+	} else if client.client, err = github.NewEnterpriseClient(baseURL, uploadURL, tc); err != nil {
+		return nil, err
+	}
+	if owner, repo, err := getGithubOwnerRepository(repositoryUrl); err != nil {
+		return nil, err
+	} else {
+		client.owner = owner
+		client.repository = repo
+		return client, nil
+	}
+}
+
+func getGithubOwnerRepository(raw string) (owner, repository string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return owner, repository, err
+	}
+	splittedUrl := strings.Split(u.Path, "/")
+	return splittedUrl[1], splittedUrl[2], nil
+}
+
+// getGithubApiUrls returns empty urls for repositories on github.com. For GitHub Enterprise Server the urls are
+// derived from the host of the repository url (https://<host>/api/v3/) unless apiURL is set explicitly.
+func getGithubApiUrls(repositoryUrl string, apiURL *string) (baseURL, uploadURL string, err error) {
+	if apiURL != nil && *apiURL != "" {
+		baseURL = strings.TrimSuffix(*apiURL, "/") + "/"
+		if strings.HasSuffix(baseURL, "/api/v3/") {
+			return baseURL, strings.TrimSuffix(baseURL, "v3/") + "uploads/", nil
+		}
+		return baseURL, baseURL, nil
+	}
+	u, err := url.Parse(repositoryUrl)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Host == githubHost {
+		return "", "", nil
+	}
+	return u.Scheme + "://" + u.Host + "/api/v3/", u.Scheme + "://" + u.Host + "/api/uploads/", nil
+}
@@ -0,0 +1,124 @@
+package repoaccess
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const githubApiURL = "https://api.github.com/"
+
+// GithubAppCredentials are used to mint installation tokens of a GitHub App instead of using a static access token
+type GithubAppCredentials struct {
+	AppID          int64
+	InstallationID int64
+	// PrivateKey is the PEM encoded private key of the app
+	PrivateKey []byte
+}
+
+// githubAppTokenSource mints a new installation access token for every call of Token, use oauth2.ReuseTokenSource to
+// only refresh the token after it expired
+type githubAppTokenSource struct {
+	appID          int64
+	installationID int64
+	privateKey     *rsa.PrivateKey
+	rest           restClient
+}
+
+type githubInstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newGithubAppTokenSource(httpClient *http.Client, credentials GithubAppCredentials, baseURL string) (oauth2.TokenSource, error) {
+	privateKey, err := parseRSAPrivateKey(credentials.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		baseURL = githubApiURL
+	}
+	return oauth2.ReuseTokenSource(nil, &githubAppTokenSource{
+		appID:          credentials.AppID,
+		installationID: credentials.InstallationID,
+		privateKey:     privateKey,
+		rest: restClient{
+			baseURL:    strings.TrimSuffix(baseURL, "/"),
+			httpClient: httpClient,
+		},
+	}), nil
+}
+
+func (s *githubAppTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.createJWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	rest := s.rest
+	rest.headers = map[string]string{
+		"Authorization": "Bearer " + jwt,
+		"Accept":        "application/vnd.github.v3+json",
+	}
+	var token githubInstallationToken
+	if err := rest.do(http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", s.installationID), nil, nil, &token); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: token.Token,
+		TokenType:   "token",
+		Expiry:      token.ExpiresAt,
+	}, nil
+}
+
+// createJWT creates the RS256 signed token used to authenticate as the app
+func (s *githubAppTokenSource) createJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		// issued in the past to allow for clock drift
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return rsaKey, nil
+	}
+	return nil, errors.New("private key is not a RSA key")
+}
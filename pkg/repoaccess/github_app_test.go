@@ -0,0 +1,151 @@
+package repoaccess
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGithubApp mints installation tokens for installation 42 of app 7 and serves the branch endpoint of owner/repo
+type fakeGithubApp struct {
+	publicKey *rsa.PublicKey
+	expiresIn time.Duration
+	minted    int
+}
+
+func (f *fakeGithubApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v3/app/installations/42/access_tokens":
+		if !f.verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.minted++
+		respond(w, true, githubInstallationToken{Token: "installation-token", ExpiresAt: time.Now().Add(f.expiresIn)})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/owner/repo/branches/main":
+		respond(w, r.Header.Get("Authorization") == "token installation-token", map[string]string{"name": "main"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeGithubApp) verify(jwt string) bool {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.publicKey, crypto.SHA256, hash[:], signature); err != nil {
+		return false
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var c map[string]int64
+	if err := json.Unmarshal(claims, &c); err != nil {
+		return false
+	}
+	return c["iss"] == 7 && c["exp"] > time.Now().Unix()
+}
+
+func newTestPrivateKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestGithubClient_GithubApp(t *testing.T) {
+	key, privateKey := newTestPrivateKey(t)
+	tests := []struct {
+		name       string
+		expiresIn  time.Duration
+		wantMinted int
+	}{
+		{
+			name:       "token is reused until it expires",
+			expiresIn:  time.Hour,
+			wantMinted: 1,
+		},
+		{
+			name:       "expired token is refreshed",
+			expiresIn:  time.Second,
+			wantMinted: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGithubApp{publicKey: &key.PublicKey, expiresIn: tt.expiresIn}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			apiURL := server.URL + "/api/v3"
+			client, err := newGithubClient(nil, Credentials{GithubApp: &GithubAppCredentials{AppID: 7, InstallationID: 42, PrivateKey: privateKey}}, server.URL+"/owner/repo", &apiURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				if exists, err := client.BranchExists("main"); err != nil || !exists {
+					t.Fatalf("BranchExists() = %v, %v", exists, err)
+				}
+			}
+			if fake.minted != tt.wantMinted {
+				t.Errorf("minted %d tokens, want %d", fake.minted, tt.wantMinted)
+			}
+		})
+	}
+}
+
+func Test_parseRSAPrivateKey(t *testing.T) {
+	key, pkcs1 := newTestPrivateKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{
+			name: "pkcs1",
+			data: pkcs1,
+		},
+		{
+			name: "pkcs8",
+			data: pkcs8,
+		},
+		{
+			name:    "not pem",
+			data:    []byte("not a key"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRSAPrivateKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRSAPrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(key) {
+				t.Errorf("parseRSAPrivateKey() returned a different key")
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package repoaccess
+
+import (
+	"fmt"
+	"github.com/google/go-github/github"
+)
+
+func (c *githubClient) BranchExists(branchName string) (exists bool, err error) {
+	if branch, resp, err := c.client.Repositories.GetBranch(c.context, c.owner, c.repository, branchName); err != nil && resp.StatusCode != 404 {
+		return false, err
+	} else if branch == nil {
+		return false, nil
+	} else {
+		return true, nil
+	}
+}
+
+func (c *githubClient) CreateBranch(sourceBranch, targetBranch string) (err error) {
+	branch, _, err := c.client.Repositories.GetBranch(c.context, c.owner, c.repository, sourceBranch)
+	if err != nil {
+		return err
+	}
+	_, _, err = c.client.Git.CreateRef(c.context, c.owner, c.repository, &github.Reference{
+		Ref: github.String(fmt.Sprintf("refs/heads/%s", targetBranch)),
+		Object: &github.GitObject{
+			SHA: branch.Commit.SHA,
+		},
+	})
+	return err
+}
+
+func (c *githubClient) DeleteBranch(branch string) (err error) {
+	if _, err := c.client.Git.DeleteRef(c.context, c.owner, c.repository, fmt.Sprintf("refs/heads/%s", branch)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *githubClient) DefaultBranch() (branch string, err error) {
+	repository, _, err := c.client.Repositories.Get(c.context, c.owner, c.repository)
+	if err != nil {
+		return "", err
+	}
+	return repository.GetDefaultBranch(), nil
+}
@@ -0,0 +1,242 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/go-github/github"
+	logger "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+func (c *githubClient) CheckForNewCommits(toBranch, fromBranch string) (newCommits bool, err error) {
+	compare, _, err := c.client.Repositories.CompareCommits(c.context, c.owner, c.repository, toBranch, fromBranch)
+	if err != nil {
+		return false, err
+	}
+	logger.WithField("func", "CheckForNewCommits").Infof("found %d commits in github repo %s/%s from branch %s to %s", len(compare.Commits), c.owner, c.repository, fromBranch, toBranch)
+	if len(compare.Commits) == 0 {
+		return false, nil
+	} else {
+		return true, nil
+	}
+}
+
+// githubBlobDownloads limits the number of parallel blob downloads
+const githubBlobDownloads = 8
+
+// GetFilesForBranch reads the tree of the branch with one request and downloads the blobs below path in parallel
+func (c *githubClient) GetFilesForBranch(branch, path string) (files []RepositoryFile, err error) {
+	logger.WithField("func", "GetFilesForBranch").Infof("starting with branch %s and path %s", branch, path)
+	b, resp, err := c.client.Repositories.GetBranch(c.context, c.owner, c.repository, branch)
+	if err != nil && resp != nil && resp.StatusCode == 404 {
+		return files, nil
+	} else if err != nil {
+		return files, err
+	}
+	path = strings.Trim(path, "/")
+	entries, err := c.getTreeEntries(b.GetCommit().GetCommit().GetTree().GetSHA(), path)
+	if err != nil {
+		return files, err
+	}
+	for _, e := range entries {
+		// submodules (type commit), directories and symlinks are skipped
+		if e.GetType() != "blob" || e.GetMode() == "120000" {
+			continue
+		}
+		if path == "" || e.GetPath() == path || strings.HasPrefix(e.GetPath(), path+"/") {
+			file := RepositoryFile{Path: e.GetPath(), SHA: e.GetSHA()}
+			if e.GetMode() != regularFileMode {
+				file.Mode = e.GetMode()
+			}
+			files = append(files, file)
+		}
+	}
+	if err := c.downloadBlobs(files); err != nil {
+		return nil, err
+	}
+	logger.WithField("func", "GetFilesForBranch").Infof("found %d files in branch %s and path %s", len(files), branch, path)
+	return files, nil
+}
+
+// getTreeEntries returns all entries of the tree recursively. If the tree is too large to be returned by github, only
+// the sub tree of path is read.
+func (c *githubClient) getTreeEntries(treeSHA, path string) (entries []github.TreeEntry, err error) {
+	tree, _, err := c.client.Git.GetTree(c.context, c.owner, c.repository, treeSHA, true)
+	if err != nil {
+		return nil, err
+	}
+	if !tree.GetTruncated() {
+		return tree.Entries, nil
+	}
+	if path == "" {
+		return nil, errors.New(fmt.Sprintf("tree of repository %s/%s is too large", c.owner, c.repository))
+	}
+	logger.WithField("func", "getTreeEntries").Infof("tree of repository %s/%s is truncated, reading sub tree %s", c.owner, c.repository, path)
+	dir := ""
+	for _, segment := range strings.Split(path, "/") {
+		tree, _, err := c.client.Git.GetTree(c.context, c.owner, c.repository, treeSHA, false)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, e := range tree.Entries {
+			if e.GetPath() != segment {
+				continue
+			}
+			found = true
+			if e.GetType() != "tree" {
+				// path is a file
+				e.Path = github.String(dir + segment)
+				return []github.TreeEntry{e}, nil
+			}
+			treeSHA = e.GetSHA()
+		}
+		if !found {
+			return nil, nil
+		}
+		dir += segment + "/"
+	}
+	tree, _, err = c.client.Git.GetTree(c.context, c.owner, c.repository, treeSHA, true)
+	if err != nil {
+		return nil, err
+	}
+	if tree.GetTruncated() {
+		return nil, errors.New(fmt.Sprintf("tree %s of repository %s/%s is too large", path, c.owner, c.repository))
+	}
+	for _, e := range tree.Entries {
+		e.Path = github.String(dir + e.GetPath())
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// downloadBlobs sets the content of all files using at most githubBlobDownloads parallel requests
+func (c *githubClient) downloadBlobs(files []RepositoryFile) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(files))
+	semaphore := make(chan struct{}, githubBlobDownloads)
+	for i := range files {
+		wg.Add(1)
+		go func(file *RepositoryFile) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			content, _, err := c.client.Git.GetBlobRaw(c.context, c.owner, c.repository, file.SHA)
+			if err != nil {
+				errs <- err
+				return
+			}
+			file.Content = string(content)
+		}(&files[i])
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// githubTreeEntry is used instead of github.TreeEntry because deleting a file needs an explicit "sha": null
+type githubTreeEntry struct {
+	Path string  `json:"path"`
+	Mode string  `json:"mode"`
+	Type string  `json:"type"`
+	SHA  *string `json:"sha"`
+}
+
+// SyncFilesWithBranch creates all changes with a single commit (blobs, tree, commit and ref update). The ref is only
+// moved if it still points to the commit the changes are based on, so a failure leaves the branch untouched.
+func (c *githubClient) SyncFilesWithBranch(branch string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	head, err := c.GetBranchHead(branch)
+	if err != nil {
+		return 0, err
+	}
+	return c.SyncFilesWithBranchAt(branch, head, currentTargetFiles, newTargetFiles)
+}
+
+func (c *githubClient) GetBranchHead(branch string) (commit string, err error) {
+	ref, _, err := c.client.Git.GetRef(c.context, c.owner, c.repository, "heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	return ref.Object.GetSHA(), nil
+}
+
+// SyncFilesWithBranchAt creates the commit with head as parent, github rejects the ref update (no force) if the branch
+// was moved in the meantime because the commit is no fast forward anymore
+func (c *githubClient) SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	logger.WithField("func", "SyncfilesWithBranch").Infof("starting for branch %s at %s and %d currentTargetFiles and %d newTargetFiles", branch, head, len(currentTargetFiles), len(newTargetFiles))
+	fileChanges := diffRepositoryFiles(currentTargetFiles, newTargetFiles)
+	if len(fileChanges) == 0 {
+		logger.WithField("func", "SyncfilesWithBranch").Infof("no changes detected for branch %s", branch)
+		return 0, nil
+	}
+	parent, _, err := c.client.Git.GetCommit(c.context, c.owner, c.repository, head)
+	if err != nil {
+		return 0, err
+	}
+	entries := make([]githubTreeEntry, 0, len(fileChanges))
+	for _, change := range fileChanges {
+		entry := githubTreeEntry{Path: change.Path, Mode: change.Mode, Type: "blob"}
+		if change.Content == nil {
+			logger.WithField("func", "SyncfilesWithBranch").Infof("deleting file %s in branch %s", change.Path, branch)
+			entry.Mode = change.Current.fileMode()
+		} else {
+			logger.WithField("func", "SyncfilesWithBranch").Infof("writing file %s in branch %s", change.Path, branch)
+			// base64 keeps binary content intact, json would replace invalid UTF-8
+			blob, _, err := c.client.Git.CreateBlob(c.context, c.owner, c.repository, &github.Blob{
+				Content:  github.String(base64.StdEncoding.EncodeToString([]byte(*change.Content))),
+				Encoding: github.String("base64"),
+			})
+			if err != nil {
+				return 0, err
+			}
+			entry.SHA = blob.SHA
+		}
+		entries = append(entries, entry)
+	}
+	tree, err := c.createTree(parent.Tree.GetSHA(), entries)
+	if err != nil {
+		return 0, err
+	}
+	author := &github.CommitAuthor{
+		Name:  github.String("github-actions"),
+		Email: github.String("github-actions&github.com"),
+	}
+	commit, _, err := c.client.Git.CreateCommit(c.context, c.owner, c.repository, &github.Commit{
+		Message:   github.String("(build) sync files"),
+		Tree:      tree,
+		Parents:   []github.Commit{{SHA: parent.SHA}},
+		Author:    author,
+		Committer: author,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, resp, err := c.client.Git.UpdateRef(c.context, c.owner, c.repository, &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: commit.SHA},
+	}, false); err != nil && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+		return 0, ErrBranchMoved
+	} else if err != nil {
+		return 0, err
+	}
+	logger.WithField("func", "SyncfilesWithBranch").Infof("committed %d changes to branch %s with commit %s", len(fileChanges), branch, commit.GetSHA())
+	return len(fileChanges), nil
+}
+
+func (c *githubClient) createTree(baseTree string, entries []githubTreeEntry) (*github.Tree, error) {
+	req, err := c.client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%s/%s/git/trees", c.owner, c.repository), map[string]interface{}{
+		"base_tree": baseTree,
+		"tree":      entries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tree := &github.Tree{}
+	if _, err := c.client.Do(c.context, req, tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
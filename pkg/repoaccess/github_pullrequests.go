@@ -0,0 +1,120 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// githubEnableAutoMerge enables the auto-merge of a pull request, only available with the graphql api
+const githubEnableAutoMerge = `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+}`
+
+func (c *githubClient) GetOpenPullRequest(fromBranch, toBranch string) (pr *PullRequest, err error) {
+	prs, _, err := c.client.PullRequests.List(c.context, c.owner, c.repository, &github.PullRequestListOptions{
+		Head: fromBranch,
+		Base: toBranch,
+	})
+	if err != nil {
+		return pr, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	pr = &PullRequest{
+		Number: *prs[0].Number,
+		Title:  *prs[0].Title,
+		URL:    *prs[0].HTMLURL,
+	}
+	return pr, nil
+}
+
+func (c *githubClient) EditPullRequest(pr *PullRequest, title, body string) error {
+	if _, _, err := c.client.PullRequests.Edit(c.context, c.owner, c.repository, pr.Number, &github.PullRequest{
+		Title: &title,
+		Body:  &body,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *githubClient) CreatePullRequest(fromBranch, toBranch, title, body string) (pr *PullRequest, err error) {
+	ghpr, _, err := c.client.PullRequests.Create(c.context, c.owner, c.repository, &github.NewPullRequest{
+		Title: &title,
+		Head:  &fromBranch,
+		Base:  &toBranch,
+		Body:  &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pr = &PullRequest{
+		Number: *ghpr.Number,
+		Title:  *ghpr.Title,
+		URL:    *ghpr.HTMLURL,
+	}
+	return pr, nil
+}
+
+func (c *githubClient) GetPullRequestState(number int) (state PullRequestState, err error) {
+	pr, _, err := c.client.PullRequests.Get(c.context, c.owner, c.repository, number)
+	if err != nil {
+		return "", err
+	}
+	if pr.GetMerged() {
+		return PullRequestMerged, nil
+	} else if pr.GetState() == "closed" {
+		return PullRequestClosed, nil
+	}
+	return PullRequestOpen, nil
+}
+
+func (c *githubClient) MergePullRequest(pr *PullRequest, method string) error {
+	result, resp, err := c.client.PullRequests.Merge(c.context, c.owner, c.repository, pr.Number, "", &github.PullRequestOptions{MergeMethod: method})
+	if err != nil && resp != nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		// required status checks or reviews are missing
+		return c.enableAutoMerge(pr, method)
+	} else if err != nil {
+		return err
+	}
+	pr.Merged, pr.MergeCommitSHA = true, result.GetSHA()
+	return nil
+}
+
+func (c *githubClient) enableAutoMerge(pr *PullRequest, method string) error {
+	ghpr, _, err := c.client.PullRequests.Get(c.context, c.owner, c.repository, pr.Number)
+	if err != nil {
+		return err
+	}
+	req, err := c.client.NewRequest(http.MethodPost, c.graphqlPath(), map[string]interface{}{
+		"query":     githubEnableAutoMerge,
+		"variables": map[string]string{"id": ghpr.GetNodeID(), "method": strings.ToUpper(method)},
+	})
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.client.Do(c.context, req, &response); err != nil {
+		return err
+	} else if len(response.Errors) > 0 {
+		return errors.New(fmt.Sprintf("enabling auto-merge of pull request %d failed: %s", pr.Number, response.Errors[0].Message))
+	}
+	return nil
+}
+
+// graphqlPath returns the graphql endpoint relative to the rest api, GitHub Enterprise Server serves it on /api/graphql
+func (c *githubClient) graphqlPath() string {
+	if strings.HasSuffix(c.client.BaseURL.Path, "/v3/") {
+		return "../graphql"
+	}
+	return "graphql"
+}
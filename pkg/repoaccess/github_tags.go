@@ -0,0 +1,71 @@
+package repoaccess
+
+import (
+	"github.com/google/go-github/github"
+	"net/http"
+)
+
+func (c *githubClient) GetTag(name string) (tag *Tag, err error) {
+	ref, resp, err := c.client.Git.GetRef(c.context, c.owner, c.repository, "tags/"+name)
+	if err != nil && resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusOK) {
+		// 200 with an error is a list of tags starting with name, so there is no exact match
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if ref.Object.GetType() != "tag" {
+		// lightweight tag
+		return &Tag{Name: name, Commit: ref.Object.GetSHA()}, nil
+	}
+	annotated, _, err := c.client.Git.GetTag(c.context, c.owner, c.repository, ref.Object.GetSHA())
+	if err != nil {
+		return nil, err
+	}
+	return &Tag{Name: name, Commit: annotated.Object.GetSHA()}, nil
+}
+
+func (c *githubClient) ResolveCommit(ref string) (commit string, err error) {
+	commit, resp, err := c.client.Repositories.GetCommitSHA1(c.context, c.owner, c.repository, ref, "")
+	if err != nil && resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity) {
+		return "", nil
+	}
+	return commit, err
+}
+
+func (c *githubClient) IsAncestor(ancestor, commit string) (isAncestor bool, err error) {
+	comparison, _, err := c.client.Repositories.CompareCommits(c.context, c.owner, c.repository, ancestor, commit)
+	if err != nil {
+		return false, err
+	}
+	return comparison.GetStatus() == "ahead" || comparison.GetStatus() == "identical", nil
+}
+
+// CreateTag creates the tag object and points the ref to it. Creating the ref fails if the tag exists, an existing tag
+// is read again right before its ref is moved, the refs api can't compare and swap the ref itself.
+func (c *githubClient) CreateTag(name, commit, message string, previous *Tag) (err error) {
+	tag, _, err := c.client.Git.CreateTag(c.context, c.owner, c.repository, &github.Tag{
+		Tag:     github.String(name),
+		Message: github.String(message),
+		Object:  &github.GitObject{Type: github.String("commit"), SHA: github.String(commit)},
+	})
+	if err != nil {
+		return err
+	}
+	ref := &github.Reference{Ref: github.String("refs/tags/" + name), Object: &github.GitObject{SHA: tag.SHA}}
+	if previous == nil {
+		if _, resp, err := c.client.Git.CreateRef(c.context, c.owner, c.repository, ref); err != nil && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+			// the tag was created in the meantime
+			return ErrTagMoved
+		} else if err != nil {
+			return err
+		}
+		return nil
+	}
+	if current, err := c.GetTag(name); err != nil {
+		return err
+	} else if current == nil || current.Commit != previous.Commit {
+		return ErrTagMoved
+	}
+	_, _, err = c.client.Git.UpdateRef(c.context, c.owner, c.repository, ref, true)
+	return err
+}
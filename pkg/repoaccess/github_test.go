@@ -0,0 +1,708 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_getGithubOwnerRepository(t *testing.T) {
+	type args struct {
+		raw string
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantOwner      string
+		wantRepository string
+		wantErr        bool
+	}{
+		{
+			name: "testsunshine",
+			args: args{
+				raw: "https://github.com/markuslackner/keptn-argo-dev",
+			},
+			wantOwner:      "markuslackner",
+			wantRepository: "keptn-argo-dev",
+			wantErr:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOwner, gotRepository, err := getGithubOwnerRepository(tt.args.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getGithubOwnerRepository() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotOwner != tt.wantOwner {
+				t.Errorf("getGithubOwnerRepository() gotOwner = %v, want %v", gotOwner, tt.wantOwner)
+			}
+			if gotRepository != tt.wantRepository {
+				t.Errorf("getGithubOwnerRepository() gotRepository = %v, want %v", gotRepository, tt.wantRepository)
+			}
+		})
+	}
+}
+
+func Test_getGithubApiUrls(t *testing.T) {
+	tests := []struct {
+		name          string
+		repositoryUrl string
+		apiURL        *string
+		wantBaseURL   string
+		wantUploadURL string
+	}{
+		{
+			name:          "github.com",
+			repositoryUrl: "https://github.com/markuslackner/keptn-argo-dev",
+		},
+		{
+			name:          "enterprise server derived from repository host",
+			repositoryUrl: "https://github.example.com/markuslackner/keptn-argo-dev",
+			wantBaseURL:   "https://github.example.com/api/v3/",
+			wantUploadURL: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:          "explicit api url",
+			repositoryUrl: "https://github.example.com/markuslackner/keptn-argo-dev",
+			apiURL:        strptr("https://api.github.example.com/api/v3"),
+			wantBaseURL:   "https://api.github.example.com/api/v3/",
+			wantUploadURL: "https://api.github.example.com/api/uploads/",
+		},
+		{
+			name:          "explicit api url without v3 suffix",
+			repositoryUrl: "https://github.example.com/markuslackner/keptn-argo-dev",
+			apiURL:        strptr("https://github-api.example.com/"),
+			wantBaseURL:   "https://github-api.example.com/",
+			wantUploadURL: "https://github-api.example.com/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBaseURL, gotUploadURL, err := getGithubApiUrls(tt.repositoryUrl, tt.apiURL)
+			if err != nil {
+				t.Errorf("getGithubApiUrls() error = %v", err)
+				return
+			}
+			if gotBaseURL != tt.wantBaseURL {
+				t.Errorf("getGithubApiUrls() gotBaseURL = %v, want %v", gotBaseURL, tt.wantBaseURL)
+			}
+			if gotUploadURL != tt.wantUploadURL {
+				t.Errorf("getGithubApiUrls() gotUploadURL = %v, want %v", gotUploadURL, tt.wantUploadURL)
+			}
+		})
+	}
+}
+
+// fakeGithubGitData is a minimal in-memory stand-in for the git data api of repository owner/repo
+type fakeGithubGitData struct {
+	fakeRepository
+	commitFiles map[string]map[string]string
+	parents     map[string]string
+	trees       map[string]map[string]string
+	blobs       map[string]string
+	refs        map[string]string
+	// modes are the file modes of files which are not regular files in all trees
+	modes     map[string]string
+	failTrees bool
+	// truncateTrees simulates a repository too large for recursive tree requests
+	truncateTrees bool
+	blobRequests  int
+	// mu serializes the parallel blob downloads
+	mu sync.Mutex
+	// beforeRefUpdate simulates a concurrent push
+	beforeRefUpdate func()
+}
+
+func newFakeGithubGitData(t *testing.T, files map[string]string) *fakeGithubGitData {
+	return &fakeGithubGitData{
+		fakeRepository: newFakeRepository(t, files),
+		commitFiles:    map[string]map[string]string{"c0": files},
+		parents:        map[string]string{},
+		trees:          map[string]map[string]string{"tree-c0": files},
+		blobs:          map[string]string{},
+		refs:           map[string]string{"main": "c0"},
+		modes:          map[string]string{},
+	}
+}
+
+func (f *fakeGithubGitData) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	const prefix = "/api/v3/repos/owner/repo/git"
+	if r.URL.Path == "/api/v3/repos/owner/repo" {
+		respond(w, true, map[string]string{"default_branch": "main"})
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/v3/repos/owner/repo/branches/") {
+		sha, ok := f.refs[strings.TrimPrefix(r.URL.Path, "/api/v3/repos/owner/repo/branches/")]
+		respond(w, ok, map[string]interface{}{"commit": map[string]interface{}{"sha": sha, "commit": map[string]interface{}{"tree": map[string]string{"sha": "tree-" + sha}}}})
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/trees/"):
+		f.listTree(w, strings.TrimPrefix(path, "/trees/"), r.URL.Query().Get("recursive") == "1")
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/blobs/"):
+		f.blobRequests++
+		content, ok := f.blobs[strings.TrimPrefix(path, "/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/refs/heads/"):
+		sha, ok := f.refs[strings.TrimPrefix(path, "/refs/heads/")]
+		respond(w, ok, map[string]interface{}{"ref": "refs/" + strings.TrimPrefix(path, "/refs/"), "object": map[string]string{"sha": sha}})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/commits/"):
+		sha := strings.TrimPrefix(path, "/commits/")
+		_, ok := f.commitFiles[sha]
+		respond(w, ok, map[string]interface{}{"sha": sha, "tree": map[string]string{"sha": "tree-" + sha}})
+	case r.Method == http.MethodPost && path == "/blobs":
+		var body map[string]string
+		decode(f.t, r, &body)
+		if body["encoding"] != "base64" {
+			f.t.Errorf("unexpected blob encoding %s", body["encoding"])
+		}
+		content, err := base64.StdEncoding.DecodeString(body["content"])
+		if err != nil {
+			f.t.Errorf("invalid blob content: %s", err)
+		}
+		sha := fmt.Sprintf("blob-%d", len(f.blobs))
+		f.blobs[sha] = string(content)
+		respond(w, true, map[string]string{"sha": sha})
+	case r.Method == http.MethodPost && path == "/trees":
+		if f.failTrees {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var body struct {
+			BaseTree string            `json:"base_tree"`
+			Tree     []json.RawMessage `json:"tree"`
+		}
+		decode(f.t, r, &body)
+		files := map[string]string{}
+		for k, v := range f.trees[body.BaseTree] {
+			files[k] = v
+		}
+		for _, raw := range body.Tree {
+			var entry map[string]*string
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				f.t.Fatal(err)
+			}
+			if _, ok := entry["sha"]; !ok {
+				f.t.Errorf("tree entry %s without sha", raw)
+			}
+			if entry["sha"] == nil {
+				delete(files, *entry["path"])
+			} else {
+				files[*entry["path"]] = f.blobs[*entry["sha"]]
+				f.modes[*entry["path"]] = *entry["mode"]
+			}
+		}
+		sha := fmt.Sprintf("tree-%d", len(f.trees))
+		f.trees[sha] = files
+		respond(w, true, map[string]string{"sha": sha})
+	case r.Method == http.MethodPost && path == "/commits":
+		var body struct {
+			Tree    string   `json:"tree"`
+			Parents []string `json:"parents"`
+		}
+		decode(f.t, r, &body)
+		sha := fmt.Sprintf("c%d", len(f.commitFiles))
+		f.commitFiles[sha] = f.trees[body.Tree]
+		f.trees["tree-"+sha] = f.trees[body.Tree]
+		f.parents[sha] = body.Parents[0]
+		respond(w, true, map[string]string{"sha": sha})
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/refs/heads/"):
+		if f.beforeRefUpdate != nil {
+			f.beforeRefUpdate()
+		}
+		branch := strings.TrimPrefix(path, "/refs/heads/")
+		var body struct {
+			SHA   string `json:"sha"`
+			Force bool   `json:"force"`
+		}
+		decode(f.t, r, &body)
+		if !body.Force && f.parents[body.SHA] != f.refs[branch] {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		f.refs[branch] = body.SHA
+		f.branches[branch] = f.commitFiles[body.SHA]
+		f.commit(branch)
+		respond(w, true, map[string]interface{}{"ref": "refs/heads/" + branch, "object": map[string]string{"sha": body.SHA}})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// listTree serves tree objects, sub trees are addressed by "<tree sha>:<directory>"
+func (f *fakeGithubGitData) listTree(w http.ResponseWriter, sha string, recursive bool) {
+	parts := strings.SplitN(sha, ":", 2)
+	files, ok := f.trees[parts[0]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	dir := ""
+	if len(parts) == 2 {
+		dir = parts[1] + "/"
+	}
+	truncated := f.truncateTrees && recursive && dir == ""
+	var entries []map[string]string
+	seen := map[string]bool{}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if !strings.HasPrefix(p, dir) || truncated {
+			continue
+		}
+		rel := strings.TrimPrefix(p, dir)
+		segments := strings.Split(rel, "/")
+		for i := 1; i < len(segments); i++ {
+			subdir := strings.Join(segments[:i], "/")
+			if !seen[subdir] && (recursive || i == 1) {
+				seen[subdir] = true
+				entries = append(entries, map[string]string{"path": subdir, "type": "tree", "mode": "040000", "sha": parts[0] + ":" + dir + subdir})
+			}
+		}
+		if recursive || len(segments) == 1 {
+			blob := fmt.Sprintf("blob-%d", len(f.blobs))
+			f.blobs[blob] = files[p]
+			mode := "100644"
+			if m, ok := f.modes[p]; ok {
+				mode = m
+			}
+			entries = append(entries, map[string]string{"path": rel, "type": "blob", "mode": mode, "sha": blob})
+		}
+	}
+	respond(w, true, map[string]interface{}{"sha": sha, "tree": entries, "truncated": truncated})
+}
+
+func TestGithubClient_GetFilesForBranch(t *testing.T) {
+	files := map[string]string{
+		"dev/values.yaml":           "tag: 1.0.1\n",
+		"staging/values.yaml":       "tag: 1.0.0\n",
+		"staging/templates/cm.yaml": "kind: ConfigMap\n",
+		"stagingfile.yaml":          "other\n",
+	}
+	tests := []struct {
+		name          string
+		branch        string
+		path          string
+		truncateTrees bool
+		modes         map[string]string
+		want          []RepositoryFile
+	}{
+		{
+			name:   "directory",
+			branch: "main",
+			path:   "/staging",
+			want: []RepositoryFile{
+				{Path: "staging/templates/cm.yaml", Content: "kind: ConfigMap\n"},
+				{Path: "staging/values.yaml", Content: "tag: 1.0.0\n"},
+			},
+		},
+		{
+			name:   "single file",
+			branch: "main",
+			path:   "dev/values.yaml",
+			want:   []RepositoryFile{{Path: "dev/values.yaml", Content: "tag: 1.0.1\n"}},
+		},
+		{
+			name:   "executable files and symlinks",
+			branch: "main",
+			path:   "staging",
+			modes:  map[string]string{"staging/values.yaml": "100755", "staging/templates/cm.yaml": "120000"},
+			want:   []RepositoryFile{{Path: "staging/values.yaml", Content: "tag: 1.0.0\n", Mode: "100755"}},
+		},
+		{
+			name:   "missing path",
+			branch: "main",
+			path:   "prod",
+		},
+		{
+			name:   "missing branch",
+			branch: "promote/dev_staging",
+			path:   "staging",
+		},
+		{
+			name:          "truncated tree",
+			branch:        "main",
+			path:          "staging/",
+			truncateTrees: true,
+			want: []RepositoryFile{
+				{Path: "staging/templates/cm.yaml", Content: "kind: ConfigMap\n"},
+				{Path: "staging/values.yaml", Content: "tag: 1.0.0\n"},
+			},
+		},
+		{
+			name:          "truncated tree with single file",
+			branch:        "main",
+			path:          "staging/templates/cm.yaml",
+			truncateTrees: true,
+			want:          []RepositoryFile{{Path: "staging/templates/cm.yaml", Content: "kind: ConfigMap\n"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeGithubGitData(t, files)
+			fake.truncateTrees = tt.truncateTrees
+			for k, v := range tt.modes {
+				fake.modes[k] = v
+			}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			apiURL := server.URL + "/api/v3"
+			client, err := newGithubClient(nil, Credentials{AccessToken: "token"}, server.URL+"/owner/repo", &apiURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := client.GetFilesForBranch(tt.branch, tt.path)
+			if err != nil {
+				t.Fatalf("GetFilesForBranch() error = %v", err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+			for i := range got {
+				got[i].SHA = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetFilesForBranch() = %v, want %v", got, tt.want)
+			}
+			if fake.blobRequests != len(tt.want) {
+				t.Errorf("%d blobs downloaded, want %d", fake.blobRequests, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGithubClient_DefaultBranch(t *testing.T) {
+	server := httptest.NewServer(newFakeGithubGitData(t, map[string]string{}))
+	defer server.Close()
+	apiURL := server.URL + "/api/v3"
+	client, err := newGithubClient(nil, Credentials{AccessToken: "token"}, server.URL+"/owner/repo", &apiURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch, err := client.DefaultBranch(); err != nil || branch != "main" {
+		t.Errorf("DefaultBranch() = %s, %v, want main", branch, err)
+	}
+}
+
+func TestGithubClient_GetPullRequestState(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  string
+		merged bool
+		want   PullRequestState
+	}{
+		{name: "open", state: "open", want: PullRequestOpen},
+		{name: "merged", state: "closed", merged: true, want: PullRequestMerged},
+		{name: "closed", state: "closed", want: PullRequestClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				respond(w, r.URL.Path == "/api/v3/repos/owner/repo/pulls/3", map[string]interface{}{"number": 3, "state": tt.state, "merged": tt.merged})
+			}))
+			defer server.Close()
+			apiURL := server.URL + "/api/v3"
+			client, err := newGithubClient(nil, Credentials{AccessToken: "token"}, server.URL+"/owner/repo", &apiURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := client.GetPullRequestState(3); err != nil || got != tt.want {
+				t.Errorf("GetPullRequestState() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGithubClient_MergePullRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiPath       string
+		pendingChecks bool
+		wantMerged    bool
+		wantSHA       string
+	}{
+		{name: "merged", apiPath: "/api/v3", wantMerged: true, wantSHA: "merge-sha"},
+		{name: "auto-merge on github enterprise", apiPath: "/api/v3", pendingChecks: true},
+		{name: "auto-merge on github.com", apiPath: "", pendingChecks: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mergeMethod string
+			var graphqlVariables map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPut && r.URL.Path == tt.apiPath+"/repos/owner/repo/pulls/3/merge":
+					var body map[string]string
+					decode(t, r, &body)
+					mergeMethod = body["merge_method"]
+					if tt.pendingChecks {
+						w.WriteHeader(http.StatusMethodNotAllowed)
+						return
+					}
+					respond(w, true, map[string]interface{}{"sha": "merge-sha", "merged": true})
+				case r.Method == http.MethodGet && r.URL.Path == tt.apiPath+"/repos/owner/repo/pulls/3":
+					respond(w, true, map[string]interface{}{"number": 3, "node_id": "PR_node"})
+				case r.Method == http.MethodPost && r.URL.Path == strings.TrimSuffix(tt.apiPath, "/v3")+"/graphql":
+					var body struct {
+						Variables map[string]string `json:"variables"`
+					}
+					decode(t, r, &body)
+					graphqlVariables = body.Variables
+					respond(w, true, map[string]interface{}{"data": map[string]interface{}{}})
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotImplemented)
+				}
+			}))
+			defer server.Close()
+			apiURL := server.URL + tt.apiPath
+			client, err := newGithubClient(nil, Credentials{AccessToken: "token"}, server.URL+"/owner/repo", &apiURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pr := &PullRequest{Number: 3}
+			if err := client.MergePullRequest(pr, "squash"); err != nil {
+				t.Fatalf("MergePullRequest() error = %v", err)
+			}
+			if pr.Merged != tt.wantMerged || pr.MergeCommitSHA != tt.wantSHA || mergeMethod != "squash" {
+				t.Errorf("MergePullRequest() merged %v with %s using %s, want %v with %s", pr.Merged, pr.MergeCommitSHA, mergeMethod, tt.wantMerged, tt.wantSHA)
+			}
+			if tt.pendingChecks && (graphqlVariables["id"] != "PR_node" || graphqlVariables["method"] != "SQUASH") {
+				t.Errorf("auto-merge enabled with %v", graphqlVariables)
+			}
+		})
+	}
+}
+
+func TestGithubClient_SyncFilesWithBranch(t *testing.T) {
+	files := map[string]string{
+		"staging/values.yaml": "tag: 1.0.0\n",
+		"staging/old.yaml":    "old: true\n",
+		"staging/same.yaml":   "same: true\n",
+		"README.md":           "readme\n",
+	}
+	current := []RepositoryFile{
+		{Path: "staging/values.yaml", Content: "tag: 1.0.0\n"},
+		{Path: "staging/old.yaml", Content: "old: true\n"},
+		{Path: "staging/same.yaml", Content: "same: true\n"},
+	}
+	target := []RepositoryFile{
+		{Path: "staging/values.yaml", Content: "tag: 1.0.1\n"},
+		{Path: "staging/same.yaml", Content: "same: true\n"},
+		{Path: "staging/new.yaml", Content: "new: true\n"},
+		{Path: "staging/chart.tgz", Content: "\x1f\x8b\x08\x00\xff\xfe"},
+	}
+	tests := []struct {
+		name        string
+		target      []RepositoryFile
+		prepare     func(f *fakeGithubGitData)
+		wantChanges int
+		wantFiles   map[string]string
+		wantModes   map[string]string
+		wantCommits int
+		wantErr     bool
+		wantMoved   bool
+	}{
+		{
+			name:        "all changes in one commit",
+			target:      target,
+			wantChanges: 4,
+			wantFiles: map[string]string{
+				"staging/chart.tgz":   "\x1f\x8b\x08\x00\xff\xfe",
+				"staging/values.yaml": "tag: 1.0.1\n",
+				"staging/same.yaml":   "same: true\n",
+				"staging/new.yaml":    "new: true\n",
+				"README.md":           "readme\n",
+			},
+			wantCommits: 1,
+		},
+		{
+			name: "mode change",
+			target: []RepositoryFile{
+				{Path: "staging/values.yaml", Content: "tag: 1.0.0\n", Mode: "100755"},
+				{Path: "staging/old.yaml", Content: "old: true\n"},
+				{Path: "staging/same.yaml", Content: "same: true\n"},
+			},
+			wantChanges: 1,
+			wantFiles:   files,
+			wantModes:   map[string]string{"staging/values.yaml": "100755"},
+			wantCommits: 1,
+		},
+		{
+			name:        "no changes",
+			target:      current,
+			wantFiles:   files,
+			wantCommits: 0,
+		},
+		{
+			name:   "failure leaves branch untouched",
+			target: target,
+			prepare: func(f *fakeGithubGitData) {
+				f.failTrees = true
+			},
+			wantFiles:   files,
+			wantCommits: 0,
+			wantErr:     true,
+		},
+		{
+			name:   "concurrent push is not overwritten",
+			target: target,
+			prepare: func(f *fakeGithubGitData) {
+				f.beforeRefUpdate = func() {
+					f.commitFiles["concurrent"] = files
+					f.refs["main"] = "concurrent"
+				}
+			},
+			wantFiles:   files,
+			wantCommits: 0,
+			wantErr:     true,
+			wantMoved:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeGithubGitData(t, files)
+			if tt.prepare != nil {
+				tt.prepare(fake)
+			}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			apiURL := server.URL + "/api/v3"
+			client, err := newGithubClient(nil, Credentials{AccessToken: "token"}, server.URL+"/owner/repo", &apiURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			changes, err := client.SyncFilesWithBranch("main", current, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SyncFilesWithBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrBranchMoved) != tt.wantMoved {
+				t.Errorf("SyncFilesWithBranch() error = %v, want ErrBranchMoved %v", err, tt.wantMoved)
+			}
+			if changes != tt.wantChanges {
+				t.Errorf("SyncFilesWithBranch() changes = %d, want %d", changes, tt.wantChanges)
+			}
+			if !reflect.DeepEqual(fake.branch("main"), tt.wantFiles) {
+				t.Errorf("branch main = %v, want %v", fake.branch("main"), tt.wantFiles)
+			}
+			if fake.commits != tt.wantCommits {
+				t.Errorf("%d commits created, want %d", fake.commits, tt.wantCommits)
+			}
+			for path, mode := range tt.wantModes {
+				if fake.modes[path] != mode {
+					t.Errorf("mode of %s = %s, want %s", path, fake.modes[path], mode)
+				}
+			}
+		})
+	}
+}
+
+// fakeGithubTags is a minimal in-memory stand-in for the tag and compare api of repository owner/repo
+type fakeGithubTags struct {
+	fakeRepository
+	// tagObjects maps the sha of annotated tag objects to their commit
+	tagObjects        map[string]string
+	tagObjectMessages map[string]string
+}
+
+func (f *fakeGithubTags) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/api/v3/repos/owner/repo"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/refs/tags/"):
+		name := strings.TrimPrefix(path, "/git/refs/tags/")
+		commit, ok := f.tags[name]
+		respond(w, ok, map[string]interface{}{"ref": "refs/tags/" + name, "object": map[string]string{"type": "tag", "sha": "tag-" + commit}})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/tags/"):
+		commit, ok := f.tagObjects[strings.TrimPrefix(path, "/git/tags/")]
+		respond(w, ok, map[string]interface{}{"object": map[string]string{"type": "commit", "sha": commit}})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/commits/"):
+		if commit, ok := f.resolve(strings.TrimPrefix(path, "/commits/")); !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		} else {
+			_, _ = w.Write([]byte(commit))
+		}
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/compare/"):
+		refs := strings.SplitN(strings.TrimPrefix(path, "/compare/"), "...", 2)
+		status := "behind"
+		if refs[0] == refs[1] {
+			status = "identical"
+		} else if f.isAncestor(refs[0], refs[1]) {
+			status = "ahead"
+		}
+		respond(w, true, map[string]string{"status": status})
+	case r.Method == http.MethodPost && path == "/git/tags" && f.failTagCreations > 0:
+		f.failTagCreations--
+		w.WriteHeader(http.StatusInternalServerError)
+	case r.Method == http.MethodPost && path == "/git/tags":
+		var body struct {
+			Tag     string `json:"tag"`
+			Message string `json:"message"`
+			Object  string `json:"object"`
+			Type    string `json:"type"`
+		}
+		decode(f.t, r, &body)
+		if body.Message == "" || body.Type != "commit" {
+			f.t.Errorf("unexpected tag %+v", body)
+		}
+		f.tagObjects["tag-"+body.Object] = body.Object
+		f.tagObjectMessages["tag-"+body.Object] = body.Message
+		respond(w, true, map[string]string{"sha": "tag-" + body.Object})
+	case r.Method == http.MethodPost && path == "/git/refs":
+		var body struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		}
+		decode(f.t, r, &body)
+		name := strings.TrimPrefix(body.Ref, "refs/tags/")
+		if _, ok := f.tags[name]; ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		f.tags[name], f.tagMessages[name] = f.tagObjects[body.SHA], f.tagObjectMessages[body.SHA]
+		respond(w, true, map[string]interface{}{"ref": body.Ref, "object": map[string]string{"sha": body.SHA}})
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/git/refs/tags/"):
+		var body struct {
+			SHA   string `json:"sha"`
+			Force bool   `json:"force"`
+		}
+		decode(f.t, r, &body)
+		name := strings.TrimPrefix(path, "/git/refs/tags/")
+		f.tags[name], f.tagMessages[name] = f.tagObjects[body.SHA], f.tagObjectMessages[body.SHA]
+		respond(w, true, map[string]interface{}{"object": map[string]string{"sha": body.SHA}})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGithubClient_Tags(t *testing.T) {
+	fake := &fakeGithubTags{fakeRepository: newFakeRepository(t, map[string]string{"dev/values.yaml": "tag: 1.0.1"}), tagObjects: map[string]string{},
+		tagObjectMessages: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	apiURL := server.URL + "/api/v3"
+	client, err := newGithubClient(nil, Credentials{AccessToken: "token"}, server.URL+"/owner/repo", &apiURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTagClient(t, client, &fake.fakeRepository)
+}
@@ -0,0 +1,50 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type gitlabClient struct {
+	project string
+	rest    restClient
+}
+
+func newGitlabClient(httpClient *http.Client, accessToken string, repositoryUrl string, customApiURL *string) (client *gitlabClient, err error) {
+	apiURL, project, err := getGitlabApiUrlProject(repositoryUrl)
+	if err != nil {
+		return nil, err
+	}
+	if customApiURL != nil && *customApiURL != "" {
+		apiURL = strings.TrimSuffix(*customApiURL, "/")
+	}
+	return &gitlabClient{
+		project: project,
+		rest: restClient{
+			baseURL:    apiURL,
+			httpClient: httpClient,
+			headers:    map[string]string{"PRIVATE-TOKEN": accessToken},
+		},
+	}, nil
+}
+
+// getGitlabApiUrlProject returns the v4 api url of the gitlab instance and the full project path
+// (including all subgroups) for a repository url like https://gitlab.com/group/subgroup/repository
+func getGitlabApiUrlProject(raw string) (apiURL, project string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return apiURL, project, err
+	}
+	project = strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if strings.Count(project, "/") < 1 {
+		return "", "", errors.New(fmt.Sprintf("repository url %s does not contain a group and a project", raw))
+	}
+	return fmt.Sprintf("%s://%s/api/v4", u.Scheme, u.Host), project, nil
+}
+
+func (c *gitlabClient) projectPath(format string, a ...interface{}) string {
+	return "/projects/" + url.PathEscape(c.project) + fmt.Sprintf(format, a...)
+}
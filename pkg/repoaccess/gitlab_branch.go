@@ -0,0 +1,36 @@
+package repoaccess
+
+import (
+	"net/http"
+	"net/url"
+)
+
+func (c *gitlabClient) BranchExists(branchName string) (exists bool, err error) {
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/branches/%s", url.PathEscape(branchName)), nil, nil, nil); isNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *gitlabClient) CreateBranch(sourceBranch, targetBranch string) (err error) {
+	return c.rest.do(http.MethodPost, c.projectPath("/repository/branches"), url.Values{
+		"branch": {targetBranch},
+		"ref":    {sourceBranch},
+	}, nil, nil)
+}
+
+func (c *gitlabClient) DeleteBranch(branch string) (err error) {
+	return c.rest.do(http.MethodDelete, c.projectPath("/repository/branches/%s", url.PathEscape(branch)), nil, nil, nil)
+}
+
+func (c *gitlabClient) DefaultBranch() (branch string, err error) {
+	var project struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := c.rest.do(http.MethodGet, c.projectPath(""), nil, nil, &project); err != nil {
+		return "", err
+	}
+	return project.DefaultBranch, nil
+}
@@ -0,0 +1,248 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const gitlabPageSize = 100
+
+type gitlabCompare struct {
+	Commits []struct {
+		ID string `json:"id"`
+	} `json:"commits"`
+}
+
+type gitlabTreeEntry struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+type gitlabFile struct {
+	FilePath string `json:"file_path"`
+	BlobID   string `json:"blob_id"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+type gitlabCommitAction struct {
+	Action   string `json:"action"`
+	FilePath string `json:"file_path"`
+	Content  string `json:"content,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	// LastCommitID is the last commit which changed the file, the commit is rejected if the file was changed since then
+	LastCommitID string `json:"last_commit_id,omitempty"`
+}
+
+type gitlabCommit struct {
+	Branch        string               `json:"branch"`
+	CommitMessage string               `json:"commit_message"`
+	Actions       []gitlabCommitAction `json:"actions"`
+}
+
+func (c *gitlabClient) CheckForNewCommits(toBranch, fromBranch string) (newCommits bool, err error) {
+	var compare gitlabCompare
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/compare"), url.Values{
+		"from": {toBranch},
+		"to":   {fromBranch},
+	}, nil, &compare); err != nil {
+		return false, err
+	}
+	logger.WithField("func", "CheckForNewCommits").Infof("found %d commits in gitlab project %s from branch %s to %s", len(compare.Commits), c.project, fromBranch, toBranch)
+	return len(compare.Commits) > 0, nil
+}
+
+// gitlabBlobDownloads limits the number of parallel blob downloads
+const gitlabBlobDownloads = 8
+
+// GetFilesForBranch lists the tree below path page by page and downloads the blobs in parallel
+func (c *gitlabClient) GetFilesForBranch(branch, path string) (files []RepositoryFile, err error) {
+	logger.WithField("func", "GetFilesForBranch").Infof("starting with branch %s and path %s", branch, path)
+	path = strings.Trim(path, "/")
+	if file, err := c.getFile(branch, path); err == nil {
+		return append(files, file), nil
+	} else if !isNotFound(err) {
+		return files, err
+	}
+	for page := 1; ; page++ {
+		var entries []gitlabTreeEntry
+		if err := c.rest.do(http.MethodGet, c.projectPath("/repository/tree"), url.Values{
+			"path":      {path},
+			"ref":       {branch},
+			"recursive": {"true"},
+			"per_page":  {strconv.Itoa(gitlabPageSize)},
+			"page":      {strconv.Itoa(page)},
+		}, nil, &entries); isNotFound(err) {
+			return files, nil
+		} else if err != nil {
+			return files, err
+		}
+		for _, e := range entries {
+			if e.Type == "blob" {
+				files = append(files, RepositoryFile{Path: e.Path, SHA: e.ID})
+			}
+		}
+		if len(entries) < gitlabPageSize {
+			break
+		}
+	}
+	if err := c.downloadBlobs(files); err != nil {
+		return nil, err
+	}
+	logger.WithField("func", "GetFilesForBranch").Infof("found %d files in branch %s and path %s", len(files), branch, path)
+	return files, nil
+}
+
+// downloadBlobs sets the content of all files using at most gitlabBlobDownloads parallel requests
+func (c *gitlabClient) downloadBlobs(files []RepositoryFile) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(files))
+	semaphore := make(chan struct{}, gitlabBlobDownloads)
+	for i := range files {
+		wg.Add(1)
+		go func(file *RepositoryFile) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			content, err := c.rest.send(http.MethodGet, c.projectPath("/repository/blobs/%s/raw", url.PathEscape(file.SHA)), nil, "", nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			file.Content = string(content)
+		}(&files[i])
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+func (c *gitlabClient) getFile(branch, path string) (file RepositoryFile, err error) {
+	var f gitlabFile
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/files/%s", url.PathEscape(path)), url.Values{"ref": {branch}}, nil, &f); err != nil {
+		return file, err
+	}
+	content := f.Content
+	if f.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return file, err
+		}
+		content = string(decoded)
+	}
+	logger.WithField("func", "GetFilesForBranch").Infof("found file in path %s", f.FilePath)
+	return RepositoryFile{
+		Content: content,
+		Path:    f.FilePath,
+		SHA:     f.BlobID,
+	}, nil
+}
+
+// SyncFilesWithBranch commits all differences between currentTargetFiles and newTargetFiles with a single commit
+func (c *gitlabClient) SyncFilesWithBranch(branch string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	logger.WithField("func", "SyncfilesWithBranch").Infof("starting for branch %s and %d currentTargetFiles and %d newTargetFiles", branch, len(currentTargetFiles), len(newTargetFiles))
+	return c.syncFilesWithBranch(branch, "", currentTargetFiles, newTargetFiles)
+}
+
+func (c *gitlabClient) GetBranchHead(branch string) (commit string, err error) {
+	var b struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/branches/%s", url.PathEscape(branch)), nil, nil, &b); err != nil {
+		return "", err
+	}
+	return b.Commit.ID, nil
+}
+
+// SyncFilesWithBranchAt checks that the branch still points to head right before committing. The commits api has no
+// expected head parameter, instead every updated or deleted file is sent with its last commit at head and created files
+// must not exist, so gitlab rejects the commit if another commit changed one of the files in the meantime.
+func (c *gitlabClient) SyncFilesWithBranchAt(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	logger.WithField("func", "SyncfilesWithBranch").Infof("starting for branch %s at %s and %d currentTargetFiles and %d newTargetFiles", branch, head, len(currentTargetFiles), len(newTargetFiles))
+	return c.syncFilesWithBranch(branch, head, currentTargetFiles, newTargetFiles)
+}
+
+// syncFilesWithBranch only commits if none of the files were changed since head, an empty head skips the check
+func (c *gitlabClient) syncFilesWithBranch(branch, head string, currentTargetFiles, newTargetFiles []RepositoryFile) (changes int, err error) {
+	commit := gitlabCommit{
+		Branch:        branch,
+		CommitMessage: "(build) sync files",
+	}
+	for _, change := range diffRepositoryFiles(currentTargetFiles, newTargetFiles) {
+		action := gitlabCommitAction{FilePath: change.Path}
+		switch {
+		case change.Content == nil:
+			action.Action = "delete"
+		case change.Current == nil:
+			action.Action = "create"
+		default:
+			action.Action = "update"
+		}
+		if change.Content != nil {
+			action.Content = base64.StdEncoding.EncodeToString([]byte(*change.Content))
+			action.Encoding = "base64"
+		}
+		commit.Actions = append(commit.Actions, action)
+	}
+	if len(commit.Actions) == 0 {
+		return 0, nil
+	}
+	if head != "" {
+		if current, err := c.GetBranchHead(branch); err != nil {
+			return 0, err
+		} else if current != head {
+			return 0, ErrBranchMoved
+		}
+		for i, action := range commit.Actions {
+			if action.Action == "create" {
+				continue
+			}
+			if commit.Actions[i].LastCommitID, err = c.getLastCommit(head, action.FilePath); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := c.rest.do(http.MethodPost, c.projectPath("/repository/commits"), nil, commit, nil); head != "" && isGitlabFileConflict(err) {
+		return 0, ErrBranchMoved
+	} else if err != nil {
+		return 0, err
+	}
+	return len(commit.Actions), nil
+}
+
+// getLastCommit returns the last commit which changed the file in the history of ref
+func (c *gitlabClient) getLastCommit(ref, path string) (commit string, err error) {
+	var commits []struct {
+		ID string `json:"id"`
+	}
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/commits"), url.Values{
+		"ref_name": {ref},
+		"path":     {path},
+		"per_page": {"1"},
+	}, nil, &commits); err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", errors.New(fmt.Sprintf("no commit found for file %s at %s", path, ref))
+	}
+	return commits[0].ID, nil
+}
+
+// isGitlabFileConflict reports whether a commit was rejected because a file was changed, created or deleted since the
+// files were read
+func isGitlabFileConflict(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+		(strings.Contains(apiErr.Body, "has changed since") || strings.Contains(apiErr.Body, "already exists") ||
+			strings.Contains(apiErr.Body, "doesn't exist"))
+}
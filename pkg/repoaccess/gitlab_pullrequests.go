@@ -0,0 +1,117 @@
+package repoaccess
+
+import (
+	"errors"
+	"keptn/git-promotion-service/pkg/model"
+	"net/http"
+	"net/url"
+)
+
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid,omitempty"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	SourceBranch string `json:"source_branch,omitempty"`
+	TargetBranch string `json:"target_branch,omitempty"`
+	WebURL       string `json:"web_url,omitempty"`
+	State        string `json:"state,omitempty"`
+	// read only fields used for merging
+	HeadPipeline    *gitlabPipeline `json:"head_pipeline,omitempty"`
+	MergeCommitSHA  string          `json:"merge_commit_sha,omitempty"`
+	SquashCommitSHA string          `json:"squash_commit_sha,omitempty"`
+}
+
+type gitlabPipeline struct {
+	Status string `json:"status"`
+}
+
+// gitlabRunningPipelineStatuses are the states of pipelines that are not finished yet
+var gitlabRunningPipelineStatuses = map[string]bool{
+	"created": true, "waiting_for_resource": true, "preparing": true, "pending": true, "running": true, "scheduled": true,
+}
+
+func (mr gitlabMergeRequest) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number: mr.IID,
+		Title:  mr.Title,
+		URL:    mr.WebURL,
+	}
+}
+
+func (c *gitlabClient) GetOpenPullRequest(fromBranch, toBranch string) (pr *PullRequest, err error) {
+	var mrs []gitlabMergeRequest
+	if err := c.rest.do(http.MethodGet, c.projectPath("/merge_requests"), url.Values{
+		"state":         {"opened"},
+		"source_branch": {fromBranch},
+		"target_branch": {toBranch},
+	}, nil, &mrs); err != nil {
+		return nil, err
+	}
+	if len(mrs) == 0 {
+		return nil, nil
+	}
+	return mrs[0].toPullRequest(), nil
+}
+
+func (c *gitlabClient) EditPullRequest(pr *PullRequest, title, body string) error {
+	return c.rest.do(http.MethodPut, c.projectPath("/merge_requests/%d", pr.Number), nil, gitlabMergeRequest{
+		Title:       title,
+		Description: body,
+	}, nil)
+}
+
+func (c *gitlabClient) CreatePullRequest(fromBranch, toBranch, title, body string) (pr *PullRequest, err error) {
+	var mr gitlabMergeRequest
+	if err := c.rest.do(http.MethodPost, c.projectPath("/merge_requests"), nil, gitlabMergeRequest{
+		Title:        title,
+		Description:  body,
+		SourceBranch: fromBranch,
+		TargetBranch: toBranch,
+	}, &mr); err != nil {
+		return nil, err
+	}
+	return mr.toPullRequest(), nil
+}
+
+func (c *gitlabClient) GetPullRequestState(number int) (state PullRequestState, err error) {
+	var mr gitlabMergeRequest
+	if err := c.rest.do(http.MethodGet, c.projectPath("/merge_requests/%d", number), nil, nil, &mr); err != nil {
+		return "", err
+	}
+	switch mr.State {
+	case "merged":
+		return PullRequestMerged, nil
+	case "closed":
+		return PullRequestClosed, nil
+	default:
+		// opened or locked
+		return PullRequestOpen, nil
+	}
+}
+
+// MergePullRequest merges with the merge method of the project, method squash squashes the commits. Method rebase is not
+// supported, the merge method of the project decides whether merge requests are rebased. If the pipeline of the merge
+// request is still running, the merge request is merged when the pipeline succeeds.
+func (c *gitlabClient) MergePullRequest(pr *PullRequest, method string) error {
+	if method == model.MergeMethodRebase {
+		return errors.New("merge method rebase is not supported by provider gitlab")
+	}
+	var current gitlabMergeRequest
+	if err := c.rest.do(http.MethodGet, c.projectPath("/merge_requests/%d", pr.Number), nil, nil, &current); err != nil {
+		return err
+	}
+	var merged gitlabMergeRequest
+	if err := c.rest.do(http.MethodPut, c.projectPath("/merge_requests/%d/merge", pr.Number), nil, map[string]bool{
+		"squash":                       method == model.MergeMethodSquash,
+		"merge_when_pipeline_succeeds": current.HeadPipeline != nil && gitlabRunningPipelineStatuses[current.HeadPipeline.Status],
+	}, &merged); err != nil {
+		return err
+	}
+	if merged.State == "merged" {
+		pr.Merged, pr.MergeCommitSHA = true, merged.MergeCommitSHA
+		if merged.SquashCommitSHA != "" && merged.MergeCommitSHA == "" {
+			pr.MergeCommitSHA = merged.SquashCommitSHA
+		}
+	}
+	return nil
+}
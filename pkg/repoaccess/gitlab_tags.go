@@ -0,0 +1,92 @@
+package repoaccess
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type gitlabTag struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Commit  struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+func (c *gitlabClient) GetTag(name string) (tag *Tag, err error) {
+	var t gitlabTag
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/tags/%s", url.PathEscape(name)), nil, nil, &t); isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &Tag{Name: t.Name, Commit: t.Commit.ID}, nil
+}
+
+func (c *gitlabClient) ResolveCommit(ref string) (commit string, err error) {
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/commits/%s", url.PathEscape(ref)), nil, nil, &result); isNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+func (c *gitlabClient) IsAncestor(ancestor, commit string) (isAncestor bool, err error) {
+	var mergeBase struct {
+		ID string `json:"id"`
+	}
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/merge_base"), url.Values{"refs[]": {ancestor, commit}}, nil, &mergeBase); err != nil {
+		return false, err
+	}
+	return mergeBase.ID == ancestor, nil
+}
+
+// CreateTag deletes an existing tag first, the tags api can't move tags. Deleting and creating the tag is not atomic:
+// the tag is only deleted if it still points to the commit of previous and is only created if nobody created it again
+// after the deletion. If the new tag can't be created the existing tag is restored.
+func (c *gitlabClient) CreateTag(name, commit, message string, previous *Tag) (err error) {
+	var existing gitlabTag
+	if err := c.rest.do(http.MethodGet, c.projectPath("/repository/tags/%s", url.PathEscape(name)), nil, nil, &existing); isNotFound(err) {
+		existing.Name = ""
+	} else if err != nil {
+		return err
+	}
+	if (previous == nil) != (existing.Name == "") || (previous != nil && existing.Commit.ID != previous.Commit) {
+		return ErrTagMoved
+	}
+	if existing.Name != "" {
+		if err := c.rest.do(http.MethodDelete, c.projectPath("/repository/tags/%s", url.PathEscape(name)), nil, nil, nil); err != nil && !isNotFound(err) {
+			return err
+		}
+		// another promotion could have created the tag again after the deletion
+		if tag, err := c.GetTag(name); err != nil {
+			return err
+		} else if tag != nil {
+			return ErrTagMoved
+		}
+	}
+	if err := c.createTag(name, commit, message); err != nil {
+		if existing.Name == "" {
+			return err
+		}
+		if restoreErr := c.createTag(name, existing.Commit.ID, existing.Message); restoreErr != nil {
+			return errors.New(fmt.Sprintf("could not create tag %s: %s, restoring it at %s failed: %s", name, err.Error(), existing.Commit.ID, restoreErr.Error()))
+		}
+		return errors.New(fmt.Sprintf("could not create tag %s, kept it at %s: %s", name, existing.Commit.ID, err.Error()))
+	}
+	return nil
+}
+
+func (c *gitlabClient) createTag(name, commit, message string) error {
+	return c.rest.do(http.MethodPost, c.projectPath("/repository/tags"), url.Values{
+		"tag_name": {name},
+		"ref":      {commit},
+		"message":  {message},
+	}, nil, nil)
+}
@@ -0,0 +1,423 @@
+package repoaccess
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeGitlab is a minimal in-memory stand-in for the gitlab v4 api of a single project
+type fakeGitlab struct {
+	fakeRepository
+	mergeRequests []gitlabMergeRequest
+	// mergeOptions are the options of the last merge request
+	mergeOptions map[string]bool
+	// changedFiles maps commit ids to the files changed by the commit, the history is linear (see isAncestor)
+	changedFiles map[string][]string
+	// beforeCommit is called once before the next commit is created, e.g. to push a concurrent commit
+	beforeCommit func()
+	// fileRequests counts the requests of single files
+	fileRequests int
+}
+
+// gitlabBlobID encodes the content in the id, so the blob can be read without knowing its branch
+func gitlabBlobID(content string) string {
+	return "blob-" + base64.URLEncoding.EncodeToString([]byte(content))
+}
+
+func newFakeGitlab(t *testing.T, files map[string]string) *fakeGitlab {
+	return &fakeGitlab{fakeRepository: newFakeRepository(t, files), changedFiles: map[string][]string{}}
+}
+
+// commitFiles commits the files to branch, nil contents delete the file
+func (f *fakeGitlab) commitFiles(branch string, files map[string]*string) {
+	f.commit(branch)
+	for path, content := range files {
+		if content == nil {
+			delete(f.branch(branch), path)
+		} else {
+			f.branch(branch)[path] = *content
+		}
+		f.changedFiles[f.headID(branch)] = append(f.changedFiles[f.headID(branch)], path)
+	}
+}
+
+// lastCommit returns the last commit up to commit which changed the file, files of the initial content are from commit-0
+func (f *fakeGitlab) lastCommit(commit, path string) string {
+	n, _ := strconv.Atoi(strings.TrimPrefix(commit, "commit-"))
+	for ; n > 0; n-- {
+		for _, p := range f.changedFiles["commit-"+strconv.Itoa(n)] {
+			if p == path {
+				return "commit-" + strconv.Itoa(n)
+			}
+		}
+	}
+	return "commit-0"
+}
+
+func (f *fakeGitlab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("PRIVATE-TOKEN") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	prefix := "/api/v4/projects/" + url.PathEscape("group/repo")
+	if !strings.HasPrefix(r.URL.EscapedPath(), prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		respond(w, true, map[string]string{"default_branch": "main"})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/repository/branches/"):
+		branch := unescape(strings.TrimPrefix(path, "/repository/branches/"))
+		respond(w, f.branch(branch) != nil, map[string]interface{}{"commit": map[string]string{"id": f.headID(branch)}})
+	case r.Method == http.MethodPost && path == "/repository/branches":
+		f.createBranch(query.Get("ref"), query.Get("branch"))
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/repository/branches/"):
+		f.deleteBranch(unescape(strings.TrimPrefix(path, "/repository/branches/")))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/repository/files/"):
+		f.fileRequests++
+		filePath := unescape(strings.TrimPrefix(path, "/repository/files/"))
+		content, ok := f.branch(query.Get("ref"))[filePath]
+		respond(w, ok, gitlabFile{FilePath: filePath, BlobID: gitlabBlobID(content), Content: base64.StdEncoding.EncodeToString([]byte(content)), Encoding: "base64"})
+	case r.Method == http.MethodGet && path == "/repository/tree":
+		var entries []gitlabTreeEntry
+		for _, k := range f.filesInDirectory(query.Get("ref"), query.Get("path")) {
+			entries = append(entries, gitlabTreeEntry{ID: gitlabBlobID(f.branch(query.Get("ref"))[k]), Type: "blob", Path: k})
+		}
+		respond(w, len(entries) > 0, entries)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/repository/blobs/") && strings.HasSuffix(path, "/raw"):
+		content, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(unescape(strings.TrimSuffix(strings.TrimPrefix(path, "/repository/blobs/"), "/raw")), "blob-"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(content)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/repository/tags/"):
+		name := unescape(strings.TrimPrefix(path, "/repository/tags/"))
+		commit, ok := f.tags[name]
+		respond(w, ok, map[string]interface{}{"name": name, "message": f.tagMessages[name], "commit": map[string]string{"id": commit}})
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/repository/tags/"):
+		name := unescape(strings.TrimPrefix(path, "/repository/tags/"))
+		_, ok := f.tags[name]
+		delete(f.tags, name)
+		respond(w, ok, map[string]string{})
+	case r.Method == http.MethodPost && path == "/repository/tags" && f.failTagCreations > 0:
+		f.failTagCreations--
+		w.WriteHeader(http.StatusInternalServerError)
+	case r.Method == http.MethodPost && path == "/repository/tags":
+		if _, ok := f.tags[query.Get("tag_name")]; ok || query.Get("message") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.tags[query.Get("tag_name")], f.tagMessages[query.Get("tag_name")] = query.Get("ref"), query.Get("message")
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodGet && path == "/repository/commits":
+		commit, ok := f.resolve(query.Get("ref_name"))
+		if !ok {
+			commit = query.Get("ref_name")
+		}
+		respond(w, true, []map[string]string{{"id": f.lastCommit(commit, query.Get("path"))}})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/repository/commits/"):
+		commit, ok := f.resolve(unescape(strings.TrimPrefix(path, "/repository/commits/")))
+		respond(w, ok, map[string]string{"id": commit})
+	case r.Method == http.MethodGet && path == "/repository/merge_base":
+		refs := query["refs[]"]
+		mergeBase := refs[1]
+		if f.isAncestor(refs[0], refs[1]) {
+			mergeBase = refs[0]
+		}
+		respond(w, true, map[string]string{"id": mergeBase})
+	case r.Method == http.MethodGet && path == "/repository/compare":
+		compare := gitlabCompare{}
+		if f.hasNewCommits(query.Get("from"), query.Get("to")) {
+			compare.Commits = append(compare.Commits, struct {
+				ID string `json:"id"`
+			}{ID: "abc"})
+		}
+		respond(w, true, compare)
+	case r.Method == http.MethodPost && path == "/repository/commits":
+		var commit gitlabCommit
+		decode(f.t, r, &commit)
+		if f.beforeCommit != nil {
+			f.beforeCommit()
+			f.beforeCommit = nil
+		}
+		files := map[string]*string{}
+		for _, a := range commit.Actions {
+			_, exists := f.branch(commit.Branch)[a.FilePath]
+			if a.Action == "create" && exists {
+				http.Error(w, `{"message":"A file with this name already exists"}`, http.StatusBadRequest)
+				return
+			} else if a.LastCommitID != "" && a.LastCommitID != f.lastCommit(f.headID(commit.Branch), a.FilePath) {
+				http.Error(w, `{"message":"You are attempting to update a file that has changed since you started editing it."}`, http.StatusBadRequest)
+				return
+			}
+			if a.Action == "delete" {
+				files[a.FilePath] = nil
+			} else {
+				content, _ := base64.StdEncoding.DecodeString(a.Content)
+				files[a.FilePath] = strptr(string(content))
+			}
+		}
+		f.commitFiles(commit.Branch, files)
+		respond(w, true, map[string]string{})
+	case r.Method == http.MethodGet && path == "/merge_requests":
+		var result []gitlabMergeRequest
+		for _, mr := range f.mergeRequests {
+			if mr.SourceBranch == query.Get("source_branch") && mr.TargetBranch == query.Get("target_branch") {
+				result = append(result, mr)
+			}
+		}
+		respond(w, true, result)
+	case r.Method == http.MethodPost && path == "/merge_requests":
+		var mr gitlabMergeRequest
+		decode(f.t, r, &mr)
+		mr.IID = len(f.mergeRequests) + 1
+		mr.WebURL = fmt.Sprintf("https://gitlab.example.com/group/repo/-/merge_requests/%d", mr.IID)
+		f.mergeRequests = append(f.mergeRequests, mr)
+		respond(w, true, mr)
+	case r.Method == http.MethodPut && strings.HasSuffix(path, "/merge"):
+		id, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/merge_requests/"), "/merge"))
+		f.mergeOptions = nil
+		decode(f.t, r, &f.mergeOptions)
+		if !f.mergeOptions["merge_when_pipeline_succeeds"] {
+			f.mergeRequests[id-1].State, f.mergeRequests[id-1].MergeCommitSHA = "merged", "merge-sha"
+		}
+		respond(w, true, f.mergeRequests[id-1])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/merge_requests/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/merge_requests/"))
+		respond(w, true, f.mergeRequests[id-1])
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/merge_requests/"):
+		var update gitlabMergeRequest
+		decode(f.t, r, &update)
+		for i, mr := range f.mergeRequests {
+			if fmt.Sprintf("/merge_requests/%d", mr.IID) == path {
+				f.mergeRequests[i].Title = update.Title
+				f.mergeRequests[i].Description = update.Description
+			}
+		}
+		respond(w, true, map[string]string{})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func Test_getGitlabApiUrlProject(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		wantApiURL  string
+		wantProject string
+		wantErr     bool
+	}{
+		{
+			name:        "gitlab.com",
+			raw:         "https://gitlab.com/group/repo",
+			wantApiURL:  "https://gitlab.com/api/v4",
+			wantProject: "group/repo",
+		},
+		{
+			name:        "self-hosted with subgroups and .git suffix",
+			raw:         "https://git.example.com/group/subgroup/repo.git",
+			wantApiURL:  "https://git.example.com/api/v4",
+			wantProject: "group/subgroup/repo",
+		},
+		{
+			name:    "missing project",
+			raw:     "https://gitlab.com/group",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotApiURL, gotProject, err := getGitlabApiUrlProject(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getGitlabApiUrlProject() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotApiURL != tt.wantApiURL {
+				t.Errorf("getGitlabApiUrlProject() gotApiURL = %v, want %v", gotApiURL, tt.wantApiURL)
+			}
+			if gotProject != tt.wantProject {
+				t.Errorf("getGitlabApiUrlProject() gotProject = %v, want %v", gotProject, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestGitlabClient(t *testing.T) {
+	fake := newFakeGitlab(t, map[string]string{
+		"dev/values.yaml":          "tag: 1.0.1",
+		"staging/values.yaml":      "tag: 1.0.0",
+		"staging/old.yaml":         "old: true",
+		"staging/chart/Chart.yaml": "name: chart",
+	})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := newGitlabClient(nil, "token", server.URL+"/group/repo", nil)
+	if err != nil {
+		t.Fatalf("newGitlabClient() error = %v", err)
+	}
+
+	if branch, err := client.DefaultBranch(); err != nil || branch != "main" {
+		t.Fatalf("DefaultBranch() = %s, %v, want main", branch, err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Fatalf("BranchExists() = %v, %v, want false", exists, err)
+	}
+	if err := client.CreateBranch("main", "promote/dev_staging"); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || !exists {
+		t.Fatalf("BranchExists() = %v, %v, want true", exists, err)
+	}
+
+	current, err := client.GetFilesForBranch("promote/dev_staging", "/staging")
+	if err != nil {
+		t.Fatalf("GetFilesForBranch() error = %v", err)
+	}
+	if want := []string{"staging/chart/Chart.yaml", "staging/old.yaml", "staging/values.yaml"}; !reflect.DeepEqual(filePaths(current), want) {
+		t.Errorf("GetFilesForBranch() = %v, want %v", filePaths(current), want)
+	}
+	// only the check whether the path is a file reads a single file, the contents are read as blobs
+	if fake.fileRequests != 1 || current[1].Content != "old: true" {
+		t.Errorf("GetFilesForBranch() made %d file requests, content %q", fake.fileRequests, current[1].Content)
+	}
+	if single, err := client.GetFilesForBranch("main", "dev/values.yaml"); err != nil || len(single) != 1 || single[0].Content != "tag: 1.0.1" {
+		t.Errorf("GetFilesForBranch() for single file = %v, %v", single, err)
+	}
+	if missing, err := client.GetFilesForBranch("main", "prod"); err != nil || len(missing) != 0 {
+		t.Errorf("GetFilesForBranch() for missing path = %v, %v", missing, err)
+	}
+
+	changes, err := client.SyncFilesWithBranch("promote/dev_staging", current, []RepositoryFile{
+		{Path: "staging/values.yaml", Content: "tag: 1.0.1"},
+		{Path: "staging/chart/Chart.yaml", Content: "name: chart"},
+		{Path: "staging/new.yaml", Content: "new: true"},
+	})
+	if err != nil {
+		t.Fatalf("SyncFilesWithBranch() error = %v", err)
+	}
+	if changes != 3 || fake.commits != 1 {
+		t.Errorf("SyncFilesWithBranch() changes = %d with %d commits, want 3 changes with 1 commit", changes, fake.commits)
+	}
+	wantFiles := map[string]string{
+		"dev/values.yaml":          "tag: 1.0.1",
+		"staging/values.yaml":      "tag: 1.0.1",
+		"staging/new.yaml":         "new: true",
+		"staging/chart/Chart.yaml": "name: chart",
+	}
+	if !reflect.DeepEqual(fake.branch("promote/dev_staging"), wantFiles) {
+		t.Errorf("branch content = %v, want %v", fake.branch("promote/dev_staging"), wantFiles)
+	}
+
+	if newCommits, err := client.CheckForNewCommits("main", "promote/dev_staging"); err != nil || !newCommits {
+		t.Errorf("CheckForNewCommits() = %v, %v, want true", newCommits, err)
+	}
+
+	head, err := client.GetBranchHead("promote/dev_staging")
+	if err != nil {
+		t.Fatalf("GetBranchHead() error = %v", err)
+	}
+	direct := []RepositoryFile{{Path: "staging/direct.yaml", Content: "direct: true"}}
+	fake.commit("promote/dev_staging")
+	if _, err := client.SyncFilesWithBranchAt("promote/dev_staging", head, nil, direct); !errors.Is(err, ErrBranchMoved) {
+		t.Errorf("SyncFilesWithBranchAt() with moved branch error = %v, want %v", err, ErrBranchMoved)
+	}
+	if head, err = client.GetBranchHead("promote/dev_staging"); err != nil {
+		t.Fatalf("GetBranchHead() error = %v", err)
+	}
+	if changes, err := client.SyncFilesWithBranchAt("promote/dev_staging", head, nil, direct); err != nil || changes != 1 || fake.branch("promote/dev_staging")["staging/direct.yaml"] != "direct: true" {
+		t.Errorf("SyncFilesWithBranchAt() = %d, %v, branch content = %v", changes, err, fake.branch("promote/dev_staging"))
+	}
+	// a file changed between the check of the head and the commit is detected with its last commit
+	if head, err = client.GetBranchHead("promote/dev_staging"); err != nil {
+		t.Fatalf("GetBranchHead() error = %v", err)
+	}
+	update := []RepositoryFile{{Path: "staging/direct.yaml", Content: "direct: false"}}
+	fake.beforeCommit = func() {
+		fake.commitFiles("promote/dev_staging", map[string]*string{"staging/direct.yaml": strptr("direct: concurrent")})
+	}
+	if _, err := client.SyncFilesWithBranchAt("promote/dev_staging", head, direct, update); !errors.Is(err, ErrBranchMoved) {
+		t.Errorf("SyncFilesWithBranchAt() with concurrent commit error = %v, want %v", err, ErrBranchMoved)
+	}
+	if content := fake.branch("promote/dev_staging")["staging/direct.yaml"]; content != "direct: concurrent" {
+		t.Errorf("concurrent change overwritten with %s", content)
+	}
+	if head, err = client.GetBranchHead("promote/dev_staging"); err != nil {
+		t.Fatalf("GetBranchHead() error = %v", err)
+	}
+	concurrent := []RepositoryFile{{Path: "staging/direct.yaml", Content: "direct: concurrent"}}
+	if changes, err := client.SyncFilesWithBranchAt("promote/dev_staging", head, concurrent, update); err != nil || changes != 1 || fake.branch("promote/dev_staging")["staging/direct.yaml"] != "direct: false" {
+		t.Errorf("SyncFilesWithBranchAt() = %d, %v, branch content = %v", changes, err, fake.branch("promote/dev_staging"))
+	}
+
+	pr, err := client.CreatePullRequest("promote/dev_staging", "main", "keptn: title", "body")
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 1 || pr.URL != "https://gitlab.example.com/group/repo/-/merge_requests/1" {
+		t.Errorf("CreatePullRequest() = %+v", pr)
+	}
+	open, err := client.GetOpenPullRequest("promote/dev_staging", "main")
+	if err != nil || open == nil || open.Number != pr.Number {
+		t.Fatalf("GetOpenPullRequest() = %+v, %v", open, err)
+	}
+	if err := client.EditPullRequest(open, "keptn: new title", "new body"); err != nil {
+		t.Fatalf("EditPullRequest() error = %v", err)
+	}
+	if fake.mergeRequests[0].Title != "keptn: new title" || fake.mergeRequests[0].Description != "new body" {
+		t.Errorf("EditPullRequest() did not update merge request: %+v", fake.mergeRequests[0])
+	}
+	for _, state := range []string{"opened", "merged", "closed"} {
+		fake.mergeRequests[0].State = state
+		want := map[string]PullRequestState{"opened": PullRequestOpen, "merged": PullRequestMerged, "closed": PullRequestClosed}[state]
+		if got, err := client.GetPullRequestState(pr.Number); err != nil || got != want {
+			t.Errorf("GetPullRequestState() with state %s = %v, %v, want %v", state, got, err, want)
+		}
+	}
+	fake.mergeRequests[0].State, fake.mergeRequests[0].HeadPipeline = "opened", &gitlabPipeline{Status: "running"}
+	if err := client.MergePullRequest(pr, "squash"); err != nil || pr.Merged || !fake.mergeOptions["merge_when_pipeline_succeeds"] || !fake.mergeOptions["squash"] {
+		t.Errorf("MergePullRequest() with running pipeline = %v, merged %v, options %v", err, pr.Merged, fake.mergeOptions)
+	}
+	fake.mergeRequests[0].HeadPipeline = &gitlabPipeline{Status: "success"}
+	fake.mergeOptions = nil
+	if err := client.MergePullRequest(pr, "rebase"); err == nil || pr.Merged || fake.mergeOptions != nil {
+		t.Errorf("MergePullRequest() with rebase = %v, merged %v, options %v, want error", err, pr.Merged, fake.mergeOptions)
+	}
+	if err := client.MergePullRequest(pr, "merge"); err != nil || !pr.Merged || pr.MergeCommitSHA != "merge-sha" || fake.mergeOptions["squash"] {
+		t.Errorf("MergePullRequest() = %v, merged %v with %s, options %v", err, pr.Merged, pr.MergeCommitSHA, fake.mergeOptions)
+	}
+
+	if err := client.DeleteBranch("promote/dev_staging"); err != nil {
+		t.Fatalf("DeleteBranch() error = %v", err)
+	}
+	if exists, err := client.BranchExists("promote/dev_staging"); err != nil || exists {
+		t.Errorf("BranchExists() after delete = %v, %v, want false", exists, err)
+	}
+}
+
+func TestGitlabClient_Tags(t *testing.T) {
+	fake := newFakeGitlab(t, map[string]string{"dev/values.yaml": "tag: 1.0.1"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client, err := newGitlabClient(nil, "token", server.URL+"/group/repo", nil)
+	if err != nil {
+		t.Fatalf("newGitlabClient() error = %v", err)
+	}
+	testTagClient(t, client, &fake.fakeRepository)
+}
@@ -0,0 +1,118 @@
+package repoaccess
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// restClient is a minimal JSON client for the REST APIs of providers without a dedicated go library
+type restClient struct {
+	baseURL    string
+	httpClient *http.Client
+	headers    map[string]string
+}
+
+// apiError is returned by restClient for every response with a non 2xx status code
+type apiError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s %s failed with status %d: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+func isStatus(err error, statusCode int) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+func isNotFound(err error) bool {
+	return isStatus(err, http.StatusNotFound)
+}
+
+// newHTTPClient returns nil (use the default client) if caBundle is empty, otherwise a client additionally trusting
+// the certificates in caBundle
+func newHTTPClient(caBundle []byte) (*http.Client, error) {
+	if len(caBundle) == 0 {
+		return nil, nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, errors.New("no valid certificate found in ca bundle")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}, nil
+}
+
+// do sends a request to baseURL + path (path must already be escaped). body is marshalled to json if not nil and
+// the response is unmarshalled into result if result is not nil.
+func (r restClient) do(method, path string, query url.Values, body interface{}, result interface{}) (err error) {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	data, err := r.send(method, path, query, contentType, reader)
+	if err != nil {
+		return err
+	}
+	if result != nil && len(data) > 0 {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
+
+// send sends a request with an arbitrary body and returns the raw response body
+func (r restClient) send(method, path string, query url.Values, contentType string, body io.Reader) (response []byte, err error) {
+	requestURL := r.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	httpClient := r.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &apiError{StatusCode: resp.StatusCode, Method: method, URL: requestURL, Body: string(data)}
+	}
+	return data, nil
+}
@@ -0,0 +1,44 @@
+package secrets
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+var invalidEnvCharacters = regexp.MustCompile("[^A-Z0-9_]")
+
+// envProvider reads secrets from environment variables named <PREFIX><SECRET>_<KEY>
+type envProvider struct {
+	prefix  string
+	environ func() []string
+}
+
+// NewEnvProvider returns a Provider reading environment variables. Secret name and key are converted to upper case
+// and all characters except letters and digits are replaced by "_", e.g. key access-token of secret gke-demo is read
+// from GKE_DEMO_ACCESS_TOKEN (with empty prefix).
+func NewEnvProvider(prefix string) Provider {
+	return &envProvider{prefix: prefix, environ: os.Environ}
+}
+
+func (p *envProvider) GetSecret(name string) (data map[string][]byte, err error) {
+	variablePrefix := p.prefix + envName(name) + "_"
+	data = make(map[string][]byte)
+	for _, e := range p.environ() {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], variablePrefix) {
+			continue
+		}
+		// environment variables can't be mapped back to the original key, so only known keys are read
+		for _, key := range keys {
+			if kv[0] == variablePrefix+envName(key) {
+				data[key] = []byte(kv[1])
+			}
+		}
+	}
+	return data, nil
+}
+
+func envName(s string) string {
+	return invalidEnvCharacters.ReplaceAllString(strings.ToUpper(s), "_")
+}
@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileProvider reads secrets from directories containing one file per key, the layout of a mounted Kubernetes secret
+type fileProvider struct {
+	directory string
+}
+
+// NewFileProvider returns a Provider reading the secret name from the sub directory name of directory. Secrets outside
+// of directory can't be read.
+func NewFileProvider(directory string) Provider {
+	return &fileProvider{directory: directory}
+}
+
+func (p *fileProvider) GetSecret(name string) (data map[string][]byte, err error) {
+	// the name is set by the promotion config, it must not select any other directory of the container
+	if name == "" || name == "." || name == ".." || filepath.IsAbs(name) || strings.ContainsAny(name, `/\`) {
+		return nil, errors.New(fmt.Sprintf("invalid secret name %s", name))
+	}
+	directory := filepath.Join(p.directory, name)
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	data = make(map[string][]byte)
+	for _, e := range entries {
+		// mounted secrets contain hidden directories and symlinks (..data) used for atomic updates
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(directory, e.Name())
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			continue
+		}
+		if data[e.Name()], err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,33 @@
+package secrets
+
+import (
+	"context"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// kubernetesProvider reads secrets from a namespace of the cluster
+type kubernetesProvider struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewKubernetesProvider returns a Provider reading Kubernetes secrets of namespace
+func NewKubernetesProvider(client kubernetes.Interface, namespace string) Provider {
+	return &kubernetesProvider{client: client, namespace: namespace}
+}
+
+func (p *kubernetesProvider) GetSecret(name string) (data map[string][]byte, err error) {
+	secret, err := p.client.CoreV1().Secrets(p.namespace).Get(context.Background(), name, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	data = make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+	for k, v := range secret.Data {
+		data[k] = v
+	}
+	for k, v := range secret.StringData {
+		data[k] = []byte(v)
+	}
+	return data, nil
+}
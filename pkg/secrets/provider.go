@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// keys read from the secret of a target
+const (
+	KeyUsername       = "username"
+	KeyAccessToken    = "access-token"
+	KeyCABundle       = "ca.crt"
+	KeyAppID          = "app-id"
+	KeyInstallationID = "installation-id"
+	KeyPrivateKey     = "private-key"
+	KeySSHPrivateKey  = "ssh-privatekey"
+	KeyKnownHosts     = "known_hosts"
+)
+
+var keys = []string{KeyUsername, KeyAccessToken, KeyCABundle, KeyAppID, KeyInstallationID, KeyPrivateKey, KeySSHPrivateKey, KeyKnownHosts}
+
+// Provider reads the key/value pairs of a named secret (e.g. access-token, ca.crt)
+type Provider interface {
+	GetSecret(name string) (data map[string][]byte, err error)
+}
+
+// Providers maps the secret sources (see model.SecretSourceKubernetes, ...) to their provider
+type Providers map[string]Provider
+
+// GetSecret reads the secret from the provider registered for source
+func (p Providers) GetSecret(source, name string) (data map[string][]byte, err error) {
+	provider, ok := p[source]
+	if !ok || provider == nil {
+		return nil, errors.New(fmt.Sprintf("secret source %s not available", source))
+	}
+	return provider.GetSecret(name)
+}
@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubernetesProvider_GetSecret(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "mysecret", Namespace: "keptn"},
+		Data:       map[string][]byte{"access-token": []byte("token")},
+	})
+	p := NewKubernetesProvider(client, "keptn")
+	if got, err := p.GetSecret("mysecret"); err != nil || !reflect.DeepEqual(got, map[string][]byte{"access-token": []byte("token")}) {
+		t.Errorf("GetSecret() = %v, %v", got, err)
+	}
+	if _, err := p.GetSecret("missing"); err == nil {
+		t.Errorf("GetSecret() for missing secret returned no error")
+	}
+}
+
+func TestFileProvider_GetSecret(t *testing.T) {
+	directory := t.TempDir()
+	secret := filepath.Join(directory, "mysecret")
+	if err := os.MkdirAll(filepath.Join(secret, "..data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"access-token": "token", "ca.crt": "cert", ".hidden": "x"} {
+		if err := os.WriteFile(filepath.Join(secret, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string][]byte{"access-token": []byte("token"), "ca.crt": []byte("cert")}
+	tests := []struct {
+		name    string
+		secret  string
+		want    map[string][]byte
+		wantErr bool
+	}{
+		{
+			name:   "relative to directory",
+			secret: "mysecret",
+			want:   want,
+		},
+		{
+			name:    "absolute path",
+			secret:  secret,
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			secret:  "missing",
+			wantErr: true,
+		},
+		{
+			name:    "outside of directory",
+			secret:  "../mysecret",
+			wantErr: true,
+		},
+		{
+			name:    "sub directory",
+			secret:  "mysecret/..data",
+			wantErr: true,
+		},
+		{
+			name:    "parent directory",
+			secret:  "..",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFileProvider(directory).GetSecret(tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSecret() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvProvider_GetSecret(t *testing.T) {
+	environ := func() []string {
+		return []string{
+			"GP_GKE_DEMO_ACCESS_TOKEN=token",
+			"GP_GKE_DEMO_CA_CRT=cert=with=equals",
+			"GP_GKE_DEMO_OTHER=ignored",
+			"GP_OTHER_ACCESS_TOKEN=other",
+			"GKE_DEMO_USERNAME=without-prefix",
+		}
+	}
+	p := &envProvider{prefix: "GP_", environ: environ}
+	want := map[string][]byte{"access-token": []byte("token"), "ca.crt": []byte("cert=with=equals")}
+	if got, err := p.GetSecret("gke-demo"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSecret() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestProviders_GetSecret(t *testing.T) {
+	providers := Providers{"env": &envProvider{environ: func() []string { return []string{"S_USERNAME=user"} }}}
+	if got, err := providers.GetSecret("env", "s"); err != nil || string(got["username"]) != "user" {
+		t.Errorf("GetSecret() = %v, %v", got, err)
+	}
+	if _, err := providers.GetSecret("kubernetes", "s"); err == nil {
+		t.Errorf("GetSecret() for unavailable source returned no error")
+	}
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"net/http"
+	"strings"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// maxPayloadSize limits the size of accepted webhook payloads
+const maxPayloadSize = 10 << 20
+
+// ErrInvalidSignature is returned for requests without a valid signature (github) or token (gitlab)
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrPayloadTooLarge is returned for payloads larger than maxPayloadSize
+var ErrPayloadTooLarge = errors.New("webhook payload too large")
+
+// PullRequestEvent is the provider independent content of a pull request webhook
+type PullRequestEvent struct {
+	Number       int
+	Title        string
+	URL          string
+	SourceBranch string
+	State        repoaccess.PullRequestState
+}
+
+type githubPullRequestEvent struct {
+	PullRequest struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		State   string `json:"state"`
+		Merged  bool   `json:"merged"`
+		Head    struct {
+			Ref string `json:"ref"`
+		} `json:"head"`
+	} `json:"pull_request"`
+}
+
+type gitlabMergeRequestEvent struct {
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		URL          string `json:"url"`
+		State        string `json:"state"`
+		SourceBranch string `json:"source_branch"`
+	} `json:"object_attributes"`
+}
+
+// Parse verifies and reads a github or gitlab pull request webhook, other events (e.g. ping) return nil
+func Parse(r *http.Request, secret []byte) (event *PullRequestEvent, err error) {
+	if len(secret) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(body) > maxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	if githubEvent := r.Header.Get("X-GitHub-Event"); githubEvent != "" {
+		if !validGithubSignature(body, r.Header.Get("X-Hub-Signature-256"), secret) {
+			return nil, ErrInvalidSignature
+		} else if githubEvent != "pull_request" {
+			return nil, nil
+		}
+		return parseGithubEvent(body)
+	} else if gitlabEvent := r.Header.Get("X-Gitlab-Event"); gitlabEvent != "" {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), secret) != 1 {
+			return nil, ErrInvalidSignature
+		} else if gitlabEvent != "Merge Request Hook" {
+			return nil, nil
+		}
+		return parseGitlabEvent(body)
+	}
+	return nil, errors.New("unsupported webhook, only github and gitlab are supported")
+}
+
+func validGithubSignature(body []byte, signature string, secret []byte) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func parseGithubEvent(body []byte) (*PullRequestEvent, error) {
+	var e githubPullRequestEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid github pull request event: %s", err.Error()))
+	}
+	state := repoaccess.PullRequestOpen
+	if e.PullRequest.Merged {
+		state = repoaccess.PullRequestMerged
+	} else if e.PullRequest.State == "closed" {
+		state = repoaccess.PullRequestClosed
+	}
+	return &PullRequestEvent{
+		Number:       e.PullRequest.Number,
+		Title:        e.PullRequest.Title,
+		URL:          e.PullRequest.HTMLURL,
+		SourceBranch: e.PullRequest.Head.Ref,
+		State:        state,
+	}, nil
+}
+
+func parseGitlabEvent(body []byte) (*PullRequestEvent, error) {
+	var e gitlabMergeRequestEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid gitlab merge request event: %s", err.Error()))
+	}
+	state := repoaccess.PullRequestOpen
+	switch e.ObjectAttributes.State {
+	case "merged":
+		state = repoaccess.PullRequestMerged
+	case "closed":
+		state = repoaccess.PullRequestClosed
+	}
+	return &PullRequestEvent{
+		Number:       e.ObjectAttributes.IID,
+		Title:        e.ObjectAttributes.Title,
+		URL:          e.ObjectAttributes.URL,
+		SourceBranch: e.ObjectAttributes.SourceBranch,
+		State:        state,
+	}, nil
+}
+
+// NewHandler returns a http.Handler passing verified pull request events to onEvent
+func NewHandler(secret []byte, onEvent func(event PullRequestEvent) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// the rest of larger bodies is not read, the connection is closed instead
+		r.Body = http.MaxBytesReader(w, r.Body, maxPayloadSize+1)
+		event, err := Parse(r, secret)
+		if errors.Is(err, ErrPayloadTooLarge) {
+			logger.WithField("func", "ServeHTTP").Warnf("rejected webhook from %s with payload larger than %d bytes", r.RemoteAddr, maxPayloadSize)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		} else if errors.Is(err, ErrInvalidSignature) {
+			logger.WithField("func", "ServeHTTP").Warnf("rejected webhook from %s with invalid signature", r.RemoteAddr)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			logger.WithField("func", "ServeHTTP").WithError(err).Warn("rejected invalid webhook")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		} else if event == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err := onEvent(*event); err != nil {
+			logger.WithField("func", "ServeHTTP").WithError(err).Errorf("processing webhook for pull request %s failed", event.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"keptn/git-promotion-service/pkg/repoaccess"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const githubPayload = `{"action":"closed","pull_request":{"number":3,"title":"keptn: Promote to stage prod (ctx: abc)","html_url":"https://github.com/owner/repo/pull/3","state":"closed","merged":true,"head":{"ref":"promote/dev_prod-abc"}}}`
+const gitlabPayload = `{"object_kind":"merge_request","object_attributes":{"iid":4,"title":"title","url":"https://gitlab.com/group/repo/-/merge_requests/4","state":"closed","source_branch":"promote/dev_prod-abc"}}`
+
+func githubSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		body    string
+		secret  string
+		want    *PullRequestEvent
+		wantErr error
+	}{
+		{
+			name:    "github merged",
+			headers: map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": githubSignature("secret", githubPayload)},
+			body:    githubPayload,
+			secret:  "secret",
+			want: &PullRequestEvent{Number: 3, Title: "keptn: Promote to stage prod (ctx: abc)", URL: "https://github.com/owner/repo/pull/3",
+				SourceBranch: "promote/dev_prod-abc", State: repoaccess.PullRequestMerged},
+		},
+		{
+			name:    "github invalid signature",
+			headers: map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": githubSignature("other", githubPayload)},
+			body:    githubPayload,
+			secret:  "secret",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "github missing signature",
+			headers: map[string]string{"X-GitHub-Event": "pull_request"},
+			body:    githubPayload,
+			secret:  "secret",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "github ping",
+			headers: map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": githubSignature("secret", "{}")},
+			body:    "{}",
+			secret:  "secret",
+		},
+		{
+			name:    "gitlab closed",
+			headers: map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "secret"},
+			body:    gitlabPayload,
+			secret:  "secret",
+			want: &PullRequestEvent{Number: 4, Title: "title", URL: "https://gitlab.com/group/repo/-/merge_requests/4",
+				SourceBranch: "promote/dev_prod-abc", State: repoaccess.PullRequestClosed},
+		},
+		{
+			name:    "gitlab invalid token",
+			headers: map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "other"},
+			body:    gitlabPayload,
+			secret:  "secret",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "payload too large",
+			headers: map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "secret"},
+			body:    gitlabPayload + strings.Repeat(" ", maxPayloadSize),
+			secret:  "secret",
+			wantErr: ErrPayloadTooLarge,
+		},
+		{
+			name:    "empty secret",
+			headers: map[string]string{"X-Gitlab-Event": "Merge Request Hook"},
+			body:    gitlabPayload,
+			wantErr: ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			got, err := Parse(r, []byte(tt.secret))
+			if err != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	var received []PullRequestEvent
+	handler := NewHandler([]byte("secret"), func(event PullRequestEvent) error {
+		received = append(received, event)
+		return nil
+	})
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "valid", token: "secret", body: gitlabPayload, wantStatus: http.StatusAccepted},
+		{name: "invalid", token: "other", body: gitlabPayload, wantStatus: http.StatusUnauthorized},
+		{name: "too large", token: "secret", body: gitlabPayload + strings.Repeat(" ", 2*maxPayloadSize), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			r.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+			r.Header.Set("X-Gitlab-Token", tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+	if len(received) != 1 || received[0].Number != 4 {
+		t.Errorf("received %+v, want the valid event only", received)
+	}
+}